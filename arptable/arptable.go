@@ -0,0 +1,294 @@
+package arptable
+
+import (
+	"container/list"
+	"packet-inspector/statistics"
+	"packet-inspector/types"
+	"slices"
+	"strings"
+	"time"
+
+	networklayer "packet-inspector/resolver/network-layer"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+const STATISTICS_GROUP = "ARP"
+
+// 最多记录的未应答请求数量，达到该值时清除过期的请求，仍不足时丢弃最早的请求
+const MAX_PENDING_REQUESTS = 4096
+
+type EventKind uint8
+
+const (
+	EVENT_NEW_STATION EventKind = 0
+	EVENT_GRATUITOUS  EventKind = 1
+	EVENT_CHANGED     EventKind = 2
+	EVENT_FLIP_FLOP   EventKind = 3
+	EVENT_DUPLICATE   EventKind = 4
+	EVENT_SPOOFING    EventKind = 5
+)
+
+var EVENT_KIND_NAME = map[EventKind]string{
+	EVENT_NEW_STATION: "new station",
+	EVENT_GRATUITOUS:  "gratuitous ARP",
+	EVENT_CHANGED:     "binding changed",
+	EVENT_FLIP_FLOP:   "binding flip-flop",
+	EVENT_DUPLICATE:   "duplicate IP address",
+	EVENT_SPOOFING:    "possible ARP spoofing",
+}
+
+// 绑定表的变化或可疑现象
+type Event struct {
+	Kind     EventKind
+	Frame    int        // 触发事件的帧序号
+	Time     time.Time  // 触发事件的抓包时间
+	IP       types.IPv4 // 涉及的 IP 地址
+	Mac      types.Mac  // 声明该 IP 地址的 MAC 地址
+	Previous types.Mac  // 之前绑定的 MAC 地址，仅在绑定变化时有效
+	Reasons  []string   // 补充说明
+}
+
+// IP 与 MAC 的绑定
+type Binding struct {
+	ip         types.IPv4
+	mac        types.Mac
+	previous   types.Mac // 上一次变化前绑定的 MAC 地址
+	changes    int       // 绑定变化的次数
+	first      time.Time // 第一次出现的抓包时间
+	last       time.Time // 最近一次出现的抓包时间
+	firstFrame int
+	lastFrame  int
+}
+
+func (binding *Binding) IP() types.IPv4 {
+	return binding.ip
+}
+
+func (binding *Binding) Mac() types.Mac {
+	return binding.mac
+}
+
+func (binding *Binding) Previous() types.Mac {
+	return binding.previous
+}
+
+func (binding *Binding) Changes() int {
+	return binding.changes
+}
+
+func (binding *Binding) First() time.Time {
+	return binding.first
+}
+
+func (binding *Binding) Last() time.Time {
+	return binding.last
+}
+
+func (binding *Binding) FirstFrame() int {
+	return binding.firstFrame
+}
+
+func (binding *Binding) LastFrame() int {
+	return binding.lastFrame
+}
+
+// 未应答的 ARP 请求
+type request struct {
+	sender types.IPv4
+	target types.IPv4
+}
+
+// 记录的未应答请求
+type pending struct {
+	key request
+	at  time.Time // 请求的抓包时间
+}
+
+// 由 ARP 报文维护的 IP ↔ MAC 绑定表
+type Table struct {
+	window   time.Duration             // 在该时长内出现过的绑定视为仍然活跃，也用于匹配请求与应答
+	bindings map[types.IPv4]*Binding   // IP -> 绑定
+	requests map[request]*list.Element // 未应答的请求 -> order 中的元素
+	order    *list.List                // 按请求时间排序的未应答请求，表头最早
+	report   func(Event)               // 事件回调
+}
+
+// 创建绑定表，report 在每个事件发生时被调用
+func New(window time.Duration, report func(Event)) *Table {
+	return &Table{
+		window:   window,
+		bindings: map[types.IPv4]*Binding{},
+		requests: map[request]*list.Element{},
+		order:    list.New(),
+		report:   report,
+	}
+}
+
+// 按 IP 地址排序的所有绑定
+func (table *Table) Bindings() []*Binding {
+	bindings := make([]*Binding, 0, len(table.bindings))
+	for _, binding := range table.bindings {
+		bindings = append(bindings, binding)
+	}
+	slices.SortFunc(bindings, func(a, b *Binding) int {
+		return strings.Compare(string(a.ip.Bytes()), string(b.ip.Bytes()))
+	})
+	return bindings
+}
+
+// 处理一个报文，frame 为其帧序号，非 ARP 报文被忽略
+func (table *Table) Inspect(frame int, packet gopacket.Packet) {
+	layer := packet.Layer(layers.LayerTypeARP)
+	if layer == nil {
+		return
+	}
+	arp, ok := networklayer.ARPResolve(layer.LayerContents()).(*networklayer.ARP)
+	if !ok || !arp.IsEthernetIPv4() {
+		return
+	}
+	timestamp := packet.Metadata().Timestamp
+
+	switch arp.Opcode() {
+	case networklayer.ARP_OPCODE_REQUEST:
+		statistics.Add(STATISTICS_GROUP, "Requests", 1)
+	case networklayer.ARP_OPCODE_REPLY:
+		statistics.Add(STATISTICS_GROUP, "Replies", 1)
+	default:
+		return
+	}
+
+	sender, mac := arp.SenderIP(), arp.SenderMac()
+	event := Event{Frame: frame, Time: timestamp, IP: sender, Mac: mac}
+
+	// 发送方硬件地址与链路层源地址不一致
+	suspicious := []string{}
+	if ethernet, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+		source := types.Mac{}
+		source.Parse([6]byte(ethernet.SrcMAC))
+		if source != mac {
+			suspicious = append(suspicious, "sender hardware address differs from link-layer source "+source.ToNamedString())
+		}
+	}
+
+	if arp.IsProbe() {
+		statistics.Add(STATISTICS_GROUP, "Probes", 1)
+		target := arp.TargetIP()
+		if binding := table.bindings[target]; binding != nil && binding.mac != mac && table.active(binding, timestamp) {
+			event.Kind, event.IP, event.Previous = EVENT_DUPLICATE, target, binding.mac
+			event.Reasons = append(suspicious, "address probe for an address in use")
+			table.emit(event)
+		}
+		return
+	}
+
+	if arp.IsGratuitous() {
+		gratuitous := event
+		gratuitous.Kind = EVENT_GRATUITOUS
+		gratuitous.Reasons = []string{networklayer.ARP_OPCODE_NAME[arp.Opcode()]}
+		table.emit(gratuitous)
+	} else if arp.Opcode() == networklayer.ARP_OPCODE_REQUEST {
+		table.request(request{sender: sender, target: arp.TargetIP()}, timestamp)
+	}
+	unsolicited := false
+	if arp.Opcode() == networklayer.ARP_OPCODE_REPLY && !arp.IsGratuitous() {
+		// 应答应当对应此前由目标发出的请求
+		key := request{sender: arp.TargetIP(), target: sender}
+		if element, ok := table.requests[key]; ok && !timestamp.After(element.Value.(*pending).at.Add(table.window)) {
+			table.order.Remove(element)
+			delete(table.requests, key)
+		} else {
+			statistics.Add(STATISTICS_GROUP, "Unsolicited replies", 1)
+			unsolicited = true
+		}
+	}
+
+	binding := table.bindings[sender]
+	if binding == nil {
+		table.bindings[sender] = &Binding{
+			ip:         sender,
+			mac:        mac,
+			first:      timestamp,
+			last:       timestamp,
+			firstFrame: frame,
+			lastFrame:  frame,
+		}
+		event.Kind = EVENT_NEW_STATION
+		event.Reasons = suspicious
+		table.emit(event)
+		return
+	}
+
+	if binding.mac != mac {
+		event.Previous = binding.mac
+		// 未经请求的应答改变了绑定
+		if unsolicited {
+			suspicious = append(suspicious, "unsolicited reply")
+		}
+		// 该 MAC 地址同时还活跃地持有其他 IP 地址
+		for _, other := range table.bindings {
+			if other != binding && other.mac == mac && table.active(other, timestamp) {
+				suspicious = append(suspicious, mac.ToNamedString()+" also claims "+other.ip.ToString())
+			}
+		}
+		switch {
+		case len(suspicious) != 0:
+			event.Kind = EVENT_SPOOFING
+		case table.active(binding, timestamp):
+			event.Kind = EVENT_DUPLICATE
+		case binding.previous == mac:
+			event.Kind = EVENT_FLIP_FLOP
+		default:
+			event.Kind = EVENT_CHANGED
+		}
+		event.Reasons = suspicious
+		binding.previous = binding.mac
+		binding.mac = mac
+		binding.changes++
+		table.emit(event)
+	} else if len(suspicious) != 0 {
+		event.Kind = EVENT_SPOOFING
+		event.Reasons = suspicious
+		table.emit(event)
+	}
+	binding.last = timestamp
+	binding.lastFrame = frame
+}
+
+// 记录一个请求，重复的请求更新请求时间
+func (table *Table) request(key request, timestamp time.Time) {
+	if element, ok := table.requests[key]; ok {
+		element.Value.(*pending).at = timestamp
+		table.order.MoveToBack(element)
+		return
+	}
+	if table.order.Len() >= MAX_PENDING_REQUESTS {
+		table.prune(timestamp)
+	}
+	table.requests[key] = table.order.PushBack(&pending{key: key, at: timestamp})
+}
+
+// 清除超过 window 仍未应答的请求，仍达到上限时丢弃最早的请求
+func (table *Table) prune(timestamp time.Time) {
+	for table.order.Len() > 0 {
+		oldest := table.order.Front().Value.(*pending)
+		if !timestamp.After(oldest.at.Add(table.window)) && table.order.Len() < MAX_PENDING_REQUESTS {
+			break
+		}
+		table.order.Remove(table.order.Front())
+		delete(table.requests, oldest.key)
+	}
+}
+
+// 绑定在 timestamp 之前的 window 内是否出现过
+func (table *Table) active(binding *Binding, timestamp time.Time) bool {
+	return !timestamp.After(binding.last.Add(table.window))
+}
+
+func (table *Table) emit(event Event) {
+	statistics.Add(STATISTICS_GROUP, "Events ("+EVENT_KIND_NAME[event.Kind]+")", 1)
+	if table.report != nil {
+		table.report(event)
+	}
+}
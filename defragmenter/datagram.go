@@ -0,0 +1,237 @@
+package defragmenter
+
+import (
+	"container/list"
+	"fmt"
+	"net/netip"
+	"packet-inspector/utils"
+	"slices"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+type DropReason uint8
+
+const (
+	DROPPED_NONE           DropReason = 0
+	DROPPED_TIMEOUT        DropReason = 1
+	DROPPED_DATAGRAM_LIMIT DropReason = 2
+	DROPPED_MEMORY_LIMIT   DropReason = 3
+	DROPPED_OVERLAP        DropReason = 4
+	DROPPED_OVERSIZED      DropReason = 5
+	DROPPED_INVALID        DropReason = 6
+	DROPPED_CAPTURE_END    DropReason = 7
+)
+
+var DROP_REASON_NAME = map[DropReason]string{
+	DROPPED_NONE:           "none",
+	DROPPED_TIMEOUT:        "timeout",
+	DROPPED_DATAGRAM_LIMIT: "datagram limit",
+	DROPPED_MEMORY_LIMIT:   "memory limit",
+	DROPPED_OVERLAP:        "IPv6 overlap",
+	DROPPED_OVERSIZED:      "oversized",
+	DROPPED_INVALID:        "inconsistent length",
+	DROPPED_CAPTURE_END:    "end of capture",
+}
+
+// 分片所属数据报的标识
+type Key struct {
+	Source         netip.Addr // 源地址
+	Destination    netip.Addr // 目的地址
+	Identification uint32     // 标识符，IPv4 为 16 bit，IPv6 为 32 bit
+	Protocol       uint8      // 上层协议类型
+}
+
+// 组成数据报的一个分片
+type Fragment struct {
+	Frame  int // 分片所在的帧序号
+	Offset int // 分片数据在数据报载荷中的偏移
+	Length int // 分片数据的长度
+}
+
+// 正在重组（或已重组完成）的 IP 数据报
+type Datagram struct {
+	element    *list.Element
+	key        Key
+	version    uint8      // IP 版本，4 或 6
+	header     []byte     // 偏移为 0 的分片的报文头，IPv6 为分片扩展头之前的部分
+	next       int        // IPv6 报文头中指向分片扩展头的字段的位置
+	nextHeader uint8      // IPv6 分片扩展头之后的报文头类型
+	payload    []byte     // 已收到的载荷，按偏移存放
+	fragments  []Fragment // 按到达顺序排列的分片
+	total      int        // 载荷总长度，未收到最后一个分片时为 -1
+	start      time.Time  // 第一个分片的抓包时间
+	end        time.Time  // 最后一个分片的抓包时间
+	retained   int        // 缓存的载荷字节数
+	overlaps   int        // 与已收到的数据重叠的分片数量
+	conflicts  int        // 重叠部分内容不一致的分片数量
+	dropped    DropReason // 被丢弃的原因
+	data       []byte     // 重组后的完整数据报
+}
+
+func (d *Datagram) Key() Key {
+	return d.key
+}
+
+func (d *Datagram) Version() uint8 {
+	return d.version
+}
+
+// 按到达顺序排列的分片
+func (d *Datagram) Fragments() []Fragment {
+	return d.fragments
+}
+
+// 贡献了分片的帧序号，按到达顺序排列
+func (d *Datagram) Frames() []int {
+	frames := make([]int, 0, len(d.fragments))
+	for _, fragment := range d.fragments {
+		frames = append(frames, fragment.Frame)
+	}
+	return frames
+}
+
+func (d *Datagram) Start() time.Time {
+	return d.start
+}
+
+func (d *Datagram) End() time.Time {
+	return d.end
+}
+
+// 已收到的载荷字节数
+func (d *Datagram) Bytes() int {
+	return d.retained
+}
+
+func (d *Datagram) Overlaps() int {
+	return d.overlaps
+}
+
+func (d *Datagram) Conflicts() int {
+	return d.conflicts
+}
+
+func (d *Datagram) Dropped() DropReason {
+	return d.dropped
+}
+
+// 重组后的完整数据报（包含报文头），未完成重组时为 nil
+func (d *Datagram) Data() []byte {
+	return d.data
+}
+
+// 以重组后的数据报构造报文，用于交给 TCP 重组器
+func (d *Datagram) Packet() gopacket.Packet {
+	if d.data == nil {
+		return nil
+	}
+	first := layers.LayerTypeIPv4
+	if d.version == 6 {
+		first = layers.LayerTypeIPv6
+	}
+	packet := gopacket.NewPacket(d.data, first, gopacket.Default)
+	packet.Metadata().Timestamp = d.end
+	packet.Metadata().CaptureLength = len(d.data)
+	packet.Metadata().Length = len(d.data)
+	return packet
+}
+
+// 可读的数据报标识，如 "192.0.2.1 -> 192.0.2.2 id 0x1234"
+func (d *Datagram) Tuple() string {
+	if d.version == 6 {
+		return fmt.Sprintf("%s -> %s id 0x%08X", d.key.Source, d.key.Destination, d.key.Identification)
+	}
+	return fmt.Sprintf("%s -> %s id 0x%04X", d.key.Source, d.key.Destination, d.key.Identification)
+}
+
+// 加入一个分片，返回载荷缓冲区新增占用的字节数（包括尚未收到的部分）；重叠部分保留先到达的数据
+func (d *Datagram) add(frame int, offset int, data []byte) int {
+	end := offset + len(data)
+	ordered := d.ordered()
+	overlap, conflict := false, false
+	for _, fragment := range ordered {
+		low, high := max(fragment.Offset, offset), min(fragment.Offset+fragment.Length, end)
+		if low >= high {
+			continue
+		}
+		overlap = true
+		if string(d.payload[low:high]) != string(data[low-offset:high-offset]) {
+			conflict = true
+		}
+	}
+	if overlap {
+		d.overlaps++
+	}
+	if conflict {
+		d.conflicts++
+	}
+
+	allocated := cap(d.payload)
+	if end > len(d.payload) {
+		d.payload = append(d.payload, make([]byte, end-len(d.payload))...)
+	}
+	stored := 0
+	cursor := offset
+	for _, fragment := range ordered {
+		if fragment.Offset > cursor && cursor < end {
+			stored += copy(d.payload[cursor:min(fragment.Offset, end)], data[cursor-offset:])
+		}
+		cursor = max(cursor, fragment.Offset+fragment.Length)
+	}
+	if cursor < end {
+		stored += copy(d.payload[cursor:end], data[cursor-offset:])
+	}
+
+	d.fragments = append(d.fragments, Fragment{Frame: frame, Offset: offset, Length: len(data)})
+	d.retained += stored
+	return cap(d.payload) - allocated
+}
+
+// 按偏移排列的分片
+func (d *Datagram) ordered() []Fragment {
+	ordered := slices.Clone(d.fragments)
+	slices.SortStableFunc(ordered, func(a, b Fragment) int {
+		return a.Offset - b.Offset
+	})
+	return ordered
+}
+
+// 是否已收到全部分片
+func (d *Datagram) complete() bool {
+	if d.total < 0 || d.header == nil {
+		return false
+	}
+	reach := 0
+	for _, fragment := range d.ordered() {
+		if fragment.Offset > reach {
+			return false
+		}
+		reach = max(reach, fragment.Offset+fragment.Length)
+	}
+	return reach >= d.total
+}
+
+// 组装完整的数据报，修正长度与分片字段
+func (d *Datagram) assemble() {
+	data := make([]byte, len(d.header)+d.total)
+	copy(data, d.header)
+	copy(data[len(d.header):], d.payload[:d.total])
+	if d.version == 6 {
+		data[d.next] = d.nextHeader
+		length := len(data) - 40
+		data[4], data[5] = byte(length>>8), byte(length)
+	} else {
+		length := len(data)
+		data[2], data[3] = byte(length>>8), byte(length)
+		// 清除 MF 标志与片偏移，保留 DF 标志
+		data[6] &= 0xC0
+		data[7] = 0
+		data[10], data[11] = 0, 0
+		checksum := utils.InternetChecksum(data[:len(d.header)])
+		data[10], data[11] = byte(checksum>>8), byte(checksum)
+	}
+	d.data = data
+}
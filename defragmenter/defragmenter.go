@@ -0,0 +1,314 @@
+package defragmenter
+
+import (
+	"container/list"
+	"net/netip"
+	"packet-inspector/statistics"
+	"packet-inspector/utils"
+	"slices"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+const STATISTICS_GROUP = "IP defragmentation"
+
+// 重组缓存的限制，0 表示不限制
+type Limits struct {
+	MaxDatagrams int // 最多同时重组的数据报数量，超出时丢弃最早的数据报
+	MaxBytes     int // 所有数据报的载荷缓冲区合计最多占用的字节数，超出时丢弃最早的数据报
+}
+
+// 分片重组器选项
+type Options struct {
+	Limits
+	Timeout time.Duration // 自第一个分片起超过该时长仍未重组完成的数据报被丢弃
+}
+
+// 以抓包时间戳驱动的 IPv4/IPv6 分片重组器
+type Defragmenter struct {
+	limits    Limits
+	timeout   time.Duration
+	datagrams map[Key]*Datagram
+	order     *list.List      // 按第一个分片到达时间排序的数据报，表头最早
+	total     int             // 所有数据报的载荷缓冲区合计占用的字节数
+	now       time.Time       // 最近一个报文的抓包时间
+	complete  func(*Datagram) // 数据报重组完成或被丢弃时的回调
+}
+
+// 创建分片重组器，complete 在每个数据报重组完成或被丢弃时被调用
+func New(options Options, complete func(*Datagram)) *Defragmenter {
+	return &Defragmenter{
+		limits:    options.Limits,
+		timeout:   options.Timeout,
+		datagrams: map[Key]*Datagram{},
+		order:     list.New(),
+		complete:  complete,
+	}
+}
+
+// 正在重组的数据报数量
+func (defragmenter *Defragmenter) Len() int {
+	return defragmenter.order.Len()
+}
+
+// 处理一个报文，frame 为其帧序号，非分片报文仅用于推进抓包时间
+func (defragmenter *Defragmenter) Defragment(frame int, packet gopacket.Packet) {
+	timestamp := packet.Metadata().Timestamp
+	if timestamp.After(defragmenter.now) {
+		defragmenter.now = timestamp
+	}
+	defragmenter.expire()
+
+	network := packet.NetworkLayer()
+	if network == nil {
+		return
+	}
+	fragment, ok := parse(datagram(network))
+	if !ok {
+		return
+	}
+	statistics.Add(STATISTICS_GROUP, "Fragments", 1)
+
+	d := defragmenter.datagrams[fragment.key]
+	if d == nil {
+		d = &Datagram{
+			key:     fragment.key,
+			version: fragment.version,
+			total:   -1,
+			start:   timestamp,
+		}
+		d.element = defragmenter.order.PushBack(d)
+		defragmenter.datagrams[d.key] = d
+		statistics.Add(STATISTICS_GROUP, "Datagrams", 1)
+		if defragmenter.limits.MaxDatagrams > 0 {
+			for defragmenter.order.Len() > defragmenter.limits.MaxDatagrams {
+				defragmenter.drop(defragmenter.order.Front().Value.(*Datagram), DROPPED_DATAGRAM_LIMIT)
+			}
+		}
+	}
+	d.end = timestamp
+
+	end := fragment.offset + len(fragment.data)
+	if end > fragment.limit {
+		d.fragments = append(d.fragments, Fragment{Frame: frame, Offset: fragment.offset, Length: len(fragment.data)})
+		defragmenter.drop(d, DROPPED_OVERSIZED)
+		return
+	}
+	if !fragment.more {
+		if d.total >= 0 && d.total != end {
+			d.fragments = append(d.fragments, Fragment{Frame: frame, Offset: fragment.offset, Length: len(fragment.data)})
+			defragmenter.drop(d, DROPPED_INVALID)
+			return
+		}
+		d.total = end
+	}
+	if d.total >= 0 && end > d.total {
+		d.fragments = append(d.fragments, Fragment{Frame: frame, Offset: fragment.offset, Length: len(fragment.data)})
+		defragmenter.drop(d, DROPPED_INVALID)
+		return
+	}
+	if fragment.offset == 0 && d.header == nil {
+		d.header = fragment.header
+		d.next = fragment.next
+		d.nextHeader = fragment.nextHeader
+	}
+
+	overlaps, conflicts := d.overlaps, d.conflicts
+	defragmenter.total += d.add(frame, fragment.offset, fragment.data)
+	if d.conflicts != conflicts {
+		statistics.Add(STATISTICS_GROUP, "Conflicting overlaps", 1)
+	}
+	if d.overlaps != overlaps {
+		statistics.Add(STATISTICS_GROUP, "Overlapping fragments", 1)
+		// RFC 5722：IPv6 数据报中出现任何重叠分片（无论内容是否一致）时整个数据报必须丢弃
+		if d.version == 6 {
+			defragmenter.drop(d, DROPPED_OVERLAP)
+			return
+		}
+	}
+
+	if d.complete() {
+		d.assemble()
+		statistics.Add(STATISTICS_GROUP, "Reassembled datagrams", 1)
+		defragmenter.finish(d)
+		return
+	}
+
+	if defragmenter.limits.MaxBytes > 0 {
+		for defragmenter.total > defragmenter.limits.MaxBytes && defragmenter.order.Len() > 0 {
+			defragmenter.drop(defragmenter.order.Front().Value.(*Datagram), DROPPED_MEMORY_LIMIT)
+		}
+	}
+}
+
+// 丢弃所有未重组完成的数据报，在抓包结束时调用
+func (defragmenter *Defragmenter) FlushAll() {
+	for defragmenter.order.Len() > 0 {
+		defragmenter.drop(defragmenter.order.Front().Value.(*Datagram), DROPPED_CAPTURE_END)
+	}
+}
+
+// 丢弃超时的数据报
+func (defragmenter *Defragmenter) expire() {
+	if defragmenter.timeout <= 0 {
+		return
+	}
+	deadline := defragmenter.now.Add(-defragmenter.timeout)
+	for defragmenter.order.Len() > 0 {
+		d := defragmenter.order.Front().Value.(*Datagram)
+		if !d.start.Before(deadline) {
+			break
+		}
+		defragmenter.drop(d, DROPPED_TIMEOUT)
+	}
+}
+
+// 丢弃数据报
+func (defragmenter *Defragmenter) drop(d *Datagram, reason DropReason) {
+	statistics.Add(STATISTICS_GROUP, "Dropped ("+DROP_REASON_NAME[reason]+")", 1)
+	d.dropped = reason
+	defragmenter.finish(d)
+}
+
+// 结束数据报并交付给回调
+func (defragmenter *Defragmenter) finish(d *Datagram) {
+	defragmenter.order.Remove(d.element)
+	delete(defragmenter.datagrams, d.key)
+	defragmenter.total -= cap(d.payload)
+	if defragmenter.complete != nil {
+		defragmenter.complete(d)
+	}
+	d.payload = nil
+}
+
+// 网络层的完整数据报（不含链路层填充）；IPv6 逐跳选项扩展头被并入报文头，其数据不在载荷中，需补回
+func datagram(network gopacket.NetworkLayer) []byte {
+	contents := slices.Clone(network.LayerContents())
+	if ipv6, ok := network.(*layers.IPv6); ok && ipv6.HopByHop != nil {
+		contents = append(contents, ipv6.HopByHop.LayerContents()...)
+	}
+	return append(contents, network.LayerPayload()...)
+}
+
+// 从报文中解析出的分片
+type fragment struct {
+	key        Key
+	version    uint8
+	header     []byte // 报文头，IPv6 为分片扩展头之前的部分
+	next       int    // IPv6 报文头中指向分片扩展头的字段的位置
+	nextHeader uint8  // IPv6 分片扩展头之后的报文头类型
+	offset     int    // 分片数据在数据报载荷中的偏移
+	more       bool   // 是否还有后续分片
+	data       []byte // 分片数据
+	limit      int    // 数据报载荷的最大长度
+}
+
+// 解析 IP 数据报，不是分片时返回 false
+func parse(packet []byte) (*fragment, bool) {
+	if len(packet) < 1 {
+		return nil, false
+	}
+	switch packet[0] >> 4 {
+	case 4:
+		return parseIPv4(packet)
+	case 6:
+		return parseIPv6(packet)
+	}
+	return nil, false
+}
+
+func parseIPv4(packet []byte) (*fragment, bool) {
+	if len(packet) < 20 {
+		return nil, false
+	}
+	headerLength := int(packet[0]&0x0F) * 4
+	length := int(utils.ExtractUint16BE(packet, 2))
+	if headerLength < 20 || length < headerLength || length > len(packet) {
+		return nil, false
+	}
+	more := packet[6]&0x20 != 0
+	offset := int(utils.ExtractUint16BE(packet, 6)&0x1FFF) * 8
+	if !more && offset == 0 {
+		return nil, false
+	}
+
+	f := &fragment{
+		key: Key{
+			Source:         netip.AddrFrom4([4]byte(packet[12:16])),
+			Destination:    netip.AddrFrom4([4]byte(packet[16:20])),
+			Identification: uint32(utils.ExtractUint16BE(packet, 4)),
+			Protocol:       packet[9],
+		},
+		version: 4,
+		offset:  offset,
+		more:    more,
+		data:    packet[headerLength:length],
+		limit:   65535 - headerLength,
+	}
+	if offset == 0 {
+		f.header = make([]byte, headerLength)
+		copy(f.header, packet)
+	}
+	return f, true
+}
+
+func parseIPv6(packet []byte) (*fragment, bool) {
+	if len(packet) < 40 {
+		return nil, false
+	}
+	length := 40 + int(utils.ExtractUint16BE(packet, 4))
+	if length > len(packet) {
+		return nil, false
+	}
+	packet = packet[:length]
+
+	// 跳过分片扩展头之前的扩展头
+	next, position := 6, 40
+	for {
+		if position+8 > length {
+			return nil, false
+		}
+		switch packet[next] {
+		case 0x00, 0x2b, 0x3c: // 逐跳选项、路由、目的选项
+			next, position = position, position+(int(packet[position+1])+1)*8
+			continue
+		case 0x33: // 认证扩展头
+			next, position = position, position+(int(packet[position+1])+2)*4
+			continue
+		case 0x2c: // 分片扩展头
+		default:
+			return nil, false
+		}
+		break
+	}
+
+	more := packet[position+3]&0x1 != 0
+	offset := int(utils.ExtractUint16BE(packet, position+2) & 0xFFF8)
+	// 偏移为 0 且没有后续分片的原子分片可直接解析，无需重组
+	if !more && offset == 0 {
+		return nil, false
+	}
+
+	f := &fragment{
+		key: Key{
+			Source:         netip.AddrFrom16([16]byte(packet[8:24])),
+			Destination:    netip.AddrFrom16([16]byte(packet[24:40])),
+			Identification: utils.ExtractUint32BE(packet, position+4),
+			Protocol:       packet[position],
+		},
+		version:    6,
+		next:       next,
+		nextHeader: packet[position],
+		offset:     offset,
+		more:       more,
+		data:       packet[position+8:],
+		limit:      65535 - (position - 40),
+	}
+	if offset == 0 {
+		f.header = make([]byte, position)
+		copy(f.header, packet)
+	}
+	return f, true
+}
@@ -0,0 +1,283 @@
+package filter
+
+import (
+	"errors"
+	"packet-inspector/resolver"
+	"strconv"
+	"strings"
+)
+
+// 显示过滤器，语法示例：
+//
+//	vlan                            存在 VLAN 标签
+//	vlan.id == 100 && ip.proto == 17
+//	!(eth.type == 0x0800) || udp.dstport >= 1024
+//	tunnel && inner.ip.dst == 10.0.0.1
+//
+// 支持 ==、!=、<、<=、>、>=，以及 &&（and）、||（or）、!（not）和括号。
+// 字段出现多次（如 QinQ 的两层 vlan.id）时，任意一个值满足即视为满足；
+// 报文经过隧道时，以 inner. 与 outer. 为前缀的字段分别只取自最内层与最外层；
+// 两侧都是数字（十进制或 0x 开头的 16 进制）时按数值比较，否则按字符串比较（不区分大小写）
+type Filter struct {
+	expression string
+	root       node
+}
+
+// 编译过滤表达式
+func Compile(expression string) (*Filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.position != len(p.tokens) {
+		return nil, errors.New("filter: unexpected " + strconv.Quote(p.tokens[p.position]))
+	}
+	return &Filter{expression: expression, root: root}, nil
+}
+
+func (filter *Filter) ToString() string {
+	return filter.expression
+}
+
+// 报文是否满足过滤条件
+func (filter *Filter) Match(packet resolver.IPacket) bool {
+	return filter.MatchFields(resolver.CollectFields(packet))
+}
+
+// 字段是否满足过滤条件
+func (filter *Filter) MatchFields(fields []resolver.Field) bool {
+	return filter.root.match(fields)
+}
+
+type node interface {
+	match(fields []resolver.Field) bool
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) match(fields []resolver.Field) bool {
+	return n.left.match(fields) || n.right.match(fields)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) match(fields []resolver.Field) bool {
+	return n.left.match(fields) && n.right.match(fields)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) match(fields []resolver.Field) bool {
+	return !n.operand.match(fields)
+}
+
+// 字段比较，operator 为空时仅判断字段（或以其为前缀的协议）是否存在
+type compareNode struct {
+	field    string
+	operator string
+	value    string
+}
+
+func (n *compareNode) match(fields []resolver.Field) bool {
+	if n.operator == "" {
+		for _, field := range fields {
+			if field.Name == n.field || strings.HasPrefix(field.Name, n.field+".") {
+				return true
+			}
+		}
+		return false
+	}
+	// a != b 等价于 !(a == b)
+	if n.operator == "!=" {
+		return !(&compareNode{field: n.field, operator: "==", value: n.value}).match(fields)
+	}
+	for _, field := range fields {
+		if field.Name == n.field && compare(field.Value, n.operator, n.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(left string, operator string, right string) bool {
+	result := 0
+	a, errA := strconv.ParseInt(left, 0, 64)
+	b, errB := strconv.ParseInt(right, 0, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case a < b:
+			result = -1
+		case a > b:
+			result = 1
+		}
+	} else {
+		result = strings.Compare(strings.ToLower(left), strings.ToLower(right))
+	}
+
+	switch operator {
+	case "==":
+		return result == 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	}
+	return false
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+// 将表达式切分为记号，带引号的字符串去掉引号后作为一个记号
+func tokenize(expression string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		if c == ' ' || c == '\t' {
+			i++
+			continue
+		}
+		if c == '"' {
+			end := strings.IndexByte(expression[i+1:], '"')
+			if end < 0 {
+				return nil, errors.New("filter: unterminated string")
+			}
+			tokens = append(tokens, "\""+expression[i+1:i+1+end])
+			i += end + 2
+			continue
+		}
+		matched := false
+		for _, operator := range operators {
+			if strings.HasPrefix(expression[i:], operator) {
+				tokens = append(tokens, operator)
+				i += len(operator)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		start := i
+		for i < len(expression) && !strings.ContainsRune(" \t\"=!<>&|()", rune(expression[i])) {
+			i++
+		}
+		if i == start {
+			return nil, errors.New("filter: unexpected " + strconv.Quote(expression[i:i+1]))
+		}
+		tokens = append(tokens, expression[start:i])
+	}
+	return tokens, nil
+}
+
+// 递归下降解析器，优先级从低到高依次为 ||、&&、!
+type parser struct {
+	tokens   []string
+	position int
+}
+
+func (p *parser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	p.position++
+	return token
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" || p.peek() == "or" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" || p.peek() == "and" {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek() == "!" || p.peek() == "not" {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	token := p.next()
+	switch token {
+	case "":
+		return nil, errors.New("filter: unexpected end of expression")
+	case "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("filter: missing )")
+		}
+		return inner, nil
+	case ")", "&&", "||", "==", "!=", "<", "<=", ">", ">=":
+		return nil, errors.New("filter: unexpected " + strconv.Quote(token))
+	}
+	if strings.HasPrefix(token, "\"") {
+		return nil, errors.New("filter: expected a field name, got a string")
+	}
+
+	n := &compareNode{field: token}
+	switch p.peek() {
+	case "==", "!=", "<", "<=", ">", ">=":
+		n.operator = p.next()
+		value := p.next()
+		switch value {
+		case "", "(", ")", "&&", "||", "!", "==", "!=", "<", "<=", ">", ">=":
+			return nil, errors.New("filter: missing value after " + n.operator)
+		}
+		n.value = strings.TrimPrefix(value, "\"")
+	}
+	return n, nil
+}
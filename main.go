@@ -2,104 +2,563 @@ package main
 
 import (
 	"encoding/hex"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"packet-inspector/arptable"
+	"packet-inspector/defragmenter"
+	"packet-inspector/filter"
+	"packet-inspector/neighbortable"
+	"packet-inspector/reassembler"
 	"packet-inspector/resolver"
 	applicationlayer "packet-inspector/resolver/application-layer"
 	datalinklayer "packet-inspector/resolver/datalink-layer"
+	networklayer "packet-inspector/resolver/network-layer"
+	transportlayer "packet-inspector/resolver/transport-layer"
+	_ "packet-inspector/resolver/tunnel" // 注册 GRE、VXLAN、Geneve 等隧道协议
+	"packet-inspector/sctpreassembler"
+	"packet-inspector/statistics"
+	"packet-inspector/tcpanalysis"
+	"packet-inspector/types"
+	"packet-inspector/udpflow"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gopacket/gopacket"
-	"github.com/gopacket/gopacket/layers"
 	"github.com/gopacket/gopacket/pcap"
-	"github.com/gopacket/gopacket/tcpassembly"
 )
 
-type reassembler struct{}
+var (
+	readFile          = flag.String("r", "", "read packets from a pcap file instead of a live device")
+	streamTimeout     = flag.Duration("stream-timeout", time.Minute/2, "close TCP connections idle for longer than this, measured in capture time")
+	follow            = flag.String("follow", "", "only print the conversation of the TCP stream with this index or endpoints (addr:port-addr:port)")
+	followMode        = flag.String("follow-mode", "ascii", "conversation format for -follow: ascii, hex or raw")
+	exportDirectory   = flag.String("export-dir", "", "write each reassembled TCP direction to a file in this directory, with an index file")
+	exportIndex       = flag.String("export-index", "csv", "index file format for -export-dir: csv or json")
+	verifyChecksums   = flag.Bool("verify-checksums", false, "drop TCP segments with a bad checksum from reassembly")
+	maxStreamBytes    = flag.Int("stream-max-bytes", 16<<20, "maximum bytes buffered per TCP connection, 0 for unlimited")
+	maxTotalBytes     = flag.Int("stream-max-total", 256<<20, "maximum bytes buffered across all TCP connections, 0 for unlimited")
+	maxConnections    = flag.Int("stream-max-connections", 65536, "maximum number of tracked TCP connections, 0 for unlimited; the least recently active connection is evicted and released at once, so its later packets start a new stream")
+	maxPagesTotal     = flag.Int("assembler-max-pages", 65536, "maximum out-of-order pages buffered by the assembler, 0 for unlimited")
+	maxPagesPerStream = flag.Int("assembler-max-pages-per-connection", 4096, "maximum out-of-order pages buffered per connection, 0 for unlimited")
+	defragTimeout     = flag.Duration("defrag-timeout", time.Minute/2, "drop IP datagrams not reassembled within this long after their first fragment, in capture time")
+	maxDatagrams      = flag.Int("defrag-max-datagrams", 4096, "maximum number of IP datagrams being reassembled, 0 for unlimited")
+	maxDatagramBytes  = flag.Int("defrag-max-bytes", 16<<20, "maximum bytes of reassembly buffers across all IP datagrams being reassembled, including gaps not yet received, 0 for unlimited")
+	arpWindow         = flag.Duration("arp-window", time.Minute, "ARP bindings seen within this long count as active when another MAC claims the address, in capture time")
+	displayFilter     = flag.String("filter", "", "only print frames matching this filter, e.g. \"vlan.id == 100 && udp\"")
+	snapLength        = flag.Int("snaplen", 262144, "maximum bytes captured per frame on a live device; longer frames are truncated, resolved as far as captured and reported by an expert")
+	fcsMode           = flag.String("fcs", "auto", "whether captured Ethernet frames end with an FCS: auto (detect by CRC-32), present or absent; Ethernet frames inside tunnels never carry one")
+	sctpTimeout       = flag.Duration("sctp-timeout", time.Minute/2, "drop SCTP messages not reassembled within this long after a fragment arrives, in capture time")
+	sctpMaxFragments  = flag.Int("sctp-max-fragments", 65536, "maximum SCTP DATA fragments buffered for reassembly, 0 for unlimited")
+	sctpMaxBytes      = flag.Int("sctp-max-bytes", 16<<20, "maximum bytes buffered across all SCTP messages being reassembled, 0 for unlimited")
+	sctpPPIDs         = flag.String("sctp-ppid", "", "comma-separated ppid=protocol pairs dispatching SCTP payload protocol identifiers to application protocols, e.g. \"1000=PieP\"")
+	udpTimeout        = flag.Duration("udp-timeout", time.Minute, "start a new UDP flow when a 5-tuple has been idle for longer than this, in capture time")
+	macNames          = flag.String("mac-names", "", "file of \"address name\" lines naming MAC addresses (6 bytes) or vendors (3-byte OUI)")
+)
+
+// 输出重组完成的连接
+func streamComplete(s *reassembler.Stream) {
+	if exporter != nil {
+		if err := exporter.Export(s); err != nil {
+			fmt.Printf("[Export] Can not export stream #%d %s: %s\n", s.Index(), s.Tuple(), err)
+		}
+	}
+
+	if *follow != "" {
+		if s.Match(*follow) {
+			fmt.Print(s.ToFollowString(followingMode))
+		}
+		return
+	}
+
+	fmt.Printf("[Stream] #%d %s, start %s, end %s, duration %s, %d bytes, %d packets, closed by %s\n",
+		s.Index(), s.Tuple(), s.Start().Format(time.RFC3339Nano), s.End().Format(time.RFC3339Nano), s.Duration(),
+		s.Bytes(), s.Packets(), reassembler.CLOSE_REASON_NAME[s.Closed()])
 
-type stream struct {
-	tcpassembly.Stream
-	net       gopacket.Flow
-	transport gopacket.Flow
-	data      []byte
-	start     time.Time
-	end       time.Time
+	halfComplete("Client -> Server", s.Client())
+	halfComplete("Server -> Client", s.Server())
+
+	if s.Truncated() {
+		fmt.Printf("[Stream] %s truncated: %d bytes dropped", s.Tuple(), s.Dropped())
+		if s.Evicted() != reassembler.EVICTED_NONE {
+			fmt.Printf(", evicted by %s", reassembler.EVICT_REASON_NAME[s.Evicted()])
+		}
+		fmt.Println()
+		expertEvent(resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SEQUENCE, Protocol: "TCP", Message: "Stream truncated by reassembly limits"})
+	}
 }
 
-func (factory *reassembler) New(net gopacket.Flow, transport gopacket.Flow) tcpassembly.Stream {
-	s := &stream{
-		net:       net,
-		transport: transport,
-		start:     time.Now(),
+// 输出连接中一个方向的数据，缺失的数据处分段解析
+func halfComplete(direction string, half *reassembler.Half) {
+	if half.Packets() == 0 {
+		return
+	}
+	fmt.Printf("[Stream] %s: %d bytes, %d packets, %d gaps (%d bytes missing), %d conflicting retransmissions\n",
+		direction, half.Bytes(), half.Packets(), len(half.Gaps()), half.Missing(), len(half.Conflicts()))
+	for _, conflict := range half.Conflicts() {
+		fmt.Printf("[Stream] %s: retransmission at offset %d overlaps %d bytes, %d bytes differ\n",
+			direction, conflict.Offset, conflict.Length, conflict.Differ)
+		expertEvent(resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SECURITY, Protocol: "TCP", Message: "Retransmitted data differs from the original"})
+	}
+
+	gaps := half.Gaps()
+	for _, segment := range half.Segments() {
+		for len(gaps) != 0 && gaps[0].Offset < segment.Offset {
+			fmt.Printf("[Stream] %s: [GAP %d bytes at offset %d]\n", direction, gaps[0].Length, gaps[0].Offset)
+			gaps = gaps[1:]
+		}
+
+		messages, leftover := applicationlayer.StreamResolve(segment.Data)
+		for _, message := range messages {
+			println(message.ToReadableString(0))
+		}
+		if len(messages) == 0 {
+			fmt.Printf("[Application Layer] Can not resolve %s\n", strings.ToUpper(hex.EncodeToString(segment.Data)))
+		} else if len(leftover) != 0 {
+			fmt.Printf("[Application Layer] %d leftover bytes can not be resolved: %s\n", len(leftover), strings.ToUpper(hex.EncodeToString(leftover)))
+		}
+	}
+	for _, gap := range gaps {
+		fmt.Printf("[Stream] %s: [GAP %d bytes at offset %d]\n", direction, gap.Length, gap.Offset)
 	}
-	s.end = s.start
-	return s
 }
 
-func (s *stream) Reassembled(reassemblies []tcpassembly.Reassembly) {
-	for _, reassembly := range reassemblies {
-		if !reassembly.Seen.Before(s.end) {
-			s.end = reassembly.Seen
+// 输出重组完成（或被丢弃）的 IP 数据报，并将重组结果交给 UDP 流跟踪器与 TCP、SCTP 重组器
+func datagramComplete(d *defragmenter.Datagram) {
+	frames := make([]string, 0, len(d.Fragments()))
+	for _, frame := range d.Frames() {
+		frames = append(frames, "#"+strconv.Itoa(frame))
+	}
+	protocol := networklayer.IPv4_PROTOCOL_NAME[d.Key().Protocol]
+	if protocol == "" {
+		protocol = strconv.Itoa(int(d.Key().Protocol))
+	}
+
+	version := "IPv" + strconv.Itoa(int(d.Version()))
+
+	if d.Dropped() != defragmenter.DROPPED_NONE {
+		if *follow == "" {
+			fmt.Printf("[Defragment] IPv%d %s (%s) dropped by %s: %d fragments, %d bytes, frames %s\n",
+				d.Version(), d.Tuple(), protocol, defragmenter.DROP_REASON_NAME[d.Dropped()],
+				len(d.Fragments()), d.Bytes(), strings.Join(frames, ", "))
+		}
+		expertEvent(resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SEQUENCE,
+			Protocol: version, Message: "Datagram dropped before reassembly (" + defragmenter.DROP_REASON_NAME[d.Dropped()] + ")"})
+		return
+	}
+
+	// 分片的报文不经过传输层的跟踪，由重组后的数据报补上，以最后一个分片的帧序号计
+	packet, last := d.Packet(), d.Frames()[len(d.Frames())-1]
+	flow := udpFlows.Inspect(last, packet)
+	streamReassembler.Assemble(packet)
+	sctpReassembler.Reassemble(last, packet)
+	if *follow == "" {
+		fmt.Printf("[Defragment] IPv%d %s (%s) reassembled: %d bytes from %d fragments, frames %s\n",
+			d.Version(), d.Tuple(), protocol, len(d.Data()), len(d.Fragments()), strings.Join(frames, ", "))
+		if d.Overlaps() != 0 {
+			fmt.Printf("[Defragment] IPv%d %s: %d overlapping fragments, %d with different data\n",
+				d.Version(), d.Tuple(), d.Overlaps(), d.Conflicts())
+		}
+	}
+	if d.Conflicts() != 0 {
+		// 数据不同的重叠分片可用于绕过检测
+		expertEvent(resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SECURITY,
+			Protocol: version, Message: "Overlapping fragments with different data"})
+	}
+	if *follow != "" {
+		return
+	}
+	resolve := networklayer.Resolvers[version]
+	if resolvedPacket := resolve(d.Data()); resolvedPacket != nil {
+		flow.Apply(resolvedPacket)
+		experts := resolver.CollectExperts(resolvedPacket)
+		expertStatistics(experts)
+		if frameFilter == nil || frameFilter.Match(resolvedPacket) {
+			println(resolvedPacket.ToReadableString(0) + resolver.ExpertsToReadableString(experts, 0))
 		}
-		s.data = append(s.data, reassembly.Bytes...)
+	} else {
+		fmt.Printf("[Network Layer] Can not resolve %s\n", strings.ToUpper(hex.EncodeToString(d.Data())))
 	}
 }
 
-func (s *stream) ReassemblyComplete() {
-	for _, resolver := range applicationlayer.Resolvers {
-		packet := resolver(s.data)
-		if packet != nil {
-			println(packet.ToReadableString(0))
-			return
+// 输出重组完成（或被丢弃）的 SCTP 用户消息，并按载荷协议标识解析
+func sctpMessageComplete(m *sctpreassembler.Message) {
+	frames := make([]string, 0, len(m.Fragments()))
+	for _, frame := range m.Frames() {
+		frames = append(frames, "#"+strconv.Itoa(frame))
+	}
+	first, last := m.TSNs()
+
+	if m.Dropped() != sctpreassembler.DROPPED_NONE {
+		if *follow == "" {
+			fmt.Printf("[SCTP] %s SSN %d dropped by %s: %d fragments, %d bytes, TSNs %d - %d, frames %s\n",
+				m.Tuple(), m.SSN(), sctpreassembler.DROP_REASON_NAME[m.Dropped()], len(m.Fragments()), len(m.Data()),
+				first, last, strings.Join(frames, ", "))
 		}
+		expertEvent(resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SEQUENCE,
+			Protocol: "SCTP", Message: "Message dropped before reassembly (" + sctpreassembler.DROP_REASON_NAME[m.Dropped()] + ")"})
+		return
+	}
+	if *follow != "" {
+		return
+	}
+	fmt.Printf("[SCTP] %s SSN %d, PPID %s reassembled: %d bytes from %d fragments, TSNs %d - %d, frames %s\n",
+		m.Tuple(), m.SSN(), transportlayer.SCTPPPIDString(m.PPID()), len(m.Data()), len(m.Fragments()),
+		first, last, strings.Join(frames, ", "))
+	if message := transportlayer.PPIDResolve(m.PPID(), m.Data()); message != nil {
+		experts := resolver.CollectExperts(message)
+		expertStatistics(experts)
+		if frameFilter == nil || frameFilter.Match(message) {
+			println(message.ToReadableString(0) + resolver.ExpertsToReadableString(experts, 0))
+		}
+	} else {
+		fmt.Printf("[Application Layer] Can not resolve %s\n", strings.ToUpper(hex.EncodeToString(m.Data())))
 	}
-	fmt.Printf("[Application Layer] Can not resolve %s\n", strings.ToUpper(hex.EncodeToString(s.data)))
 }
 
-func worker(packet gopacket.Packet) {
-	var resolvedPacket resolver.IPacket = nil
-	for _, resolve := range datalinklayer.Resolvers {
-		resolvedPacket = resolve(packet.Data())
-		if packet != nil {
-			break
+// 解析 -sctp-ppid 的 "ppid=protocol" 列表
+func parseSCTPPPIDs(value string) {
+	for _, pair := range strings.Split(value, ",") {
+		ppid, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			panic("invalid SCTP PPID mapping " + pair)
 		}
+		number, err := strconv.ParseUint(ppid, 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		if applicationlayer.Resolvers[name] == nil {
+			panic("unknown application protocol " + name)
+		}
+		transportlayer.SCTP_PPID_PROTOCOL[uint32(number)] = name
+	}
+}
+
+// 输出 ARP 绑定表的事件
+func arpEvent(event arptable.Event) {
+	switch event.Kind {
+	case arptable.EVENT_SPOOFING:
+		expertEvent(resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SECURITY, Protocol: "ARP", Message: "Possible ARP spoofing"})
+	case arptable.EVENT_DUPLICATE:
+		expertEvent(resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_PROTOCOL, Protocol: "ARP", Message: "Duplicate IP address"})
+	}
+	if *follow != "" {
+		return
+	}
+	fmt.Printf("[ARP] Frame #%d: %s: %s is-at %s", event.Frame, arptable.EVENT_KIND_NAME[event.Kind],
+		event.IP.ToString(), event.Mac.ToNamedString())
+	if event.Kind != arptable.EVENT_NEW_STATION && event.Kind != arptable.EVENT_GRATUITOUS {
+		fmt.Printf(", previously %s", event.Previous.ToNamedString())
 	}
+	if len(event.Reasons) != 0 {
+		fmt.Printf(" (%s)", strings.Join(event.Reasons, "; "))
+	}
+	fmt.Println()
+}
+
+func worker(frame int, packet gopacket.Packet, analysis tcpanalysis.Result, flow udpflow.Result) {
+	if *follow != "" {
+		return
+	}
+
+	resolvedPacket := datalinklayer.LinkTypeResolve(linkType, packet.Data())
 	if resolvedPacket == nil {
-		fmt.Printf("[Datalink Layer] Can not resolve %s\n", strings.ToUpper(hex.EncodeToString(packet.Data())))
-	} else {
-		println(resolvedPacket.ToReadableString(0))
+		expertStatistics([]resolver.Expert{{Severity: resolver.SEVERITY_ERROR, Group: resolver.GROUP_MALFORMED,
+			Protocol: "Frame", Message: "Could not be resolved"}})
+		if frameFilter == nil {
+			fmt.Printf("[Datalink Layer] Can not resolve %s\n", strings.ToUpper(hex.EncodeToString(packet.Data())))
+		}
+		return
+	}
+	// 抓包长度不足帧长的帧只解析已抓取的部分
+	if link, ok := resolvedPacket.(resolver.IExperts); ok && packet.Metadata().Length > packet.Metadata().CaptureLength {
+		link.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_MALFORMED, "Frame", "Frame truncated by snaplen")
+	}
+	analysis.Apply(resolvedPacket)
+	flow.Apply(resolvedPacket)
+
+	fields := resolver.CollectFields(resolvedPacket)
+	experts := resolver.CollectExperts(resolvedPacket)
+	vlanStatistics(fields, len(packet.Data()))
+	tunnelStatistics(fields, len(packet.Data()))
+	stpStatistics(fields)
+	expertStatistics(experts)
+	if frameFilter != nil && !frameFilter.MatchFields(fields) {
+		return
+	}
+	println("[Frame] #" + strconv.Itoa(frame) + "\n" + resolvedPacket.ToReadableString(0) + resolver.ExpertsToReadableString(experts, 0))
+}
+
+// 按严重程度、分组、协议与描述统计专家信息
+func expertStatistics(experts []resolver.Expert) {
+	for _, expert := range experts {
+		statistics.Add("Expert info", expert.ToString(), 1)
+	}
+}
+
+// 输出并统计不属于某个报文的专家信息，如分片重组与连接重组中发现的问题
+func expertEvent(expert resolver.Expert) {
+	expertStatistics([]resolver.Expert{expert})
+	if *follow == "" {
+		fmt.Printf("[Expert] %s\n", expert.ToString())
+	}
+}
+
+// 按 VLAN 统计帧数与字节数，QinQ 的多层 VLAN ID 由外向内以 '/' 连接
+func vlanStatistics(fields []resolver.Field, length int) {
+	ids := []string{}
+	for _, field := range fields {
+		if field.Name == "vlan.id" {
+			ids = append(ids, field.Value)
+		}
 	}
+	if len(ids) == 0 {
+		return
+	}
+	key := strings.Join(ids, "/")
+	statistics.Add("VLAN frames", key, 1)
+	statistics.Add("VLAN bytes", key, uint64(length))
 }
 
+// 按隧道统计帧数与字节数，嵌套的隧道分别统计
+func tunnelStatistics(fields []resolver.Field, length int) {
+	for _, field := range fields {
+		if field.Name == "tunnel" {
+			statistics.Add("Tunnel frames", field.Value, 1)
+			statistics.Add("Tunnel bytes", field.Value, uint64(length))
+		}
+	}
+}
+
+// 按根桥统计 BPDU 数，按发送者统计拓扑变化（含 TCN）次数
+func stpStatistics(fields []resolver.Field) {
+	var root, bridge string
+	bpdu, tc := false, false
+	for _, field := range fields {
+		switch field.Name {
+		case "stp.type":
+			bpdu = true
+			tc = tc || field.Value == "0x80"
+		case "stp.root.mac":
+			root = field.Value
+		case "stp.bridge.mac":
+			bridge = field.Value
+		case "stp.flags.tc":
+			tc = tc || field.Value == "1"
+		}
+	}
+	if !bpdu {
+		return
+	}
+	if root != "" {
+		statistics.Add("STP root bridges", root, 1)
+	}
+	if tc {
+		// TCN BPDU 没有桥 ID，以 "TCN" 计
+		if bridge == "" {
+			bridge = "TCN"
+		}
+		statistics.Add("STP topology changes", bridge, 1)
+	}
+}
+
+// -follow 的输出格式
+var followingMode reassembler.FollowMode
+
+// -export-dir 的导出器
+var exporter *reassembler.Exporter
+
+// -filter 的过滤器
+var frameFilter *filter.Filter
+
+// TCP 重组器
+var streamReassembler *reassembler.Reassembler
+
+// SCTP 用户消息重组器
+var sctpReassembler *sctpreassembler.Reassembler
+
+// UDP 流跟踪器
+var udpFlows *udpflow.Tracker
+
+// 抓包的链路类型
+var linkType uint16
+
 func main() {
-	if len(os.Args) < 2 {
+	flag.Parse()
+	var err error
+	mode, ok := reassembler.ParseFollowMode(*followMode)
+	if !ok {
+		panic("unknown follow mode " + *followMode)
+	}
+	followingMode = mode
+
+	if *displayFilter != "" {
+		frameFilter, err = filter.Compile(*displayFilter)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	fcs, ok := datalinklayer.ParseFCSMode(*fcsMode)
+	if !ok {
+		panic("unknown FCS mode " + *fcsMode)
+	}
+	datalinklayer.EthernetFCSMode = fcs
+
+	if *macNames != "" {
+		if err := types.LoadMacNames(*macNames); err != nil {
+			panic(err)
+		}
+	}
+
+	if *sctpPPIDs != "" {
+		parseSCTPPPIDs(*sctpPPIDs)
+	}
+
+	if *exportDirectory != "" {
+		format, ok := reassembler.ParseIndexFormat(*exportIndex)
+		if !ok {
+			panic("unknown index format " + *exportIndex)
+		}
+		exporter, err = reassembler.NewExporter(*exportDirectory, format)
+		if err != nil {
+			panic(err)
+		}
+		defer exporter.Close()
+	}
+
+	var handle *pcap.Handle
+	if *readFile != "" {
+		handle, err = pcap.OpenOffline(*readFile)
+	} else if flag.NArg() < 1 {
 		panic("no device specified")
+	} else {
+		handle, err = pcap.OpenLive(flag.Arg(0), int32(*snapLength), false, 30*time.Second)
 	}
-	handle, err := pcap.OpenLive(os.Args[1], 4096, false, 30*time.Second)
 	if err != nil {
 		panic(err)
 	}
 	defer handle.Close()
+	linkType = uint16(handle.LinkType())
+	if datalinklayer.LINK_TYPE_NAME[linkType] == "" {
+		fmt.Printf("[Datalink Layer] Unsupported link type %d (%s), frames will not be resolved\n", linkType, handle.LinkType())
+	}
 
-	streamFactory := &reassembler{}
-	streamPool := tcpassembly.NewStreamPool(streamFactory)
-	assembler := tcpassembly.NewAssembler(streamPool)
-	nextFlush := time.Now().Add(time.Minute / 2)
+	streamReassembler = reassembler.New(reassembler.Options{
+		Limits: reassembler.Limits{
+			MaxStreamBytes:        *maxStreamBytes,
+			MaxTotalBytes:         *maxTotalBytes,
+			MaxConnections:        *maxConnections,
+			MaxPagesTotal:         *maxPagesTotal,
+			MaxPagesPerConnection: *maxPagesPerStream,
+		},
+		Timeout:         *streamTimeout,
+		VerifyChecksums: *verifyChecksums,
+	}, streamComplete)
+	sctpReassembler = sctpreassembler.New(sctpreassembler.Options{
+		Limits: sctpreassembler.Limits{
+			MaxFragments: *sctpMaxFragments,
+			MaxBytes:     *sctpMaxBytes,
+		},
+		Timeout: *sctpTimeout,
+	}, sctpMessageComplete)
+	bindings := arptable.New(*arpWindow, arpEvent)
+	neighbors := neighbortable.New()
+	connections := tcpanalysis.New()
+	udpFlows = udpflow.New(*udpTimeout)
+	ipDefragmenter := defragmenter.New(defragmenter.Options{
+		Limits: defragmenter.Limits{
+			MaxDatagrams: *maxDatagrams,
+			MaxBytes:     *maxDatagramBytes,
+		},
+		Timeout: *defragTimeout,
+	}, datagramComplete)
 
-	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
-	for packet := range packetSource.Packets() {
-		go worker(packet)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
 
-		if time.Now().After(nextFlush) {
-			assembler.FlushOlderThan(time.Now().Add(time.Minute / 2))
-			nextFlush = time.Now().Add(time.Minute / 2)
+	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
+	packets := packetSource.Packets()
+	workers := sync.WaitGroup{}
+	frame := 0
+loop:
+	for {
+		var packet gopacket.Packet
+		select {
+		case <-interrupt:
+			break loop
+		case p, ok := <-packets:
+			if !ok {
+				break loop
+			}
+			packet = p
 		}
 
-		tcp, ok := packet.TransportLayer().(*layers.TCP)
-		if ok {
-			assembler.AssembleWithTimestamp(packet.NetworkLayer().NetworkFlow(), tcp, packet.Metadata().Timestamp)
-		}
+		frame++
+		// 连接状态依赖报文顺序，必须在并发解析之前得到
+		analysis := connections.Inspect(frame, packet)
+		flow := udpFlows.Inspect(frame, packet)
+		workers.Add(1)
+		go func(frame int) {
+			defer workers.Done()
+			worker(frame, packet, analysis, flow)
+		}(frame)
+		bindings.Inspect(frame, packet)
+		neighbors.Inspect(frame, packet)
+		ipDefragmenter.Defragment(frame, packet)
+		streamReassembler.Assemble(packet)
+		sctpReassembler.Reassemble(frame, packet)
+	}
 
+	workers.Wait()
+	ipDefragmenter.FlushAll()
+	streamReassembler.FlushAll()
+	sctpReassembler.FlushAll()
+	if *follow != "" {
+		return
+	}
+	if len(bindings.Bindings()) != 0 {
+		fmt.Print("[ARP Bindings] {\n")
+		for _, binding := range bindings.Bindings() {
+			mac := binding.Mac()
+			fmt.Printf("\t%s is-at %s, frames #%d - #%d", binding.IP().ToString(), mac.ToNamedString(), binding.FirstFrame(), binding.LastFrame())
+			if binding.Changes() != 0 {
+				previous := binding.Previous()
+				fmt.Printf(", %d changes, previously %s", binding.Changes(), previous.ToNamedString())
+			}
+			fmt.Println()
+		}
+		fmt.Print("}\n")
+	}
+	if len(neighbors.Neighbors()) != 0 {
+		fmt.Print("[Neighbors] {\n")
+		for _, neighbor := range neighbors.Neighbors() {
+			fmt.Printf("\t%s, frames #%d - #%d\n", neighbor.ToString(), neighbor.FirstFrame(), neighbor.LastFrame())
+		}
+		fmt.Print("}\n")
+	}
+	if len(connections.Connections()) != 0 {
+		fmt.Print("[TCP Analysis] {\n")
+		for _, connection := range connections.Connections() {
+			fmt.Printf("\t%s, frames #%d - #%d\n", connection.ToString(), connection.FirstFrame(), connection.LastFrame())
+		}
+		if omitted := connections.Omitted(); omitted.Connections() != 0 {
+			fmt.Printf("\t%s\n", omitted.ToString())
+		}
+		fmt.Print("}\n")
+	}
+	if len(udpFlows.Flows()) != 0 {
+		fmt.Print("[UDP Flows] {\n")
+		for _, flow := range udpFlows.Flows() {
+			fmt.Printf("\t%s, frames #%d - #%d\n", flow.ToString(), flow.FirstFrame(), flow.LastFrame())
+		}
+		if omitted := udpFlows.Omitted(); omitted.Flows() != 0 {
+			fmt.Printf("\t%s\n", omitted.ToString())
+		}
+		fmt.Print("}\n")
 	}
+	fmt.Print("[Statistics] {\n", statistics.ToReadableString(1), "}\n")
 }
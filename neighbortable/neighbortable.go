@@ -0,0 +1,228 @@
+package neighbortable
+
+import (
+	"cmp"
+	"packet-inspector/statistics"
+	"packet-inspector/types"
+	"slices"
+	"strings"
+	"time"
+
+	datalinklayer "packet-inspector/resolver/datalink-layer"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+const STATISTICS_GROUP = "Neighbors"
+
+const (
+	PROTOCOL_LLDP = "LLDP"
+	PROTOCOL_CDP  = "CDP"
+)
+
+// 由 LLDP 或 CDP 通告的邻居
+type Neighbor struct {
+	protocol     string
+	source       types.Mac // 通告的链路层源地址
+	chassis      string    // 机箱 ID（CDP 为设备 ID）
+	port         string    // 邻居上发出通告的端口
+	description  string    // 端口描述
+	name         string    // 系统名称
+	capabilities []string  // 已启用的能力（CDP 为全部能力）
+	management   []string  // 管理地址
+	ttl          int       // 最近一次通告的生存时间（秒），0 表示邻居已关闭
+	first        time.Time // 第一次出现的抓包时间
+	last         time.Time // 最近一次出现的抓包时间
+	firstFrame   int
+	lastFrame    int
+	frames       int // 通告的次数
+}
+
+func (neighbor *Neighbor) Protocol() string {
+	return neighbor.protocol
+}
+
+func (neighbor *Neighbor) Source() types.Mac {
+	return neighbor.source
+}
+
+func (neighbor *Neighbor) ChassisID() string {
+	return neighbor.chassis
+}
+
+func (neighbor *Neighbor) PortID() string {
+	return neighbor.port
+}
+
+func (neighbor *Neighbor) PortDescription() string {
+	return neighbor.description
+}
+
+func (neighbor *Neighbor) SystemName() string {
+	return neighbor.name
+}
+
+func (neighbor *Neighbor) Capabilities() []string {
+	return neighbor.capabilities
+}
+
+func (neighbor *Neighbor) ManagementAddresses() []string {
+	return neighbor.management
+}
+
+func (neighbor *Neighbor) TTL() int {
+	return neighbor.ttl
+}
+
+func (neighbor *Neighbor) First() time.Time {
+	return neighbor.first
+}
+
+func (neighbor *Neighbor) Last() time.Time {
+	return neighbor.last
+}
+
+func (neighbor *Neighbor) FirstFrame() int {
+	return neighbor.firstFrame
+}
+
+func (neighbor *Neighbor) LastFrame() int {
+	return neighbor.lastFrame
+}
+
+func (neighbor *Neighbor) Frames() int {
+	return neighbor.frames
+}
+
+// 格式化为一行摘要，如 "LLDP sw1 (00:16:81:11:22:33) port ge-0/0/1 ..."
+func (neighbor *Neighbor) ToString() string {
+	builder := new(strings.Builder)
+	builder.WriteString(neighbor.protocol)
+	builder.WriteByte(' ')
+	if neighbor.name != "" {
+		builder.WriteString(neighbor.name)
+		builder.WriteString(" (")
+		builder.WriteString(neighbor.chassis)
+		builder.WriteString(")")
+	} else {
+		builder.WriteString(neighbor.chassis)
+	}
+	builder.WriteString(" port ")
+	builder.WriteString(neighbor.port)
+	if neighbor.description != "" && neighbor.description != neighbor.port {
+		builder.WriteString(" (")
+		builder.WriteString(neighbor.description)
+		builder.WriteString(")")
+	}
+	builder.WriteString(", via ")
+	builder.WriteString(neighbor.source.ToNamedString())
+	if len(neighbor.capabilities) != 0 {
+		builder.WriteString(", capabilities: ")
+		builder.WriteString(strings.Join(neighbor.capabilities, ", "))
+	}
+	if len(neighbor.management) != 0 {
+		builder.WriteString(", management: ")
+		builder.WriteString(strings.Join(neighbor.management, ", "))
+	}
+	if neighbor.ttl == 0 {
+		builder.WriteString(", shut down")
+	}
+	return builder.String()
+}
+
+type key struct {
+	protocol string
+	chassis  string
+	port     string
+}
+
+// 由 LLDP 与 CDP 通告维护的邻居表
+type Table struct {
+	neighbors map[key]*Neighbor
+}
+
+func New() *Table {
+	return &Table{neighbors: map[key]*Neighbor{}}
+}
+
+// 按协议、机箱 ID 与端口 ID 排序的所有邻居
+func (table *Table) Neighbors() []*Neighbor {
+	neighbors := make([]*Neighbor, 0, len(table.neighbors))
+	for _, neighbor := range table.neighbors {
+		neighbors = append(neighbors, neighbor)
+	}
+	slices.SortFunc(neighbors, func(a, b *Neighbor) int {
+		return cmp.Or(
+			strings.Compare(a.protocol, b.protocol),
+			strings.Compare(a.chassis, b.chassis),
+			strings.Compare(a.port, b.port),
+		)
+	})
+	return neighbors
+}
+
+// 处理一个报文，frame 为其帧序号，非 LLDP 与 CDP 报文被忽略
+func (table *Table) Inspect(frame int, packet gopacket.Packet) {
+	var neighbor *Neighbor
+	if layer := packet.Layer(layers.LayerTypeLinkLayerDiscovery); layer != nil {
+		lldp, ok := datalinklayer.LLDPResolve(layer.LayerContents()).(*datalinklayer.LLDP)
+		if !ok {
+			return
+		}
+		supported, enabled := lldp.Capabilities()
+		capabilities := datalinklayer.LLDPCapabilityNames(enabled)
+		if enabled == 0 {
+			capabilities = datalinklayer.LLDPCapabilityNames(supported)
+		}
+		neighbor = &Neighbor{
+			protocol:     PROTOCOL_LLDP,
+			chassis:      lldp.ChassisID(),
+			port:         lldp.PortID(),
+			description:  lldp.PortDescription(),
+			name:         lldp.SystemName(),
+			capabilities: capabilities,
+			management:   lldp.ManagementAddresses(),
+			ttl:          int(lldp.TTL()),
+		}
+	} else if layer := packet.Layer(layers.LayerTypeCiscoDiscovery); layer != nil {
+		contents := append(slices.Clone(layer.LayerContents()), layer.LayerPayload()...)
+		cdp, ok := datalinklayer.CDPResolve(contents).(*datalinklayer.CDP)
+		if !ok {
+			return
+		}
+		neighbor = &Neighbor{
+			protocol:     PROTOCOL_CDP,
+			chassis:      cdp.DeviceID(),
+			port:         cdp.PortID(),
+			name:         cdp.SystemName(),
+			capabilities: datalinklayer.CDPCapabilityNames(cdp.Capabilities()),
+			management:   cdp.Addresses(),
+			ttl:          int(cdp.TTL()),
+		}
+	} else {
+		return
+	}
+	statistics.Add(STATISTICS_GROUP, neighbor.protocol+" frames", 1)
+
+	if ethernet, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+		neighbor.source.Parse([6]byte(ethernet.SrcMAC))
+	}
+	timestamp := packet.Metadata().Timestamp
+	neighbor.first, neighbor.last = timestamp, timestamp
+	neighbor.firstFrame, neighbor.lastFrame = frame, frame
+	neighbor.frames = 1
+
+	index := key{protocol: neighbor.protocol, chassis: neighbor.chassis, port: neighbor.port}
+	previous := table.neighbors[index]
+	if previous == nil {
+		statistics.Add(STATISTICS_GROUP, neighbor.protocol+" neighbors", 1)
+	} else {
+		neighbor.first, neighbor.firstFrame = previous.first, previous.firstFrame
+		neighbor.frames += previous.frames
+	}
+	if neighbor.ttl == 0 {
+		statistics.Add(STATISTICS_GROUP, neighbor.protocol+" shutdowns", 1)
+	}
+	table.neighbors[index] = neighbor
+}
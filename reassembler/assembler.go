@@ -0,0 +1,126 @@
+package reassembler
+
+import (
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly"
+)
+
+// 重组器选项
+type Options struct {
+	Limits
+	Timeout         time.Duration // 连接空闲超过该时长即视为结束
+	VerifyChecksums bool          // 是否丢弃校验和错误的报文
+}
+
+// 传递给重组器的报文上下文
+type context struct {
+	captureInfo   gopacket.CaptureInfo
+	checksumValid bool
+	release       bool // 是否为释放已淘汰连接而构造的 RST 报文
+}
+
+func (c *context) GetCaptureInfo() gopacket.CaptureInfo {
+	return c.captureInfo
+}
+
+// 以抓包时间戳驱动、感知 TCP 状态的重组器
+type Reassembler struct {
+	factory   *Factory
+	assembler *reassembly.Assembler
+	timeout   time.Duration // 连接空闲超过该时长即视为结束
+	now       time.Time     // 最近一个报文的抓包时间
+	nextFlush time.Time     // 下一次检查空闲连接的时间
+}
+
+// 创建重组器，complete 在每个连接结束时被调用
+func New(options Options, complete func(*Stream)) *Reassembler {
+	factory := NewFactory(options.Limits, complete)
+	factory.verifyChecksums = options.VerifyChecksums
+	assembler := reassembly.NewAssembler(reassembly.NewStreamPool(factory))
+	assembler.MaxBufferedPagesTotal = options.MaxPagesTotal
+	assembler.MaxBufferedPagesPerConnection = options.MaxPagesPerConnection
+	return &Reassembler{
+		factory:   factory,
+		assembler: assembler,
+		timeout:   options.Timeout,
+	}
+}
+
+// 重组一个报文，非 TCP 报文仅用于推进抓包时间
+func (r *Reassembler) Assemble(packet gopacket.Packet) {
+	timestamp := packet.Metadata().Timestamp
+	if timestamp.After(r.now) {
+		r.now = timestamp
+	}
+
+	if r.nextFlush.IsZero() {
+		r.nextFlush = r.now.Add(r.timeout)
+	} else if r.now.After(r.nextFlush) {
+		r.FlushOlderThan(r.now.Add(-r.timeout))
+		r.nextFlush = r.now.Add(r.timeout)
+	}
+
+	network := packet.NetworkLayer()
+	tcp, ok := packet.TransportLayer().(*layers.TCP)
+	if network == nil || !ok {
+		return
+	}
+
+	c := &context{
+		captureInfo:   packet.Metadata().CaptureInfo,
+		checksumValid: true,
+	}
+	if !packet.Metadata().Truncated && tcp.SetNetworkLayerForChecksum(network) == nil {
+		checksum, err := tcp.ComputeChecksum()
+		c.checksumValid = err != nil || checksum == 0
+	}
+	r.assembler.AssembleWithContext(network.NetworkFlow(), tcp, c)
+	r.release()
+}
+
+// 从连接池中释放已淘汰的连接：向两个方向各送入一个 RST 报文关闭连接，连接池随即移除该连接
+func (r *Reassembler) release() {
+	r.factory.releasing = true
+	for len(r.factory.evicted) > 0 {
+		s := r.factory.evicted[0]
+		r.factory.evicted = r.factory.evicted[1:]
+		for _, flows := range [][2]gopacket.Flow{{s.net, s.transport}, {s.net.Reverse(), s.transport.Reverse()}} {
+			header := make([]byte, 20)
+			copy(header[0:2], flows[1].Src().Raw())
+			copy(header[2:4], flows[1].Dst().Raw())
+			header[12] = 5 << 4
+			header[13] = 0x04 // RST
+			tcp := new(layers.TCP)
+			if tcp.DecodeFromBytes(header, gopacket.NilDecodeFeedback) != nil {
+				continue
+			}
+			c := &context{
+				captureInfo:   gopacket.CaptureInfo{Timestamp: r.now},
+				checksumValid: true,
+				release:       true,
+			}
+			r.assembler.AssembleWithContext(flows[0], tcp, c)
+		}
+	}
+	r.factory.evicted = nil
+	r.factory.releasing = false
+}
+
+// 结束所有在 t 之前就不再活动的连接
+func (r *Reassembler) FlushOlderThan(t time.Time) {
+	r.factory.closing = CLOSED_TIMEOUT
+	r.assembler.FlushCloseOlderThan(t)
+	r.factory.closing = CLOSED_NONE
+	r.release()
+}
+
+// 抓包结束时，结束所有连接
+func (r *Reassembler) FlushAll() {
+	r.factory.closing = CLOSED_CAPTURE_END
+	r.assembler.FlushAll()
+	r.factory.closing = CLOSED_NONE
+	r.factory.evicted = nil
+}
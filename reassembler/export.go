@@ -0,0 +1,200 @@
+package reassembler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	applicationlayer "packet-inspector/resolver/application-layer"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type IndexFormat uint8
+
+const (
+	INDEX_CSV  IndexFormat = 0
+	INDEX_JSON IndexFormat = 1 // 每行一个 JSON 对象
+)
+
+var INDEX_FORMAT_NAME = map[IndexFormat]string{
+	INDEX_CSV:  "csv",
+	INDEX_JSON: "json",
+}
+
+// 按名称查找索引格式
+func ParseIndexFormat(name string) (IndexFormat, bool) {
+	for format, formatName := range INDEX_FORMAT_NAME {
+		if strings.EqualFold(name, formatName) {
+			return format, true
+		}
+	}
+	return INDEX_CSV, false
+}
+
+// 索引文件中的一条记录
+type IndexRecord struct {
+	Index          int    `json:"index"`
+	Client         string `json:"client"`
+	Server         string `json:"server"`
+	Start          string `json:"start"`
+	End            string `json:"end"`
+	DurationMs     int64  `json:"duration_ms"`
+	Packets        int    `json:"packets"`
+	Bytes          int    `json:"bytes"`
+	Closed         string `json:"closed"`
+	Truncated      bool   `json:"truncated"`
+	Dropped        int    `json:"dropped"`
+	ClientBytes    int    `json:"client_bytes"`
+	ClientGaps     int    `json:"client_gaps"`
+	ClientMissing  int    `json:"client_missing"`
+	ClientConflict int    `json:"client_conflicts"`
+	ClientProtocol string `json:"client_protocol"`
+	ClientFile     string `json:"client_file"`
+	ServerBytes    int    `json:"server_bytes"`
+	ServerGaps     int    `json:"server_gaps"`
+	ServerMissing  int    `json:"server_missing"`
+	ServerConflict int    `json:"server_conflicts"`
+	ServerProtocol string `json:"server_protocol"`
+	ServerFile     string `json:"server_file"`
+}
+
+var INDEX_CSV_HEADER = []string{
+	"index", "client", "server", "start", "end", "duration_ms", "packets", "bytes", "closed", "truncated", "dropped",
+	"client_bytes", "client_gaps", "client_missing", "client_conflicts", "client_protocol", "client_file",
+	"server_bytes", "server_gaps", "server_missing", "server_conflicts", "server_protocol", "server_file",
+}
+
+func (record *IndexRecord) csv() []string {
+	return []string{
+		strconv.Itoa(record.Index), record.Client, record.Server, record.Start, record.End,
+		strconv.FormatInt(record.DurationMs, 10), strconv.Itoa(record.Packets), strconv.Itoa(record.Bytes),
+		record.Closed, strconv.FormatBool(record.Truncated), strconv.Itoa(record.Dropped),
+		strconv.Itoa(record.ClientBytes), strconv.Itoa(record.ClientGaps), strconv.Itoa(record.ClientMissing),
+		strconv.Itoa(record.ClientConflict), record.ClientProtocol, record.ClientFile,
+		strconv.Itoa(record.ServerBytes), strconv.Itoa(record.ServerGaps), strconv.Itoa(record.ServerMissing),
+		strconv.Itoa(record.ServerConflict), record.ServerProtocol, record.ServerFile,
+	}
+}
+
+// 将重组后的连接按方向写入文件，并维护索引文件
+type Exporter struct {
+	directory string
+	format    IndexFormat
+	index     *os.File
+	csv       *csv.Writer
+	json      *json.Encoder
+}
+
+// 在 directory 下创建导出器，索引文件为 index.csv 或 index.json
+func NewExporter(directory string, format IndexFormat) (*Exporter, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	index, err := os.Create(filepath.Join(directory, "index."+INDEX_FORMAT_NAME[format]))
+	if err != nil {
+		return nil, err
+	}
+
+	exporter := &Exporter{directory: directory, format: format, index: index}
+	if format == INDEX_JSON {
+		exporter.json = json.NewEncoder(index)
+	} else {
+		exporter.csv = csv.NewWriter(index)
+		if err := exporter.csv.Write(INDEX_CSV_HEADER); err != nil {
+			index.Close()
+			return nil, err
+		}
+		exporter.csv.Flush()
+	}
+	return exporter, nil
+}
+
+// 导出一个连接
+func (exporter *Exporter) Export(s *Stream) error {
+	client := endpoint(s.net.Src(), s.transport.Src())
+	server := endpoint(s.net.Dst(), s.transport.Dst())
+	record := IndexRecord{
+		Index:          s.index,
+		Client:         client,
+		Server:         server,
+		Start:          s.start.Format(time.RFC3339Nano),
+		End:            s.end.Format(time.RFC3339Nano),
+		DurationMs:     s.Duration().Milliseconds(),
+		Packets:        s.Packets(),
+		Bytes:          s.Bytes(),
+		Closed:         CLOSE_REASON_NAME[s.closed],
+		Truncated:      s.truncated,
+		Dropped:        s.dropped,
+		ClientBytes:    s.client.bytes,
+		ClientGaps:     len(s.client.gaps),
+		ClientMissing:  s.client.Missing(),
+		ClientConflict: len(s.client.conflicts),
+		ClientProtocol: s.client.protocol(),
+		ServerBytes:    s.server.bytes,
+		ServerGaps:     len(s.server.gaps),
+		ServerMissing:  s.server.Missing(),
+		ServerConflict: len(s.server.conflicts),
+		ServerProtocol: s.server.protocol(),
+	}
+
+	var err error
+	prefix := s.start.UTC().Format("20060102T150405.000000Z") + "_"
+	if record.ClientFile, err = exporter.write(prefix+fileName(client)+"-"+fileName(server), &s.client); err != nil {
+		return err
+	}
+	if record.ServerFile, err = exporter.write(prefix+fileName(server)+"-"+fileName(client), &s.server); err != nil {
+		return err
+	}
+
+	if exporter.format == INDEX_JSON {
+		return exporter.json.Encode(record)
+	}
+	if err := exporter.csv.Write(record.csv()); err != nil {
+		return err
+	}
+	exporter.csv.Flush()
+	return exporter.csv.Error()
+}
+
+// 关闭索引文件
+func (exporter *Exporter) Close() error {
+	if exporter.csv != nil {
+		exporter.csv.Flush()
+	}
+	return exporter.index.Close()
+}
+
+// 将一个方向的数据按原偏移写入文件，缺失的数据处留空；没有数据时不创建文件
+func (exporter *Exporter) write(name string, half *Half) (string, error) {
+	if len(half.segments) == 0 {
+		return "", nil
+	}
+	file, err := os.Create(filepath.Join(exporter.directory, name))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	for _, segment := range half.segments {
+		if _, err := file.WriteAt(segment.Data, int64(segment.Offset)); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// 端点在文件名中的形式，地址与端口以 '.' 分隔，IPv6 地址中的 ':' 替换为 '_'
+func fileName(endpoint string) string {
+	address, port := endpoint[:strings.LastIndexByte(endpoint, ':')], endpoint[strings.LastIndexByte(endpoint, ':')+1:]
+	address = strings.Trim(address, "[]")
+	return strings.ReplaceAll(address, ":", "_") + "." + port
+}
+
+// 识别该方向第一段数据的应用层协议
+func (half *Half) protocol() string {
+	if len(half.segments) == 0 {
+		return ""
+	}
+	return applicationlayer.StreamProtocol(half.segments[0].Data)
+}
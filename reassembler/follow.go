@@ -0,0 +1,142 @@
+package reassembler
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type FollowMode uint8
+
+const (
+	FOLLOW_ASCII FollowMode = 0 // 可打印字符原样输出，其余以 '.' 代替
+	FOLLOW_HEX   FollowMode = 1 // 带偏移的 16 进制转储
+	FOLLOW_RAW   FollowMode = 2 // 不加格式的 16 进制字符串
+)
+
+var FOLLOW_MODE_NAME = map[FollowMode]string{
+	FOLLOW_ASCII: "ascii",
+	FOLLOW_HEX:   "hex",
+	FOLLOW_RAW:   "raw",
+}
+
+// 按名称查找跟踪模式
+func ParseFollowMode(name string) (FollowMode, bool) {
+	for mode, modeName := range FOLLOW_MODE_NAME {
+		if strings.EqualFold(name, modeName) {
+			return mode, true
+		}
+	}
+	return FOLLOW_ASCII, false
+}
+
+// 以对话形式输出连接的数据，客户端与服务端的发言分开显示
+func (s *Stream) ToFollowString(mode FollowMode) string {
+	builder := new(strings.Builder)
+	client := endpoint(s.net.Src(), s.transport.Src())
+	server := endpoint(s.net.Dst(), s.transport.Dst())
+
+	builder.WriteString("===================================================================\n")
+	builder.WriteString("Follow: tcp,")
+	builder.WriteString(FOLLOW_MODE_NAME[mode])
+	builder.WriteString("\nStream: ")
+	builder.WriteString(strconv.Itoa(s.index))
+	builder.WriteString("\nNode 0: ")
+	builder.WriteString(client)
+	builder.WriteString("\nNode 1: ")
+	builder.WriteString(server)
+	builder.WriteByte('\n')
+
+	for _, turn := range s.turns {
+		half, from, to := &s.client, client, server
+		if !turn.FromClient {
+			half, from, to = &s.server, server, client
+		}
+
+		builder.WriteString("-------- ")
+		builder.WriteString(from)
+		builder.WriteString(" -> ")
+		builder.WriteString(to)
+		builder.WriteString(" (")
+		builder.WriteString(strconv.Itoa(turn.Length))
+		builder.WriteString(" bytes at offset ")
+		builder.WriteString(strconv.Itoa(turn.Offset))
+		builder.WriteString(") --------\n")
+
+		if turn.Gap {
+			builder.WriteString("[GAP ")
+			builder.WriteString(strconv.Itoa(turn.Length))
+			builder.WriteString(" bytes missing]\n")
+			continue
+		}
+
+		data := half.Read(turn.Offset, turn.Length)
+		switch mode {
+		case FOLLOW_HEX:
+			builder.WriteString(hexDump(data, turn.Offset, !turn.FromClient))
+		case FOLLOW_RAW:
+			builder.WriteString(strings.ToUpper(hex.EncodeToString(data)))
+			builder.WriteByte('\n')
+		default:
+			builder.WriteString(printable(data))
+			if len(data) != 0 && data[len(data)-1] != '\n' {
+				builder.WriteByte('\n')
+			}
+		}
+		if len(data) < turn.Length {
+			builder.WriteString("[")
+			builder.WriteString(strconv.Itoa(turn.Length - len(data)))
+			builder.WriteString(" bytes not retained]\n")
+		}
+	}
+	builder.WriteString("===================================================================\n")
+
+	return builder.String()
+}
+
+// 不可打印的字符以 '.' 代替
+func printable(data []byte) string {
+	text := make([]byte, len(data))
+	for i, b := range data {
+		if b == '\n' || b == '\r' || b == '\t' || (b >= 0x20 && b < 0x7F) {
+			text[i] = b
+		} else {
+			text[i] = '.'
+		}
+	}
+	return string(text)
+}
+
+// 每行 16 字节的 16 进制转储，服务端的数据缩进一级
+func hexDump(data []byte, offset int, indent bool) string {
+	builder := new(strings.Builder)
+	for line := 0; line < len(data); line += 16 {
+		if indent {
+			builder.WriteByte('\t')
+		}
+		builder.WriteString(fmt.Sprintf("%08X", offset+line))
+		builder.WriteString("  ")
+		end := min(line+16, len(data))
+		for i := line; i < line+16; i++ {
+			if i < end {
+				builder.WriteString(fmt.Sprintf("%02X ", data[i]))
+			} else {
+				builder.WriteString("   ")
+			}
+			if i == line+7 {
+				builder.WriteByte(' ')
+			}
+		}
+		builder.WriteString(" |")
+		for _, b := range data[line:end] {
+			if b >= 0x20 && b < 0x7F {
+				builder.WriteByte(b)
+			} else {
+				builder.WriteByte('.')
+			}
+		}
+		builder.WriteString("|\n")
+	}
+	return builder.String()
+}
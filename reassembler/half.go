@@ -0,0 +1,148 @@
+package reassembler
+
+import "packet-inspector/statistics"
+
+// 连续的一段数据，offset 为其第一个字节在该方向字节流中的偏移
+type Segment struct {
+	Offset int
+	Data   []byte
+}
+
+// 缺失的数据区间
+type Gap struct {
+	Offset int
+	Length int
+}
+
+// 内容与已收到的数据不一致的重传
+type Conflict struct {
+	Offset int // 重叠区间在该方向字节流中的偏移
+	Length int // 重叠的字节数
+	Differ int // 内容不一致的字节数
+}
+
+// 一次发言，即同一方向连续到达的一段数据
+type Turn struct {
+	FromClient bool // 是否由客户端发出
+	Offset     int  // 在该方向字节流中的偏移
+	Length     int  // 字节数
+	Gap        bool // 是否为缺失的数据
+}
+
+// TCP 连接中一个方向的数据
+type Half struct {
+	segments  []Segment  // 已缓存的数据，按偏移排序
+	gaps      []Gap      // 缺失的数据区间
+	conflicts []Conflict // 内容不一致的重传
+	offset    int        // 下一个字节在字节流中的偏移
+	bytes     int        // 收到的载荷字节数，包括被丢弃的部分
+	packets   int        // 收到的报文数
+	overlaps  int        // 与已缓存的乱序数据重叠的字节数
+	fin       bool       // 是否收到 FIN
+}
+
+// 已缓存的数据，按偏移排序，相邻两段之间为缺失或被丢弃的数据
+func (half *Half) Segments() []Segment {
+	return half.segments
+}
+
+// 缺失的数据区间
+func (half *Half) Gaps() []Gap {
+	return half.gaps
+}
+
+// 缺失的总字节数
+func (half *Half) Missing() int {
+	missing := 0
+	for _, gap := range half.gaps {
+		missing += gap.Length
+	}
+	return missing
+}
+
+// 内容与已收到的数据不一致的重传
+func (half *Half) Conflicts() []Conflict {
+	return half.conflicts
+}
+
+// 收到的载荷字节数，包括被丢弃的部分
+func (half *Half) Bytes() int {
+	return half.bytes
+}
+
+// 收到的报文数
+func (half *Half) Packets() int {
+	return half.packets
+}
+
+// 与已缓存的乱序数据重叠的字节数
+func (half *Half) Overlaps() int {
+	return half.overlaps
+}
+
+// 追加按序重组的数据，skip 为其之前缺失的字节数
+func (half *Half) deliver(bytes []byte, skip int, s *Stream) {
+	if skip > 0 {
+		half.gaps = append(half.gaps, Gap{Offset: half.offset, Length: skip})
+		s.speak(half, half.offset, skip, true)
+		half.offset += skip
+		statistics.Add(STATISTICS_GROUP, "Gaps", 1)
+		statistics.Add(STATISTICS_GROUP, "Missing bytes", uint64(skip))
+	}
+	half.bytes += len(bytes)
+	if len(bytes) != 0 {
+		s.speak(half, half.offset, len(bytes), false)
+	}
+
+	kept := s.reserve(len(bytes))
+	if kept > 0 {
+		last := len(half.segments) - 1
+		if last >= 0 && half.segments[last].Offset+len(half.segments[last].Data) == half.offset {
+			half.segments[last].Data = append(half.segments[last].Data, bytes[:kept]...)
+		} else {
+			segment := Segment{Offset: half.offset, Data: make([]byte, kept)}
+			copy(segment.Data, bytes)
+			half.segments = append(half.segments, segment)
+		}
+	}
+	half.offset += len(bytes)
+}
+
+// 读取 [offset, offset+length) 区间内已缓存的数据，缺失或被丢弃的部分被跳过
+func (half *Half) Read(offset int, length int) []byte {
+	data := []byte{}
+	for _, segment := range half.segments {
+		from := max(offset, segment.Offset)
+		to := min(offset+length, segment.Offset+len(segment.Data))
+		if from < to {
+			data = append(data, segment.Data[from-segment.Offset:to-segment.Offset]...)
+		}
+	}
+	return data
+}
+
+// 将重传的数据与已缓存的数据比较，记录内容不一致的部分
+func (half *Half) compare(offset int, payload []byte) {
+	end := offset + len(payload)
+	if end > half.offset {
+		end = half.offset
+	}
+	if end <= offset {
+		return
+	}
+
+	differ := 0
+	for _, segment := range half.segments {
+		from := max(offset, segment.Offset)
+		to := min(end, segment.Offset+len(segment.Data))
+		for i := from; i < to; i++ {
+			if payload[i-offset] != segment.Data[i-segment.Offset] {
+				differ++
+			}
+		}
+	}
+	if differ != 0 {
+		half.conflicts = append(half.conflicts, Conflict{Offset: offset, Length: end - offset, Differ: differ})
+		statistics.Add(STATISTICS_GROUP, "Conflicting retransmissions", 1)
+	}
+}
@@ -0,0 +1,135 @@
+package reassembler
+
+import (
+	"container/list"
+	"packet-inspector/statistics"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly"
+)
+
+const STATISTICS_GROUP = "TCP reassembly"
+
+// 重组缓存的内存限制，0 表示不限制
+type Limits struct {
+	MaxStreamBytes        int // 单个连接最多缓存的字节数，超出部分丢弃
+	MaxTotalBytes         int // 所有连接合计最多缓存的字节数，超出时淘汰最久未活动的连接
+	MaxConnections        int // 最多同时跟踪的连接数量，超出时淘汰最久未活动的连接
+	MaxPagesTotal         int // 重组器最多缓存的乱序页数
+	MaxPagesPerConnection int // 单个连接最多缓存的乱序页数
+}
+
+// 流工厂，负责跟踪所有连接并执行内存限制
+type Factory struct {
+	limits          Limits
+	verifyChecksums bool          // 是否丢弃校验和错误的报文
+	streams         *list.List    // 按活动时间排序的连接，表头为最近活动的连接
+	total           int           // 所有连接合计缓存的字节数
+	complete        func(*Stream) // 连接结束（或被淘汰）时的回调
+	closing         CloseReason   // 连接因重组器刷新而结束时记录的原因
+	next            int           // 下一个连接的序号
+	evicted         []*Stream     // 已淘汰、仍留在重组器连接池中等待释放的连接
+	releasing       bool          // 是否正在释放已淘汰的连接
+}
+
+// 创建流工厂，complete 在每个连接结束时被调用
+func NewFactory(limits Limits, complete func(*Stream)) *Factory {
+	return &Factory{
+		limits:   limits,
+		streams:  list.New(),
+		complete: complete,
+	}
+}
+
+func (factory *Factory) New(net gopacket.Flow, transport gopacket.Flow, tcp *layers.TCP, ac reassembly.AssemblerContext) reassembly.Stream {
+	// 释放时连接已不在连接池中（如已空闲超时），占位的连接随即被关闭，不计入统计
+	if factory.releasing {
+		return &Stream{factory: factory, completed: true}
+	}
+	s := &Stream{
+		factory:     factory,
+		index:       factory.next,
+		net:         net,
+		transport:   transport,
+		start:       ac.GetCaptureInfo().Timestamp,
+		fsm:         reassembly.NewTCPSimpleFSM(reassembly.TCPSimpleFSMOptions{SupportMissingEstablishment: true}),
+		optionCheck: reassembly.NewTCPOptionCheck(),
+	}
+	// 第一个报文是 SYN+ACK 时，它来自服务端
+	if tcp.SYN && tcp.ACK {
+		s.reversed = true
+		s.net, s.transport = net.Reverse(), transport.Reverse()
+	}
+	s.end = s.start
+	factory.next++
+	s.element = factory.streams.PushFront(s)
+	statistics.Add(STATISTICS_GROUP, "Streams", 1)
+
+	if factory.limits.MaxConnections > 0 {
+		for factory.streams.Len() > factory.limits.MaxConnections {
+			factory.evict(factory.streams.Back().Value.(*Stream), EVICTED_CONNECTION_LIMIT)
+		}
+	}
+	return s
+}
+
+// 正在跟踪的连接数量
+func (factory *Factory) Len() int {
+	return factory.streams.Len()
+}
+
+// 所有连接合计缓存的字节数
+func (factory *Factory) Total() int {
+	return factory.total
+}
+
+// 将连接标记为最近活动
+func (factory *Factory) touch(s *Stream) {
+	factory.streams.MoveToFront(s.element)
+}
+
+// 超出总缓存上限时，按最久未活动的顺序淘汰连接
+func (factory *Factory) enforceTotal() {
+	if factory.limits.MaxTotalBytes <= 0 {
+		return
+	}
+	for factory.total > factory.limits.MaxTotalBytes && factory.streams.Len() > 0 {
+		factory.evict(factory.streams.Back().Value.(*Stream), EVICTED_MEMORY_LIMIT)
+	}
+}
+
+// 淘汰连接，已缓存的数据立即交付；连接随后从重组器的连接池中释放，之后到达的报文属于新的连接
+func (factory *Factory) evict(s *Stream, reason EvictReason) {
+	statistics.Add(STATISTICS_GROUP, "Evicted ("+EVICT_REASON_NAME[reason]+")", 1)
+	s.evicted = reason
+	s.closed = CLOSED_EVICTED
+	s.truncated = true
+	factory.finish(s)
+	factory.evicted = append(factory.evicted, s)
+}
+
+// 结束连接并交付给回调
+func (factory *Factory) finish(s *Stream) {
+	if s.completed {
+		return
+	}
+	s.completed = true
+	if s.closed == CLOSED_NONE {
+		if s.client.fin && s.server.fin {
+			s.closed = CLOSED_FIN
+		} else {
+			s.closed = factory.closing
+		}
+	}
+	factory.streams.Remove(s.element)
+	factory.total -= s.retained
+	if s.truncated {
+		statistics.Add(STATISTICS_GROUP, "Truncated streams", 1)
+	}
+	if factory.complete != nil {
+		factory.complete(s)
+	}
+	s.client.segments = nil
+	s.server.segments = nil
+}
@@ -0,0 +1,287 @@
+package reassembler
+
+import (
+	"container/list"
+	"packet-inspector/statistics"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly"
+)
+
+type EvictReason uint8
+
+const (
+	EVICTED_NONE             EvictReason = 0
+	EVICTED_CONNECTION_LIMIT EvictReason = 1
+	EVICTED_MEMORY_LIMIT     EvictReason = 2
+)
+
+var EVICT_REASON_NAME = map[EvictReason]string{
+	EVICTED_NONE:             "none",
+	EVICTED_CONNECTION_LIMIT: "connection limit",
+	EVICTED_MEMORY_LIMIT:     "memory limit",
+}
+
+type CloseReason uint8
+
+const (
+	CLOSED_NONE        CloseReason = 0
+	CLOSED_FIN         CloseReason = 1
+	CLOSED_RST         CloseReason = 2
+	CLOSED_TIMEOUT     CloseReason = 3
+	CLOSED_CAPTURE_END CloseReason = 4
+	CLOSED_EVICTED     CloseReason = 5
+)
+
+var CLOSE_REASON_NAME = map[CloseReason]string{
+	CLOSED_NONE:        "not closed",
+	CLOSED_FIN:         "FIN",
+	CLOSED_RST:         "RST",
+	CLOSED_TIMEOUT:     "timeout",
+	CLOSED_CAPTURE_END: "end of capture",
+	CLOSED_EVICTED:     "eviction",
+}
+
+// 双向 TCP 连接
+type Stream struct {
+	factory     *Factory
+	element     *list.Element
+	index       int           // 连接序号，按创建顺序从 0 开始
+	net         gopacket.Flow // 客户端到服务端的网络层地址
+	transport   gopacket.Flow // 客户端到服务端的传输层端口
+	reversed    bool          // 重组器认定的方向是否与实际的客户端/服务端相反
+	client      Half          // 客户端发往服务端的数据
+	server      Half          // 服务端发往客户端的数据
+	turns       []Turn        // 双向数据按到达顺序排列的发言
+	fsm         *reassembly.TCPSimpleFSM
+	optionCheck reassembly.TCPOptionCheck
+	start       time.Time   // 第一个报文的抓包时间
+	end         time.Time   // 最后一个报文的抓包时间
+	retained    int         // 缓存的字节数
+	closed      CloseReason // 连接结束的原因
+	truncated   bool        // 是否有数据因内存限制被丢弃
+	dropped     int         // 被丢弃的字节数
+	evicted     EvictReason // 被淘汰的原因
+	completed   bool        // 是否已交付
+}
+
+// 连接序号，按创建顺序从 0 开始
+func (s *Stream) Index() int {
+	return s.index
+}
+
+// 客户端到服务端的网络层地址
+func (s *Stream) Net() gopacket.Flow {
+	return s.net
+}
+
+// 客户端到服务端的传输层端口
+func (s *Stream) Transport() gopacket.Flow {
+	return s.transport
+}
+
+// 客户端发往服务端的数据
+func (s *Stream) Client() *Half {
+	return &s.client
+}
+
+// 服务端发往客户端的数据
+func (s *Stream) Server() *Half {
+	return &s.server
+}
+
+// 双向数据按到达顺序排列的发言
+func (s *Stream) Turns() []Turn {
+	return s.turns
+}
+
+// 第一个报文的抓包时间
+func (s *Stream) Start() time.Time {
+	return s.start
+}
+
+// 最后一个报文的抓包时间
+func (s *Stream) End() time.Time {
+	return s.end
+}
+
+// 持续时间
+func (s *Stream) Duration() time.Duration {
+	return s.end.Sub(s.start)
+}
+
+// 双向合计收到的载荷字节数，包括被丢弃的部分
+func (s *Stream) Bytes() int {
+	return s.client.bytes + s.server.bytes
+}
+
+// 双向合计收到的报文数
+func (s *Stream) Packets() int {
+	return s.client.packets + s.server.packets
+}
+
+// 连接结束的原因
+func (s *Stream) Closed() CloseReason {
+	return s.closed
+}
+
+// 是否有数据因内存限制被丢弃
+func (s *Stream) Truncated() bool {
+	return s.truncated
+}
+
+// 因内存限制被丢弃的字节数
+func (s *Stream) Dropped() int {
+	return s.dropped
+}
+
+// 被淘汰的原因
+func (s *Stream) Evicted() EvictReason {
+	return s.evicted
+}
+
+// 以 "客户端地址:端口 -> 服务端地址:端口" 表示的连接
+func (s *Stream) Tuple() string {
+	return endpoint(s.net.Src(), s.transport.Src()) + " -> " + endpoint(s.net.Dst(), s.transport.Dst())
+}
+
+func endpoint(address gopacket.Endpoint, port gopacket.Endpoint) string {
+	if address.EndpointType() == layers.EndpointIPv6 {
+		return "[" + address.String() + "]:" + port.String()
+	}
+	return address.String() + ":" + port.String()
+}
+
+// 是否与选择器匹配，选择器为连接序号，或 "地址:端口-地址:端口" 形式的两个端点（不分先后）
+func (s *Stream) Match(selector string) bool {
+	selector = strings.TrimSpace(selector)
+	if index, err := strconv.Atoi(selector); err == nil {
+		return index == s.index
+	}
+	selector = strings.TrimPrefix(strings.TrimPrefix(selector, "tcp:"), "TCP:")
+	selector = strings.ReplaceAll(selector, "->", "-")
+	first, second, founded := strings.Cut(selector, "-")
+	if !founded {
+		return false
+	}
+	first, second = strings.TrimSpace(first), strings.TrimSpace(second)
+	client := endpoint(s.net.Src(), s.transport.Src())
+	server := endpoint(s.net.Dst(), s.transport.Dst())
+	return (first == client && second == server) || (first == server && second == client)
+}
+
+// 记录一次发言，与上一次发言方向相同时合并
+func (s *Stream) speak(half *Half, offset int, length int, gap bool) {
+	fromClient := half == &s.client
+	if last := len(s.turns) - 1; last >= 0 {
+		turn := &s.turns[last]
+		if turn.FromClient == fromClient && turn.Gap == gap && turn.Offset+turn.Length == offset {
+			turn.Length += length
+			return
+		}
+	}
+	s.turns = append(s.turns, Turn{FromClient: fromClient, Offset: offset, Length: length, Gap: gap})
+}
+
+// 重组器所认定方向对应的一侧数据
+func (s *Stream) half(dir reassembly.TCPFlowDirection) *Half {
+	if (dir == reassembly.TCPDirClientToServer) != s.reversed {
+		return &s.client
+	}
+	return &s.server
+}
+
+func (s *Stream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+	if context, ok := ac.(*context); ok && context.release {
+		// 释放连接的 RST 报文：放在下一个期望的序号上，使重组器立即关闭该方向
+		if nextSeq != -1 {
+			tcp.Seq = uint32(nextSeq)
+		} else {
+			*start = true
+		}
+		return true
+	}
+	half := s.half(dir)
+	if s.completed {
+		// 已被淘汰的连接，丢弃后续数据
+		s.dropped += len(tcp.Payload)
+		statistics.Add(STATISTICS_GROUP, "Dropped bytes", uint64(len(tcp.Payload)))
+		return false
+	}
+
+	half.packets++
+	if ci.Timestamp.After(s.end) {
+		s.end = ci.Timestamp
+	}
+	if tcp.RST {
+		s.closed = CLOSED_RST
+	}
+	if tcp.FIN {
+		half.fin = true
+	}
+
+	if context, ok := ac.(*context); ok && !context.checksumValid {
+		statistics.Add(STATISTICS_GROUP, "Bad checksums", 1)
+		if s.factory.verifyChecksums {
+			statistics.Add(STATISTICS_GROUP, "Rejected packets (checksum)", 1)
+			return false
+		}
+	}
+	if !s.fsm.CheckState(tcp, dir) {
+		statistics.Add(STATISTICS_GROUP, "Rejected packets (state)", 1)
+		return false
+	}
+
+	// 重传：与已交付的数据比较
+	retransmission := false
+	if nextSeq != -1 && len(tcp.Payload) != 0 {
+		diff := nextSeq.Difference(reassembly.Sequence(tcp.Seq))
+		keepAlive := diff == -1 && len(tcp.Payload) <= 1
+		if diff < 0 && !keepAlive {
+			retransmission = true
+			half.compare(half.offset+diff, tcp.Payload)
+		}
+	}
+	if err := s.optionCheck.Accept(tcp, ci, dir, nextSeq, start); err != nil && !retransmission {
+		statistics.Add(STATISTICS_GROUP, "Rejected packets (options)", 1)
+		return false
+	}
+	return true
+}
+
+func (s *Stream) ReassembledSG(sg reassembly.ScatterGather, ac reassembly.AssemblerContext) {
+	if s.completed {
+		return
+	}
+	dir, _, _, skip := sg.Info()
+	length, _ := sg.Lengths()
+	half := s.half(dir)
+
+	s.factory.touch(s)
+	half.overlaps += sg.Stats().OverlapBytes
+	half.deliver(sg.Fetch(length), skip, s)
+	s.factory.enforceTotal()
+}
+
+func (s *Stream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+	s.factory.finish(s)
+	return true
+}
+
+// 申请缓存 length 字节，返回实际允许缓存的字节数，超出单个连接上限的部分被丢弃
+func (s *Stream) reserve(length int) int {
+	kept := length
+	if limit := s.factory.limits.MaxStreamBytes; limit > 0 && s.retained+length > limit {
+		kept = max(limit-s.retained, 0)
+		s.truncated = true
+		s.dropped += length - kept
+		statistics.Add(STATISTICS_GROUP, "Dropped bytes", uint64(length-kept))
+	}
+	s.retained += kept
+	s.factory.total += kept
+	return kept
+}
@@ -6,10 +6,12 @@ import (
 	"packet-inspector/resolver"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type FlexRay struct {
 	resolver.IPacket
+	FlowNoted
 	raw              []byte
 	reserved         bool   // 缺省位（1 bit）
 	payloadIndicator bool   // 有效负载指示（1 bit）
@@ -32,6 +34,22 @@ func (flexray *FlexRay) Hex() string {
 	return strings.ToUpper(hex.EncodeToString(flexray.raw))
 }
 
+func (flexray *FlexRay) ID() uint16 {
+	return flexray.id
+}
+
+func (flexray *FlexRay) CycleCount() uint8 {
+	return flexray.cycleCount
+}
+
+func (flexray *FlexRay) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "flexray.id", Value: strconv.Itoa(int(flexray.id))},
+		{Name: "flexray.cycle", Value: strconv.Itoa(int(flexray.cycleCount))},
+	}
+	return append(fields, flexray.flowFields()...)
+}
+
 func (flexray *FlexRay) ToReadableString(indent int) string {
 	builder := new(strings.Builder)
 	tabs := make([]byte, indent)
@@ -117,6 +135,8 @@ func (flexray *FlexRay) ToReadableString(indent int) string {
 	builder.WriteString(fmt.Sprintf("%06X", flexray.trailer))
 	builder.WriteByte('\n')
 
+	builder.WriteString(flexray.flowString(tabs))
+
 	builder.Write(tabs)
 	builder.WriteString("Raw: ")
 	builder.WriteString(flexray.Hex())
@@ -161,3 +181,89 @@ func FlexRayResolve(packet []byte) resolver.IPacket {
 
 	return flexray
 }
+
+// 从字节流中切分出一条完整的 FlexRay 帧，返回其长度
+func FlexRayFrame(stream []byte) int {
+	if len(stream) < 8 {
+		return 0
+	}
+	length := int(stream[2]>>1)*2 + 8
+	if length > len(stream) {
+		return 0
+	}
+	return length
+}
+
+// FlexRay 周期计数器的取值范围
+const FLEXRAY_CYCLES = 64
+
+// 同一方向上同一 ID 的上一帧
+type flexRaySlot struct {
+	frame      int
+	cycle      uint8
+	repetition int // 由前两帧得出的周期间隔（周期复用时大于 1），0 表示未知
+}
+
+// 按方向与 ID 跟踪 FlexRay 周期计数器，周期计数与间隔不符时说明丢失了帧
+type flexRayTracker struct {
+	slots         map[[2]uint16]*flexRaySlot // 键为方向（1 表示由发起方发出）与 ID
+	frames        int
+	missed        int // 丢失的帧数
+	repeated      int // 周期计数与上一帧相同的帧数
+	discontinuity int // 周期计数不连续的次数
+}
+
+func newFlexRayTracker() FlowTracker {
+	return &flexRayTracker{slots: map[[2]uint16]*flexRaySlot{}}
+}
+
+func (tracker *flexRayTracker) Track(message resolver.IPacket, frame int, timestamp time.Time, forward bool) FlowNote {
+	flexray, ok := message.(*FlexRay)
+	if !ok {
+		return FlowNote{}
+	}
+	tracker.frames++
+	key := [2]uint16{0, flexray.id}
+	if forward {
+		key[0] = 1
+	}
+	slot := tracker.slots[key]
+	if slot == nil {
+		tracker.slots[key] = &flexRaySlot{frame: frame, cycle: flexray.cycleCount}
+		return FlowNote{Lines: []string{"First frame with this ID"}}
+	}
+
+	note := FlowNote{}
+	note.Lines = append(note.Lines, fmt.Sprintf("Previous frame with this ID: #%d (cycle %d)", slot.frame, slot.cycle))
+	note.Fields = append(note.Fields, resolver.Field{Name: "flexray.previous", Value: strconv.Itoa(slot.frame)})
+	delta := (int(flexray.cycleCount) - int(slot.cycle) + FLEXRAY_CYCLES) % FLEXRAY_CYCLES
+	switch {
+	case delta == 0:
+		tracker.repeated++
+		note.Lines = append(note.Lines, "Same cycle as the previous frame")
+		note.Experts = append(note.Experts, resolver.Expert{Severity: resolver.SEVERITY_NOTE, Group: resolver.GROUP_SEQUENCE, Protocol: "FlexRay", Message: "Repeated cycle count"})
+	case slot.repetition == 0:
+		slot.repetition = delta
+	case delta != slot.repetition:
+		tracker.discontinuity++
+		// 间隔不是周期间隔的整数倍时无法得出丢失的帧数
+		missed := 0
+		if delta%slot.repetition == 0 {
+			missed = delta/slot.repetition - 1
+			tracker.missed += missed
+		}
+		note.Lines = append(note.Lines, fmt.Sprintf("Cycle gap: %d cycles, expected %d (%d frames missed)", delta, slot.repetition, missed))
+		note.Fields = append(note.Fields, resolver.Field{Name: "flexray.cycle_gap", Value: strconv.Itoa(delta)})
+		note.Experts = append(note.Experts, resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SEQUENCE, Protocol: "FlexRay", Message: "Cycle count discontinuity"})
+	}
+	if slot.repetition != 0 {
+		note.Lines = append(note.Lines, "Cycle repetition: "+strconv.Itoa(slot.repetition))
+	}
+	slot.frame, slot.cycle = frame, flexray.cycleCount
+	return note
+}
+
+func (tracker *flexRayTracker) Summary() string {
+	return fmt.Sprintf("FlexRay: %d frames, %d IDs, %d discontinuities, %d frames missed, %d repeated",
+		tracker.frames, len(tracker.slots), tracker.discontinuity, tracker.missed, tracker.repeated)
+}
@@ -0,0 +1,66 @@
+package applicationlayer
+
+import (
+	"packet-inspector/resolver"
+	"strings"
+	"time"
+)
+
+// 按 UDP 流保存应用层状态的跟踪器，每个流为每种协议创建一个，消息必须按抓包顺序送入
+type FlowTracker interface {
+	// 跟踪一条消息并返回对它的注解，forward 表示消息由流的发起方发出
+	Track(message resolver.IPacket, frame int, timestamp time.Time, forward bool) FlowNote
+	// 一行摘要，如 "PieP: 3 requests, 2 responses"
+	Summary() string
+}
+
+// 各协议的跟踪器构造函数，键为 Resolvers 中的名称
+var FlowTrackers = map[string]func() FlowTracker{}
+
+// 跟踪器对一条消息的注解
+type FlowNote struct {
+	Lines   []string          // 附加到消息输出中的说明，如 "Response to: frame #3"
+	Fields  []resolver.Field  // 附加的过滤字段
+	Experts []resolver.Expert // 附加的专家信息
+}
+
+// 可以附加流注解的消息
+type IFlowNoted interface {
+	SetFlowNote(note FlowNote)
+}
+
+// 嵌入到消息结构体中，为消息提供流注解及其专家信息
+type FlowNoted struct {
+	resolver.ExpertInfo
+	note FlowNote
+}
+
+func (noted *FlowNoted) SetFlowNote(note FlowNote) {
+	noted.note = note
+	for _, expert := range note.Experts {
+		noted.AddExpert(expert.Severity, expert.Group, expert.Protocol, expert.Message)
+	}
+}
+
+func (noted *FlowNoted) flowFields() []resolver.Field {
+	return noted.note.Fields
+}
+
+// 注解的可读形式，没有注解时为空
+func (noted *FlowNoted) flowString(tabs []byte) string {
+	if len(noted.note.Lines) == 0 {
+		return ""
+	}
+	builder := new(strings.Builder)
+	builder.Write(tabs)
+	builder.WriteString("Flow: {\n")
+	for _, line := range noted.note.Lines {
+		builder.Write(tabs)
+		builder.WriteByte('\t')
+		builder.WriteString(line)
+		builder.WriteByte('\n')
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+	return builder.String()
+}
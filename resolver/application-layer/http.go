@@ -1,6 +1,7 @@
 package applicationlayer
 
 import (
+	"bytes"
 	"encoding/hex"
 	"packet-inspector/resolver"
 	"strconv"
@@ -129,27 +130,47 @@ func HTTPResolve(packet []byte) resolver.IPacket {
 	if !founded {
 		return nil
 	}
-
-	line, headers, founded := strings.Cut(header, "\r\n")
-	if !founded {
+	if !http.parseHeader(header) {
 		return nil
 	}
 
-	temp := strings.Split(line, " ")
+	if http.isChunked() {
+		decoded, length := httpDechunk([]byte(body))
+		if length != len(body) {
+			return nil
+		}
+		http.body = decoded
+	} else {
+		http.body = []byte(body)
+	}
+	http.raw = make([]byte, len(packet))
+	copy(http.raw, packet)
+
+	return http
+}
+
+// 解析起始行与请求头
+func (http *HTTP) parseHeader(header string) bool {
+	line, headers, _ := strings.Cut(header, "\r\n")
+
+	temp := strings.SplitN(line, " ", 3)
 	if len(temp) != 3 {
-		return nil
+		return false
 	}
 
-	if strings.HasPrefix(temp[0], "HTTP") {
+	if strings.HasPrefix(temp[0], "HTTP/") {
 		http.packetType = HTTP_RESPONSE
 		http.version = temp[0]
 		statusCode, err := strconv.Atoi(temp[1])
 		if err != nil {
-			return nil
+			return false
 		}
 		http.statusCode = uint16(statusCode)
 		http.statusMessage = temp[2]
 	} else {
+		if !strings.HasPrefix(temp[2], "HTTP/") {
+			return false
+		}
 		http.packetType = HTTP_REQUEST
 		http.method = temp[0]
 		http.url = temp[1]
@@ -157,18 +178,114 @@ func HTTPResolve(packet []byte) resolver.IPacket {
 	}
 
 	http.headers = map[string]string{}
+	if headers == "" {
+		return true
+	}
 	lines := strings.Split(headers, "\r\n")
 	for _, line := range lines {
 		key, value, founded := strings.Cut(line, ":")
 		if !founded {
-			return nil
+			return false
 		}
 		http.headers[key] = strings.Trim(value, " ")
 	}
+	return true
+}
 
-	http.body = []byte(body)
-	http.raw = make([]byte, len(packet))
-	copy(http.raw, packet)
+// 查找请求头，忽略大小写
+func (http *HTTP) header(key string) (string, bool) {
+	for k, v := range http.headers {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
 
-	return http
+// 载荷是否使用分块传输编码
+func (http *HTTP) isChunked() bool {
+	value, founded := http.header("Transfer-Encoding")
+	if !founded {
+		return false
+	}
+	codings := strings.Split(value, ",")
+	return strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked")
+}
+
+// 载荷是否一直延续到连接关闭
+func (http *HTTP) isUntilClose() bool {
+	if http.packetType == HTTP_REQUEST {
+		return false
+	}
+	return http.statusCode >= 200 && http.statusCode != 204 && http.statusCode != 304
+}
+
+// 解码分块传输编码的载荷，返回解码后的数据以及消耗的字节数；数据不完整时返回 -1
+func httpDechunk(body []byte) ([]byte, int) {
+	decoded := []byte{}
+	offset := 0
+	for {
+		end := bytes.Index(body[offset:], []byte("\r\n"))
+		if end < 0 {
+			return nil, -1
+		}
+		size, _, _ := strings.Cut(string(body[offset:offset+end]), ";")
+		length, err := strconv.ParseUint(strings.TrimSpace(size), 16, 31)
+		if err != nil {
+			return nil, -1
+		}
+		offset += end + 2
+
+		if length == 0 {
+			// 跳过尾部首部，直至空行
+			for {
+				end = bytes.Index(body[offset:], []byte("\r\n"))
+				if end < 0 {
+					return nil, -1
+				}
+				offset += end + 2
+				if end == 0 {
+					return decoded, offset
+				}
+			}
+		}
+
+		if offset+int(length)+2 > len(body) || body[offset+int(length)] != '\r' || body[offset+int(length)+1] != '\n' {
+			return nil, -1
+		}
+		decoded = append(decoded, body[offset:offset+int(length)]...)
+		offset += int(length) + 2
+	}
+}
+
+// 从字节流中切分出一条完整的 HTTP 消息，返回其长度
+func HTTPFrame(stream []byte) int {
+	end := bytes.Index(stream, []byte("\r\n\r\n"))
+	if end < 0 {
+		return 0
+	}
+	http := new(HTTP)
+	if !http.parseHeader(string(stream[:end])) {
+		return 0
+	}
+	headerLength := end + 4
+
+	if http.isChunked() {
+		_, length := httpDechunk(stream[headerLength:])
+		if length < 0 {
+			return 0
+		}
+		return headerLength + length
+	}
+	if value, founded := http.header("Content-Length"); founded {
+		length, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || length < 0 || headerLength+length > len(stream) {
+			return 0
+		}
+		return headerLength + length
+	}
+	if http.isUntilClose() {
+		return len(stream)
+	}
+	return headerLength
 }
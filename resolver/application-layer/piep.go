@@ -7,10 +7,15 @@ import (
 	"packet-inspector/utils"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// PieP 帧的起始位，固定为 0xAA
+const PIEP_START_BIT uint8 = 0xAA
+
 type PieP struct {
 	resolver.IPacket
+	FlowNoted
 	raw        []byte // 原始报文
 	startBit   uint8  // 起始位
 	address    uint32 // 设备地址
@@ -27,6 +32,23 @@ func (piep *PieP) Hex() string {
 	return strings.ToUpper(hex.EncodeToString(piep.raw))
 }
 
+func (piep *PieP) Address() uint32 {
+	return piep.address
+}
+
+func (piep *PieP) FrameType() uint8 {
+	return piep.frameType
+}
+
+func (piep *PieP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "piep.address", Value: fmt.Sprintf("0x%08X", piep.address)},
+		{Name: "piep.type", Value: fmt.Sprintf("0x%02X", piep.frameType)},
+		{Name: "piep.len", Value: strconv.Itoa(int(piep.dataLength))},
+	}
+	return append(fields, piep.flowFields()...)
+}
+
 func (piep *PieP) ToReadableString(indent int) string {
 	builder := new(strings.Builder)
 	tabs := make([]byte, indent)
@@ -62,6 +84,8 @@ func (piep *PieP) ToReadableString(indent int) string {
 	builder.WriteString(strings.ToUpper(hex.EncodeToString(piep.payload)))
 	builder.WriteByte('\n')
 
+	builder.WriteString(piep.flowString(tabs))
+
 	builder.Write(tabs)
 	builder.WriteString("Raw: ")
 	builder.WriteString(piep.Hex())
@@ -83,7 +107,7 @@ func PiePResolve(packet []byte) resolver.IPacket {
 	piep.frameType = utils.ExtractUint8BE(packet, 5)
 	piep.dataLength = utils.ExtractUint8BE(packet, 6)
 
-	if int(piep.dataLength)+7 != length {
+	if !piepHeaderValid(packet) || int(piep.dataLength)+7 != length {
 		return nil
 	}
 	if piep.dataLength > 0 {
@@ -97,3 +121,93 @@ func PiePResolve(packet []byte) resolver.IPacket {
 
 	return piep
 }
+
+// 报文头是否符合 PieP：起始位固定，帧类型不能是全 0 或全 1（多为填充或噪声）
+func piepHeaderValid(packet []byte) bool {
+	return packet[0] == PIEP_START_BIT && packet[5] != 0x00 && packet[5] != 0xFF
+}
+
+// 从字节流中切分出一条完整的 PieP 报文，返回其长度
+func PiePFrame(stream []byte) int {
+	if len(stream) < 7 || !piepHeaderValid(stream) {
+		return 0
+	}
+	length := int(utils.ExtractUint8BE(stream, 6)) + 7
+	if length > len(stream) {
+		return 0
+	}
+	return length
+}
+
+// 等待响应的 PieP 请求
+type piepRequest struct {
+	frame     int
+	timestamp time.Time
+	raw       []byte
+}
+
+// 按设备地址匹配 PieP 请求与响应：流的发起方发出的消息为请求，另一方发出的同一地址的消息为对它的响应
+type piepTracker struct {
+	pending    map[uint32]piepRequest // 按设备地址等待响应的请求
+	requests   int
+	responses  int
+	repeated   int // 重复发送的请求数
+	unanswered int // 被同一地址的新请求取代而没有得到响应的请求数
+	unmatched  int // 没有对应请求的响应数
+}
+
+func newPiePTracker() FlowTracker {
+	return &piepTracker{pending: map[uint32]piepRequest{}}
+}
+
+func (tracker *piepTracker) Track(message resolver.IPacket, frame int, timestamp time.Time, forward bool) FlowNote {
+	piep, ok := message.(*PieP)
+	if !ok {
+		return FlowNote{}
+	}
+	note := FlowNote{}
+	if forward {
+		tracker.requests++
+		note.Lines = append(note.Lines, "Role: Request")
+		note.Fields = append(note.Fields, resolver.Field{Name: "piep.request", Value: "1"})
+		if previous, ok := tracker.pending[piep.address]; ok {
+			if string(previous.raw) == string(piep.raw) {
+				tracker.repeated++
+				note.Lines = append(note.Lines, "Repeats request in frame #"+strconv.Itoa(previous.frame))
+				note.Fields = append(note.Fields, resolver.Field{Name: "piep.repeated", Value: strconv.Itoa(previous.frame)})
+				note.Experts = append(note.Experts, resolver.Expert{Severity: resolver.SEVERITY_NOTE, Group: resolver.GROUP_SEQUENCE, Protocol: "PieP", Message: "Repeated request"})
+			} else {
+				tracker.unanswered++
+				note.Lines = append(note.Lines, "Request in frame #"+strconv.Itoa(previous.frame)+" was not answered")
+				note.Experts = append(note.Experts, resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SEQUENCE, Protocol: "PieP", Message: "Previous request not answered"})
+			}
+		}
+		tracker.pending[piep.address] = piepRequest{frame: frame, timestamp: timestamp, raw: piep.raw}
+		return note
+	}
+
+	tracker.responses++
+	note.Lines = append(note.Lines, "Role: Response")
+	note.Fields = append(note.Fields, resolver.Field{Name: "piep.response", Value: "1"})
+	request, ok := tracker.pending[piep.address]
+	if !ok {
+		tracker.unmatched++
+		note.Lines = append(note.Lines, "Response to: (request not seen)")
+		note.Experts = append(note.Experts, resolver.Expert{Severity: resolver.SEVERITY_WARN, Group: resolver.GROUP_SEQUENCE, Protocol: "PieP", Message: "Response without request"})
+		return note
+	}
+	delete(tracker.pending, piep.address)
+	elapsed := timestamp.Sub(request.timestamp)
+	note.Lines = append(note.Lines, "Response to: frame #"+strconv.Itoa(request.frame), "Response time: "+elapsed.String())
+	note.Fields = append(note.Fields,
+		resolver.Field{Name: "piep.response_to", Value: strconv.Itoa(request.frame)},
+		resolver.Field{Name: "piep.time_us", Value: strconv.FormatInt(elapsed.Microseconds(), 10)},
+	)
+	return note
+}
+
+// 仍在等待响应的请求也计为未得到响应
+func (tracker *piepTracker) Summary() string {
+	return fmt.Sprintf("PieP: %d requests, %d responses, %d repeated, %d unanswered, %d without request",
+		tracker.requests, tracker.responses, tracker.repeated, tracker.unanswered+len(tracker.pending), tracker.unmatched)
+}
@@ -4,8 +4,49 @@ import "packet-inspector/resolver"
 
 var Resolvers = map[string]resolver.PacketResolver{}
 
+var Framers = map[string]resolver.MessageFramer{}
+
+// 切分字节流时尝试各协议的顺序，约束越严格的协议越靠前，以免被宽松的协议误识别
+var FramerPriority = []string{}
+
+// 特征较弱的长度前缀协议，只有从字节流开头起连续的消息都能按该协议切分到末尾时才认定字节流属于该协议
+var FramerConsistent = map[string]bool{}
+
 func init() {
 	Resolvers["PieP"] = PiePResolve
 	Resolvers["FlexRay"] = FlexRayResolve
 	Resolvers["HTTP"] = HTTPResolve
+
+	FlowTrackers["PieP"] = newPiePTracker
+	FlowTrackers["FlexRay"] = newFlexRayTracker
+
+	Framers["PieP"] = PiePFrame
+	Framers["FlexRay"] = FlexRayFrame
+	Framers["HTTP"] = HTTPFrame
+
+	FramerPriority = append(FramerPriority, "HTTP", "PieP", "FlexRay")
+	FramerConsistent["PieP"] = true
+	FramerConsistent["FlexRay"] = true
+}
+
+// 按 FramerPriority 的顺序（其余协议随后）尝试解析一条完整的消息，返回解析结果与协议名，无法解析时返回 nil
+func Resolve(packet []byte) (resolver.IPacket, string) {
+	tried := map[string]bool{}
+	for _, name := range FramerPriority {
+		tried[name] = true
+		if resolve := Resolvers[name]; resolve != nil {
+			if message := resolve(packet); message != nil {
+				return message, name
+			}
+		}
+	}
+	for name, resolve := range Resolvers {
+		if tried[name] {
+			continue
+		}
+		if message := resolve(packet); message != nil {
+			return message, name
+		}
+	}
+	return nil, ""
 }
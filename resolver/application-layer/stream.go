@@ -0,0 +1,99 @@
+package applicationlayer
+
+import "packet-inspector/resolver"
+
+// 判断末尾的不完整消息时补足的长度，不小于各协议的最大消息长度
+const MAX_FRAME_LENGTH = 65536
+
+// 将重组后的字节流切分为连续的应用层消息，返回解析出的消息以及无法解析的剩余字节
+func StreamResolve(stream []byte) ([]resolver.IPacket, []byte) {
+	messages := []resolver.IPacket{}
+	previous := ""
+	for len(stream) != 0 {
+		message, name, length := frameResolve(stream, previous)
+		if message == nil {
+			break
+		}
+		messages = append(messages, message)
+		previous = name
+		stream = stream[length:]
+	}
+	return messages, stream
+}
+
+// 尝试从字节流开头解析一条消息，优先使用上一条消息的协议
+func frameResolve(stream []byte, previous string) (resolver.IPacket, string, int) {
+	if previous != "" {
+		if message, length := frameResolveWith(stream, previous, previous); message != nil {
+			return message, previous, length
+		}
+		// 末尾是上一条消息所属协议的一条不完整的消息，不改用其他协议
+		if Framers[previous] != nil && frameIncomplete(stream, previous) {
+			return nil, "", 0
+		}
+	}
+	tried := map[string]bool{previous: true}
+	for _, name := range FramerPriority {
+		if tried[name] {
+			continue
+		}
+		tried[name] = true
+		if message, length := frameResolveWith(stream, name, previous); message != nil {
+			return message, name, length
+		}
+	}
+	for name := range Framers {
+		if tried[name] {
+			continue
+		}
+		if message, length := frameResolveWith(stream, name, previous); message != nil {
+			return message, name, length
+		}
+	}
+	return nil, "", 0
+}
+
+// 按指定协议解析字节流开头的一条消息；首次认定特征较弱的协议时要求整个字节流都能按该协议连续切分
+func frameResolveWith(stream []byte, name string, previous string) (resolver.IPacket, int) {
+	frame, resolve := Framers[name], Resolvers[name]
+	if frame == nil || resolve == nil {
+		return nil, 0
+	}
+	length := frame(stream)
+	if length <= 0 || length > len(stream) {
+		return nil, 0
+	}
+	message := resolve(stream[:length])
+	if message == nil || (name != previous && FramerConsistent[name] && !frameConsistent(stream[length:], name)) {
+		return nil, 0
+	}
+	return message, length
+}
+
+// 剩余的字节流能否按指定协议连续切分到末尾，末尾允许有一条不完整的消息
+func frameConsistent(stream []byte, name string) bool {
+	frame, resolve := Framers[name], Resolvers[name]
+	for len(stream) != 0 {
+		length := frame(stream)
+		if length <= 0 || length > len(stream) {
+			return frameIncomplete(stream, name)
+		}
+		if resolve(stream[:length]) == nil {
+			return false
+		}
+		stream = stream[length:]
+	}
+	return true
+}
+
+// 识别字节流所属的应用层协议，返回能解析出第一条消息的协议名；无法识别时返回空字符串
+func StreamProtocol(stream []byte) string {
+	_, name, _ := frameResolve(stream, "")
+	return name
+}
+
+// 字节流是否为指定协议的一条不完整的消息：补足长度后能切分出比现有数据更长的消息，说明报文头合法、只是数据不完整
+func frameIncomplete(stream []byte, name string) bool {
+	padded := append(stream[:len(stream):len(stream)], make([]byte, MAX_FRAME_LENGTH)...)
+	return Framers[name](padded) > len(stream)
+}
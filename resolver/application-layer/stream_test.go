@@ -0,0 +1,44 @@
+package applicationlayer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func piepFrame(address byte, frameType byte, data ...byte) []byte {
+	return append([]byte{PIEP_START_BIT, 0, 0, 0, address, frameType, byte(len(data))}, data...)
+}
+
+// TLS ClientHello 之后跟随随机字节的字节流不应被识别为 PieP
+func TestStreamResolveNonPieP(t *testing.T) {
+	stream := []byte{
+		0x16, 0x03, 0x01, 0x00, 0x2F, // TLS 记录头：握手，长度 47
+		0x01, 0x00, 0x00, 0x2B, 0x03, 0x03, // ClientHello
+	}
+	random := rand.New(rand.NewSource(1))
+	for range 200 {
+		stream = append(stream, byte(random.Intn(256)))
+	}
+	if name := StreamProtocol(stream); name == "PieP" {
+		t.Fatalf("StreamProtocol() = %q, want not PieP", name)
+	}
+	messages, _ := StreamResolve(stream)
+	for _, message := range messages {
+		if _, ok := message.(*PieP); ok {
+			t.Fatalf("StreamResolve() returned a PieP message from a TLS stream")
+		}
+	}
+}
+
+// 连续的 PieP 帧（末尾一条不完整）仍被切分为 PieP
+func TestStreamResolvePieP(t *testing.T) {
+	stream := append(piepFrame(1, 0x01, 0x10), piepFrame(1, 0x02, 0x20, 0x21)...)
+	stream = append(stream, piepFrame(2, 0x01, 0x30, 0x31, 0x32)[:8]...)
+	if name := StreamProtocol(stream); name != "PieP" {
+		t.Fatalf("StreamProtocol() = %q, want PieP", name)
+	}
+	messages, leftover := StreamResolve(stream)
+	if len(messages) != 2 || len(leftover) != 8 {
+		t.Fatalf("StreamResolve() = %d messages, %d bytes left, want 2 messages, 8 bytes left", len(messages), len(leftover))
+	}
+}
@@ -0,0 +1,335 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// CDP TLV 类型
+const (
+	CDP_TLV_DEVICE_ID            uint16 = 0x0001
+	CDP_TLV_ADDRESSES            uint16 = 0x0002
+	CDP_TLV_PORT_ID              uint16 = 0x0003
+	CDP_TLV_CAPABILITIES         uint16 = 0x0004
+	CDP_TLV_SOFTWARE_VERSION     uint16 = 0x0005
+	CDP_TLV_PLATFORM             uint16 = 0x0006
+	CDP_TLV_IP_PREFIXES          uint16 = 0x0007
+	CDP_TLV_VTP_DOMAIN           uint16 = 0x0009
+	CDP_TLV_NATIVE_VLAN          uint16 = 0x000A
+	CDP_TLV_DUPLEX               uint16 = 0x000B
+	CDP_TLV_APPLIANCE_VLAN       uint16 = 0x000E
+	CDP_TLV_POWER                uint16 = 0x0010
+	CDP_TLV_MTU                  uint16 = 0x0011
+	CDP_TLV_SYSTEM_NAME          uint16 = 0x0014
+	CDP_TLV_MANAGEMENT_ADDRESSES uint16 = 0x0016
+	CDP_TLV_POWER_AVAILABLE      uint16 = 0x001A
+)
+
+var CDP_TLV_NAME = map[uint16]string{
+	CDP_TLV_DEVICE_ID:            "Device ID",
+	CDP_TLV_ADDRESSES:            "Addresses",
+	CDP_TLV_PORT_ID:              "Port ID",
+	CDP_TLV_CAPABILITIES:         "Capabilities",
+	CDP_TLV_SOFTWARE_VERSION:     "Software version",
+	CDP_TLV_PLATFORM:             "Platform",
+	CDP_TLV_IP_PREFIXES:          "IP prefixes",
+	CDP_TLV_VTP_DOMAIN:           "VTP management domain",
+	CDP_TLV_NATIVE_VLAN:          "Native VLAN",
+	CDP_TLV_DUPLEX:               "Duplex",
+	CDP_TLV_APPLIANCE_VLAN:       "Appliance VLAN",
+	CDP_TLV_POWER:                "Power consumption",
+	CDP_TLV_MTU:                  "MTU",
+	CDP_TLV_SYSTEM_NAME:          "System name",
+	CDP_TLV_MANAGEMENT_ADDRESSES: "Management addresses",
+	CDP_TLV_POWER_AVAILABLE:      "Power available",
+}
+
+// 设备能力，按位表示
+var CDP_CAPABILITY_NAME = map[uint32]string{
+	0x0001: "Router",
+	0x0002: "Transparent bridge",
+	0x0004: "Source route bridge",
+	0x0008: "Switch",
+	0x0010: "Host",
+	0x0020: "IGMP capable",
+	0x0040: "Repeater",
+	0x0080: "VoIP phone",
+	0x0100: "Remotely managed",
+	0x0200: "CVTA",
+	0x0400: "Two-port MAC relay",
+}
+
+// 一个 CDP TLV
+type CDPTLV struct {
+	Type  uint16 // 类型
+	Value []byte // 值，不含 4 字节的类型与长度
+}
+
+// Cisco 发现协议
+type CDP struct {
+	resolver.IPacket
+	raw      []byte   // 原始数据
+	version  uint8    // 版本，1 或 2
+	ttl      uint8    // 生存时间（秒）
+	checksum uint16   // 校验和
+	tlvs     []CDPTLV // 所有 TLV
+}
+
+func (cdp *CDP) Raw() []byte {
+	return cdp.raw
+}
+
+func (cdp *CDP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(cdp.raw))
+}
+
+func (cdp *CDP) Inner() resolver.IPacket {
+	return nil
+}
+
+func (cdp *CDP) TLVs() []CDPTLV {
+	return cdp.tlvs
+}
+
+// 生存时间（秒）
+func (cdp *CDP) TTL() uint8 {
+	return cdp.ttl
+}
+
+// 第一个指定类型的 TLV 的值，不存在时返回 nil
+func (cdp *CDP) value(tlvType uint16) []byte {
+	for _, tlv := range cdp.tlvs {
+		if tlv.Type == tlvType {
+			return tlv.Value
+		}
+	}
+	return nil
+}
+
+func (cdp *CDP) DeviceID() string {
+	return string(cdp.value(CDP_TLV_DEVICE_ID))
+}
+
+func (cdp *CDP) PortID() string {
+	return string(cdp.value(CDP_TLV_PORT_ID))
+}
+
+func (cdp *CDP) Platform() string {
+	return string(cdp.value(CDP_TLV_PLATFORM))
+}
+
+func (cdp *CDP) SoftwareVersion() string {
+	return string(cdp.value(CDP_TLV_SOFTWARE_VERSION))
+}
+
+func (cdp *CDP) SystemName() string {
+	return string(cdp.value(CDP_TLV_SYSTEM_NAME))
+}
+
+// 设备能力
+func (cdp *CDP) Capabilities() uint32 {
+	value := cdp.value(CDP_TLV_CAPABILITIES)
+	if len(value) < 4 {
+		return 0
+	}
+	return utils.ExtractUint32BE(value, 0)
+}
+
+// 本征 VLAN，不存在时返回 0
+func (cdp *CDP) NativeVLAN() uint16 {
+	value := cdp.value(CDP_TLV_NATIVE_VLAN)
+	if len(value) < 2 {
+		return 0
+	}
+	return utils.ExtractUint16BE(value, 0)
+}
+
+// 地址与管理地址
+func (cdp *CDP) Addresses() []string {
+	addresses := []string{}
+	for _, tlv := range cdp.tlvs {
+		if tlv.Type == CDP_TLV_ADDRESSES || tlv.Type == CDP_TLV_MANAGEMENT_ADDRESSES {
+			addresses = append(addresses, cdpAddresses(tlv.Value)...)
+		}
+	}
+	return addresses
+}
+
+// 按位表示的能力的名称列表
+func CDPCapabilityNames(capabilities uint32) []string {
+	names := []string{}
+	for bit := uint32(1); bit <= 0x0400; bit <<= 1 {
+		if capabilities&bit != 0 {
+			names = append(names, CDP_CAPABILITY_NAME[bit])
+		}
+	}
+	return names
+}
+
+// 解析地址列表：4 字节的数量，之后每个地址为协议类型、协议长度、协议、2 字节地址长度与地址
+func cdpAddresses(value []byte) []string {
+	addresses := []string{}
+	if len(value) < 4 {
+		return addresses
+	}
+	count := int(utils.ExtractUint32BE(value, 0))
+	offset := 4
+	for range count {
+		if offset+2 > len(value) {
+			break
+		}
+		protocolType, protocolLength := value[offset], int(value[offset+1])
+		if offset+2+protocolLength+2 > len(value) {
+			break
+		}
+		protocol := value[offset+2 : offset+2+protocolLength]
+		offset += 2 + protocolLength
+		addressLength := int(utils.ExtractUint16BE(value, offset))
+		offset += 2
+		if offset+addressLength > len(value) {
+			break
+		}
+		address := value[offset : offset+addressLength]
+		offset += addressLength
+
+		family := uint8(0)
+		switch {
+		// NLPID 0xCC 为 IPv4
+		case protocolType == 1 && protocolLength == 1 && protocol[0] == 0xCC:
+			family = ADDRESS_FAMILY_IPv4
+		// 802.2 SNAP 头部，最后两字节为以太网帧类型
+		case protocolType == 2 && protocolLength == 8 && utils.ExtractUint16BE(protocol, 6) == ETHERNET_PROTOCOL_IPv6:
+			family = ADDRESS_FAMILY_IPv6
+		case protocolType == 2 && protocolLength == 8 && utils.ExtractUint16BE(protocol, 6) == ETHERNET_PROTOCOL_IPv4:
+			family = ADDRESS_FAMILY_IPv4
+		}
+		addresses = append(addresses, familyAddress(family, address))
+	}
+	return addresses
+}
+
+func (cdp *CDP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "cdp.version", Value: strconv.Itoa(int(cdp.version))},
+		{Name: "cdp.ttl", Value: strconv.Itoa(int(cdp.ttl))},
+		{Name: "cdp.device_id", Value: cdp.DeviceID()},
+		{Name: "cdp.port_id", Value: cdp.PortID()},
+	}
+	if platform := cdp.Platform(); platform != "" {
+		fields = append(fields, resolver.Field{Name: "cdp.platform", Value: platform})
+	}
+	if vlan := cdp.NativeVLAN(); vlan != 0 {
+		fields = append(fields, resolver.Field{Name: "cdp.native_vlan", Value: strconv.Itoa(int(vlan))})
+	}
+	for _, address := range cdp.Addresses() {
+		fields = append(fields, resolver.Field{Name: "cdp.address", Value: address})
+	}
+	return fields
+}
+
+func (cdp *CDP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: CDP (Datalink)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Version: ")
+	builder.WriteString(strconv.Itoa(int(cdp.version)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("TTL: ")
+	builder.WriteString(strconv.Itoa(int(cdp.ttl)))
+	builder.WriteString(" s\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Checksum: ")
+	builder.WriteString(fmt.Sprintf("0x%04X", cdp.checksum))
+	builder.WriteByte('\n')
+
+	for _, tlv := range cdp.tlvs {
+		builder.Write(tabs)
+		if name := CDP_TLV_NAME[tlv.Type]; name != "" {
+			builder.WriteString(name)
+		} else {
+			builder.WriteString(fmt.Sprintf("TLV type 0x%04X", tlv.Type))
+		}
+		builder.WriteString(": ")
+		value := tlv.Value
+		switch {
+		case tlv.Type == CDP_TLV_DEVICE_ID || tlv.Type == CDP_TLV_PORT_ID || tlv.Type == CDP_TLV_SOFTWARE_VERSION ||
+			tlv.Type == CDP_TLV_PLATFORM || tlv.Type == CDP_TLV_VTP_DOMAIN || tlv.Type == CDP_TLV_SYSTEM_NAME:
+			builder.WriteString(strconv.Quote(string(value)))
+		case tlv.Type == CDP_TLV_ADDRESSES || tlv.Type == CDP_TLV_MANAGEMENT_ADDRESSES:
+			builder.WriteString(strings.Join(cdpAddresses(value), ", "))
+		case tlv.Type == CDP_TLV_CAPABILITIES && len(value) >= 4:
+			capabilities := utils.ExtractUint32BE(value, 0)
+			builder.WriteString(fmt.Sprintf("0x%08X (", capabilities))
+			builder.WriteString(strings.Join(CDPCapabilityNames(capabilities), ", "))
+			builder.WriteString(")")
+		case (tlv.Type == CDP_TLV_NATIVE_VLAN || tlv.Type == CDP_TLV_POWER) && len(value) >= 2:
+			builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(value, 0))))
+			if tlv.Type == CDP_TLV_POWER {
+				builder.WriteString(" mW")
+			}
+		case tlv.Type == CDP_TLV_APPLIANCE_VLAN && len(value) >= 3:
+			builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(value, 1))))
+		case tlv.Type == CDP_TLV_DUPLEX && len(value) >= 1:
+			if value[0] != 0 {
+				builder.WriteString("Full")
+			} else {
+				builder.WriteString("Half")
+			}
+		case tlv.Type == CDP_TLV_MTU && len(value) >= 4:
+			builder.WriteString(strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10))
+		default:
+			builder.WriteString(strings.ToUpper(hex.EncodeToString(value)))
+		}
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(cdp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 CDP，packet 从版本开始
+func CDPResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 4 || (packet[0] != 1 && packet[0] != 2) {
+		return nil
+	}
+
+	cdp := new(CDP)
+	cdp.version = packet[0]
+	cdp.ttl = packet[1]
+	cdp.checksum = utils.ExtractUint16BE(packet, 2)
+	offset := 4
+	for offset+4 <= length {
+		size := int(utils.ExtractUint16BE(packet, offset+2))
+		if size < 4 || offset+size > length {
+			return nil
+		}
+		tlv := CDPTLV{Type: utils.ExtractUint16BE(packet, offset)}
+		tlv.Value = make([]byte, size-4)
+		copy(tlv.Value, packet[offset+4:offset+size])
+		cdp.tlvs = append(cdp.tlvs, tlv)
+		offset += size
+	}
+	cdp.raw = make([]byte, offset)
+	copy(cdp.raw, packet)
+
+	return cdp
+}
@@ -7,27 +7,63 @@ import (
 	networklayer "packet-inspector/resolver/network-layer"
 	"packet-inspector/types"
 	"packet-inspector/utils"
+	"strconv"
 	"strings"
 )
 
 type EthernetInnerProtocol uint16
 
 const (
-	ETHERNET_PROTOCOL_IPv4 uint16 = 0x0800
-	ETHERNET_PROTOCOL_ARP  uint16 = 0x0806
-	ETHERNET_PROTOCOL_IPv6 uint16 = 0x86DD
+	ETHERNET_PROTOCOL_IPv4   uint16 = 0x0800
+	ETHERNET_PROTOCOL_ARP    uint16 = 0x0806
+	ETHERNET_PROTOCOL_RARP   uint16 = 0x8035
+	ETHERNET_PROTOCOL_8021Q  uint16 = 0x8100 // VLAN C-Tag
+	ETHERNET_PROTOCOL_IPv6   uint16 = 0x86DD
+	ETHERNET_PROTOCOL_MPLS   uint16 = 0x8847
+	ETHERNET_PROTOCOL_MPLSMC uint16 = 0x8848 // MPLS 组播
+	ETHERNET_PROTOCOL_8021AD uint16 = 0x88A8 // QinQ S-Tag
+	ETHERNET_PROTOCOL_LLDP   uint16 = 0x88CC
+	ETHERNET_PROTOCOL_QINQ   uint16 = 0x9100 // 旧式 QinQ 外层标签
 )
 
 var ETHERNET_PROTOCOL_NAME = map[uint16]string{
-	ETHERNET_PROTOCOL_IPv4: "IPv4",
-	ETHERNET_PROTOCOL_ARP:  "ARP",
-	ETHERNET_PROTOCOL_IPv6: "IPv6",
+	ETHERNET_PROTOCOL_IPv4:   "IPv4",
+	ETHERNET_PROTOCOL_ARP:    "ARP",
+	ETHERNET_PROTOCOL_RARP:   "RARP",
+	ETHERNET_PROTOCOL_8021Q:  "802.1Q",
+	ETHERNET_PROTOCOL_IPv6:   "IPv6",
+	ETHERNET_PROTOCOL_MPLS:   "MPLS",
+	ETHERNET_PROTOCOL_MPLSMC: "MPLS multicast",
+	ETHERNET_PROTOCOL_8021AD: "802.1ad",
+	ETHERNET_PROTOCOL_LLDP:   "LLDP",
+	ETHERNET_PROTOCOL_QINQ:   "QinQ",
 }
 
-// 尝试用以太网帧格式解析报文
+// 按以太网帧类型解析载荷，先查找链路层协议（如 VLAN），再查找网络层协议
+func EtherTypeResolve(etype uint16, payload []byte) resolver.IPacket {
+	name := ETHERNET_PROTOCOL_NAME[etype]
+	if resolve := EtherTypeResolvers[name]; resolve != nil {
+		return resolve(payload)
+	}
+	if resolve := networklayer.Resolvers[name]; resolve != nil {
+		return resolve(payload)
+	}
+	return nil
+}
+
+// 尝试用以太网帧格式解析隧道等封装中的内层帧，内层帧不带 FCS
 func EthernetResolve(packet []byte) resolver.IPacket {
+	return ethernetResolve(packet, false)
+}
+
+// 尝试用以太网帧格式解析抓包的最外层帧，按 EthernetFCSMode 检测 FCS
+func CapturedEthernetResolve(packet []byte) resolver.IPacket {
+	return ethernetResolve(packet, true)
+}
+
+func ethernetResolve(packet []byte, captured bool) resolver.IPacket {
 	length := len(packet)
-	if length < 14 || length > 1500 {
+	if length < 14 {
 		return nil
 	}
 
@@ -35,13 +71,13 @@ func EthernetResolve(packet []byte) resolver.IPacket {
 
 	/**
 	 * 第 12 字节
-	 * > 0x600 为 EthernetII
-	 * < 1500 为 IEEE 802.3 SNAP
+	 * >= 0x600 为 EthernetII
+	 * <= 1500 为 IEEE 802.3，该字段为长度，载荷为 LLC
 	 */
 	if 0x600 <= temp {
-		return EthernetIIResolve(packet)
+		return EthernetIIResolve(packet, captured)
 	} else if temp <= 1500 {
-		return IEEE8023SNAPResolve(packet)
+		return IEEE8023Resolve(packet, captured)
 	} else {
 		return nil
 	}
@@ -55,10 +91,15 @@ type IEthernet interface {
 
 type BaseEthernet struct {
 	IEthernet
+	resolver.ExpertInfo
 	raw         []byte    // 原始报文
 	destination types.Mac // 目的 MAC 地址
 	source      types.Mac // 源 MAC 地址
-	etype       uint16    // 以太网帧类型，大于 0x600 为 Ethernet II，小于 1500 为 IEEE 802.3 SNAP
+	etype       uint16    // 以太网帧类型，大于 0x600 为 Ethernet II，小于 1500 为 IEEE 802.3 的长度
+	padding     []byte    // 短帧补足最小帧长的填充
+	fcsPresent  bool      // 是否带有 FCS
+	fcs         uint32    // 帧校验序列（CRC-32）
+	fcsValid    bool      // FCS 是否正确
 }
 
 // 16 进制化的原始报文
@@ -81,12 +122,152 @@ func (ethernet *BaseEthernet) Destination() types.Mac {
 	return ethernet.destination
 }
 
+// 解析 MAC 地址，抓包的最外层帧还要检测 FCS，返回 FCS 之前的长度
+func (ethernet *BaseEthernet) readHeader(packet []byte, captured bool) int {
+	ethernet.destination.Parse([6]byte(packet[0:6]))
+	ethernet.source.Parse([6]byte(packet[6:12]))
+	ethernet.etype = utils.ExtractUint16BE(packet, 12)
+
+	end := len(packet)
+	if !captured {
+		return end
+	}
+	ethernet.fcsPresent, ethernet.fcsValid = detectFCS(packet)
+	if ethernet.fcsPresent {
+		end -= 4
+		ethernet.fcs = utils.ExtractUint32LE(packet, end)
+	}
+	return end
+}
+
+// 记录上层协议没有用完的数据（used 之后、end 之前）为填充，并保存原始报文
+func (ethernet *BaseEthernet) readTrailer(packet []byte, used int, end int) {
+	length := len(packet)
+	if used < end {
+		// 自动模式下只有校验正确的末尾 4 字节才视为 FCS，其余多出的数据（如加上 VLAN 标签前的填充、厂商尾部）均为填充
+		ethernet.padding = make([]byte, end-used)
+		copy(ethernet.padding, packet[used:end])
+	}
+	if ethernet.fcsPresent && !ethernet.fcsValid {
+		ethernet.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "Ethernet", "Bad FCS")
+	}
+	ethernet.raw = make([]byte, length)
+	copy(ethernet.raw, packet)
+}
+
+func (ethernet *BaseEthernet) baseFields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "eth.src", Value: ethernet.source.ToString()},
+		{Name: "eth.dst", Value: ethernet.destination.ToString()},
+	}
+	if len(ethernet.padding) != 0 {
+		fields = append(fields, resolver.Field{Name: "eth.padding", Value: strconv.Itoa(len(ethernet.padding))})
+	}
+	if ethernet.fcsPresent {
+		status := "bad"
+		if ethernet.fcsValid {
+			status = "good"
+		}
+		fields = append(fields,
+			resolver.Field{Name: "eth.fcs", Value: fmt.Sprintf("0x%08X", ethernet.fcs)},
+			resolver.Field{Name: "eth.fcs.status", Value: status},
+		)
+	}
+	return fields
+}
+
+// 输出源、目的 MAC 地址
+func (ethernet *BaseEthernet) writeAddresses(builder *strings.Builder, tabs []byte) {
+	builder.Write(tabs)
+	builder.WriteString("Source MAC address: ")
+	builder.WriteString(macString(ethernet.source))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Destination MAC address: ")
+	builder.WriteString(macString(ethernet.destination))
+	builder.WriteByte('\n')
+}
+
+// 输出载荷、填充、FCS 与原始报文
+func (ethernet *BaseEthernet) writeTrailer(builder *strings.Builder, tabs []byte, data resolver.IPacket) {
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if data != nil {
+		builder.WriteString(data.ToReadableString(len(tabs) + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	if len(ethernet.padding) != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Padding: ")
+		builder.WriteString(strconv.Itoa(len(ethernet.padding)))
+		builder.WriteString(" bytes (")
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(ethernet.padding)))
+		builder.WriteString(")")
+		for _, b := range ethernet.padding {
+			if b != 0 {
+				builder.WriteString(" [non-zero]")
+				break
+			}
+		}
+		builder.WriteByte('\n')
+	}
+
+	if ethernet.fcsPresent {
+		builder.Write(tabs)
+		builder.WriteString("FCS: ")
+		builder.WriteString(fmt.Sprintf("0x%08X", ethernet.fcs))
+		if ethernet.fcsValid {
+			builder.WriteString(" (correct)\n")
+		} else {
+			builder.WriteString(" (incorrect)\n")
+		}
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(ethernet.Hex())
+	builder.WriteByte('\n')
+}
+
+// 带名称与地址类型的 MAC 地址，如 "Vector_12:34:56 (00:16:81:12:34:56)"
+func macString(mac types.Mac) string {
+	builder := new(strings.Builder)
+	named, address := mac.ToNamedString(), mac.ToString()
+	builder.WriteString(named)
+	if named != address {
+		builder.WriteString(" (")
+		builder.WriteString(address)
+		builder.WriteString(")")
+	}
+	if mac.IsMulticast() && !mac.IsBroadcast() {
+		builder.WriteString(" [multicast]")
+	}
+	if mac.IsLocal() && !mac.IsBroadcast() {
+		builder.WriteString(" [locally administered]")
+	}
+	return builder.String()
+}
+
 // EthernetII 协议
 type EthernetII struct {
 	BaseEthernet
 	data resolver.IPacket // 载荷的数据
 }
 
+func (ethernet *EthernetII) Inner() resolver.IPacket {
+	return ethernet.data
+}
+
+func (ethernet *EthernetII) Fields() []resolver.Field {
+	return append(ethernet.baseFields(), resolver.Field{Name: "eth.type", Value: fmt.Sprintf("0x%04X", ethernet.etype)})
+}
+
 // 转换为可读字符串
 func (ethernet *EthernetII) ToReadableString(indent int) string {
 	builder := new(strings.Builder)
@@ -98,15 +279,7 @@ func (ethernet *EthernetII) ToReadableString(indent int) string {
 	builder.Write(tabs)
 	builder.WriteString("Protocol: Ethernet (Datalink)\n")
 
-	builder.Write(tabs)
-	builder.WriteString("Source MAC address: ")
-	builder.WriteString(ethernet.source.ToString())
-	builder.WriteByte('\n')
-
-	builder.Write(tabs)
-	builder.WriteString("Destination MAC address: ")
-	builder.WriteString(ethernet.destination.ToString())
-	builder.WriteByte('\n')
+	ethernet.writeAddresses(builder, tabs)
 
 	builder.Write(tabs)
 	builder.WriteString("Protocol type: ")
@@ -119,81 +292,81 @@ func (ethernet *EthernetII) ToReadableString(indent int) string {
 	}
 	builder.WriteString(")\n")
 
-	builder.Write(tabs)
-	builder.WriteString("Data: {\n")
-	if ethernet.data != nil {
-		builder.WriteString(ethernet.data.ToReadableString(indent + 1))
-	} else {
-		builder.Write(tabs)
-		builder.WriteString("\t(NOT RESOLVED)\n")
-	}
-	builder.Write(tabs)
-	builder.WriteString("}\n")
-
-	builder.Write(tabs)
-	builder.WriteString("Raw: ")
-	builder.WriteString(ethernet.Hex())
-	builder.WriteByte('\n')
+	ethernet.writeTrailer(builder, tabs, ethernet.data)
 
 	return builder.String()
 }
 
-// 以 EthernetII 协议格式解析报文
-func EthernetIIResolve(packet []byte) *EthernetII {
+// 以 EthernetII 协议格式解析报文，captured 表示是否为抓包的最外层帧（可能带有 FCS）
+func EthernetIIResolve(packet []byte, captured bool) *EthernetII {
 	ethernet := new(EthernetII)
-	ethernet.destination.Parse([6]byte(packet[0:6]))
-	ethernet.source.Parse([6]byte(packet[6:12]))
-
-	length := len(packet)
-
-	ethernet.etype = utils.ExtractUint16BE(packet, 12)
-	if resolve := networklayer.Resolvers[ETHERNET_PROTOCOL_NAME[ethernet.etype]]; resolve != nil {
-		ethernet.data = resolve(packet[14:length])
-	} else {
-		ethernet.data = nil
+	end := ethernet.readHeader(packet, captured)
+	ethernet.data = EtherTypeResolve(ethernet.etype, packet[14:end])
+	used := end
+	if ethernet.data != nil {
+		used = min(end, 14+len(ethernet.data.Raw()))
 	}
-	ethernet.raw = make([]byte, length)
-	copy(ethernet.raw, packet)
+	ethernet.readTrailer(packet, used, end)
 
 	return ethernet
 }
 
-// IEEE 802.3 SNAP 协议
-type IEEE8023SNAP struct {
+// IEEE 802.3 协议，类型字段为载荷长度，载荷为 IEEE 802.2 LLC
+type IEEE8023 struct {
 	BaseEthernet
-	dsap    byte             // 目的服务访问点，固定为 0xAA
-	ssap    byte             // 源服务访问点，固定为 0xAA
-	control byte             // 固定为 0x03
-	oui     [3]byte          // 组织唯一标识符
-	utype   uint16           // 上层协议类型（仅当 oui 字段为 0x000000 时）
-	data    resolver.IPacket // 上层协议数据
+	novell bool             // 是否为 Novell 原始 802.3 帧（载荷直接为 IPX，没有 LLC）
+	data   resolver.IPacket // LLC 数据
 }
 
-// 以 IEEE 802.3 SNAP 协议格式解析报文
-func IEEE8023SNAPResolve(packet []byte) *IEEE8023SNAP {
-	ethernet := new(IEEE8023SNAP)
-	ethernet.destination.Parse([6]byte(packet[0:6]))
-	ethernet.source.Parse([6]byte(packet[6:12]))
+func (ethernet *IEEE8023) Inner() resolver.IPacket {
+	return ethernet.data
+}
 
-	length := len(packet)
+func (ethernet *IEEE8023) Fields() []resolver.Field {
+	return append(ethernet.baseFields(), resolver.Field{Name: "eth.len", Value: strconv.Itoa(int(ethernet.etype))})
+}
 
-	ethernet.etype = utils.ExtractUint16BE(packet, 12)
-	ethernet.dsap = utils.ExtractUint8BE(packet, 14)
-	ethernet.ssap = utils.ExtractUint8BE(packet, 14)
-	ethernet.control = utils.ExtractUint8BE(packet, 14)
-	if ethernet.dsap != 0xAA || ethernet.ssap != 0xAA || ethernet.control != 0x03 {
+func (ethernet *IEEE8023) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: IEEE 802.3 Ethernet (Datalink)\n")
+
+	ethernet.writeAddresses(builder, tabs)
+
+	builder.Write(tabs)
+	builder.WriteString("Length: ")
+	builder.WriteString(strconv.Itoa(int(ethernet.etype)))
+	if ethernet.novell {
+		builder.WriteString(" (Novell raw IPX)")
+	}
+	builder.WriteByte('\n')
+
+	ethernet.writeTrailer(builder, tabs, ethernet.data)
+
+	return builder.String()
+}
+
+// 以 IEEE 802.3 协议格式解析报文，长度字段之后的数据为填充，captured 表示是否为抓包的最外层帧（可能带有 FCS）
+func IEEE8023Resolve(packet []byte, captured bool) resolver.IPacket {
+	ethernet := new(IEEE8023)
+	end := ethernet.readHeader(packet, captured)
+	if 14+int(ethernet.etype) > end {
 		return nil
 	}
-	copy(ethernet.oui[:], packet[17:20])
-	ethernet.utype = utils.ExtractUint16BE(packet, 20)
-	resolve := networklayer.Resolvers[ETHERNET_PROTOCOL_NAME[ethernet.utype]]
-	if (ethernet.oui[0]|ethernet.oui[1]|ethernet.oui[2]) == 0 && resolve != nil {
-		ethernet.data = resolve(packet[14:length])
+	used := 14 + int(ethernet.etype)
+	payload := packet[14:used]
+	// Novell 原始 802.3 帧直接承载 IPX，IPX 校验和固定为 0xFFFF
+	if len(payload) >= 2 && payload[0] == 0xFF && payload[1] == 0xFF {
+		ethernet.novell = true
 	} else {
-		ethernet.data = nil
+		ethernet.data = LLCResolve(payload)
 	}
-	ethernet.raw = make([]byte, length)
-	copy(ethernet.raw, packet)
+	ethernet.readTrailer(packet, used, end)
 
 	return ethernet
 }
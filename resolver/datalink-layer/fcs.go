@@ -0,0 +1,49 @@
+package datalinklayer
+
+import (
+	"hash/crc32"
+	"packet-inspector/utils"
+	"strings"
+)
+
+// 抓包中的以太网帧是否带有 FCS（帧校验序列），多数网卡在交付前会去掉 FCS
+type FCSMode uint8
+
+const (
+	FCS_AUTO    FCSMode = 0 // 帧末尾 4 字节恰好是其余部分的 CRC-32 时视为 FCS
+	FCS_PRESENT FCSMode = 1 // 总是带有 FCS，校验失败时报告错误
+	FCS_ABSENT  FCSMode = 2 // 总是不带 FCS
+)
+
+var FCS_MODE_NAME = map[FCSMode]string{
+	FCS_AUTO:    "auto",
+	FCS_PRESENT: "present",
+	FCS_ABSENT:  "absent",
+}
+
+// 按名称查找 FCS 模式
+func ParseFCSMode(name string) (FCSMode, bool) {
+	for mode, modeName := range FCS_MODE_NAME {
+		if strings.EqualFold(name, modeName) {
+			return mode, true
+		}
+	}
+	return FCS_AUTO, false
+}
+
+// 抓包的最外层以太网帧的 FCS 处理方式，隧道等封装中的内层帧总是不带 FCS
+var EthernetFCSMode = FCS_AUTO
+
+// 检测帧末尾的 FCS，返回是否带有 FCS 以及校验是否正确；FCS 以小端序存放
+func detectFCS(frame []byte) (present bool, valid bool) {
+	length := len(frame)
+	// 最短帧 64 字节（含 FCS）
+	if EthernetFCSMode == FCS_ABSENT || length < 64 {
+		return false, false
+	}
+	valid = crc32.ChecksumIEEE(frame[:length-4]) == utils.ExtractUint32LE(frame, length-4)
+	if EthernetFCSMode == FCS_PRESENT {
+		return true, valid
+	}
+	return valid, valid
+}
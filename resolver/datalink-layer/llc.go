@@ -0,0 +1,439 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// 由 LLC 目的服务访问点分派的协议，键为 LLC_SAP_NAME 中的名称
+var LLCResolvers = map[string]resolver.PacketResolver{}
+
+// 由 SNAP 组织代码与协议号分派的协议（组织代码为 0 时按以太网帧类型分派），键为 SNAP_PROTOCOL_NAME 中的名称
+var SNAPResolvers = map[string]resolver.PacketResolver{}
+
+// IEEE 802.2 服务访问点，最低位为 I/G（DSAP）或 C/R（SSAP）标识，不参与比较
+const (
+	LLC_SAP_NULL        uint8 = 0x00
+	LLC_SAP_LLC_MGMT    uint8 = 0x02
+	LLC_SAP_SNA         uint8 = 0x04
+	LLC_SAP_IP          uint8 = 0x06
+	LLC_SAP_PROWAY      uint8 = 0x0E
+	LLC_SAP_STP         uint8 = 0x42
+	LLC_SAP_X25         uint8 = 0x7E
+	LLC_SAP_ARP         uint8 = 0x98
+	LLC_SAP_SNAP        uint8 = 0xAA
+	LLC_SAP_BANYAN      uint8 = 0xBC
+	LLC_SAP_IPX         uint8 = 0xE0
+	LLC_SAP_NETBIOS     uint8 = 0xF0
+	LLC_SAP_LAN_MANAGER uint8 = 0xF4
+	LLC_SAP_ISO         uint8 = 0xFE
+)
+
+var LLC_SAP_NAME = map[uint8]string{
+	LLC_SAP_NULL:        "Null",
+	LLC_SAP_LLC_MGMT:    "LLC Sublayer Management",
+	LLC_SAP_SNA:         "SNA",
+	LLC_SAP_IP:          "IP",
+	LLC_SAP_PROWAY:      "PROWAY",
+	LLC_SAP_STP:         "STP",
+	LLC_SAP_X25:         "X.25",
+	LLC_SAP_ARP:         "ARP",
+	LLC_SAP_SNAP:        "SNAP",
+	LLC_SAP_BANYAN:      "Banyan VINES",
+	LLC_SAP_IPX:         "IPX",
+	LLC_SAP_NETBIOS:     "NetBIOS",
+	LLC_SAP_LAN_MANAGER: "LAN Manager",
+	LLC_SAP_ISO:         "ISO Network Layer",
+}
+
+// LLC 帧格式，由控制字段的低两位决定
+const (
+	LLC_FORMAT_I uint8 = 0 // 信息帧，控制字段 2 字节
+	LLC_FORMAT_S uint8 = 1 // 监控帧，控制字段 2 字节
+	LLC_FORMAT_U uint8 = 3 // 无编号帧，控制字段 1 字节
+)
+
+var LLC_FORMAT_NAME = map[uint8]string{
+	LLC_FORMAT_I: "Information",
+	LLC_FORMAT_S: "Supervisory",
+	LLC_FORMAT_U: "Unnumbered",
+}
+
+// 无编号帧的命令，已去掉 P/F 位（0x10）
+const (
+	LLC_U_UI    uint8 = 0x03
+	LLC_U_DM    uint8 = 0x0F
+	LLC_U_DISC  uint8 = 0x43
+	LLC_U_UA    uint8 = 0x63
+	LLC_U_SABME uint8 = 0x6F
+	LLC_U_FRMR  uint8 = 0x87
+	LLC_U_XID   uint8 = 0xAF
+	LLC_U_TEST  uint8 = 0xE3
+)
+
+var LLC_U_NAME = map[uint8]string{
+	LLC_U_UI:    "UI",
+	LLC_U_DM:    "DM",
+	LLC_U_DISC:  "DISC",
+	LLC_U_UA:    "UA",
+	LLC_U_SABME: "SABME",
+	LLC_U_FRMR:  "FRMR",
+	LLC_U_XID:   "XID",
+	LLC_U_TEST:  "TEST",
+}
+
+// 监控帧的功能（控制字段第 2、3 位）
+var LLC_S_NAME = map[uint8]string{
+	0: "RR",
+	1: "REJ",
+	2: "RNR",
+}
+
+// IEEE 802.2 LLC
+type LLC struct {
+	resolver.IPacket
+	raw     []byte           // 原始数据
+	dsap    uint8            // 目的服务访问点
+	ssap    uint8            // 源服务访问点
+	control uint16           // 控制字段，I 帧与 S 帧为 2 字节，U 帧为 1 字节
+	format  uint8            // 帧格式
+	info    []byte           // 信息字段
+	data    resolver.IPacket // 上层协议数据
+}
+
+func (llc *LLC) Raw() []byte {
+	return llc.raw
+}
+
+func (llc *LLC) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(llc.raw))
+}
+
+func (llc *LLC) Inner() resolver.IPacket {
+	return llc.data
+}
+
+// 帧类型的名称，如 "UI"、"RR"、"I"
+func (llc *LLC) Command() string {
+	switch llc.format {
+	case LLC_FORMAT_U:
+		if name := LLC_U_NAME[uint8(llc.control)&^0x10]; name != "" {
+			return name
+		}
+		return "Unknown"
+	case LLC_FORMAT_S:
+		if name := LLC_S_NAME[uint8(llc.control>>10)&0x03]; name != "" {
+			return name
+		}
+		return "Unknown"
+	default:
+		return "I"
+	}
+}
+
+// 轮询/终止（P/F）位
+func (llc *LLC) pollFinal() bool {
+	if llc.format == LLC_FORMAT_U {
+		return llc.control&0x10 != 0
+	}
+	return llc.control&0x01 != 0
+}
+
+func (llc *LLC) Fields() []resolver.Field {
+	return []resolver.Field{
+		{Name: "llc.dsap", Value: fmt.Sprintf("0x%02X", llc.dsap)},
+		{Name: "llc.ssap", Value: fmt.Sprintf("0x%02X", llc.ssap)},
+		{Name: "llc.control", Value: fmt.Sprintf("0x%02X", llc.control)},
+		{Name: "llc.type", Value: llc.Command()},
+	}
+}
+
+func (llc *LLC) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: IEEE 802.2 LLC (Datalink)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("DSAP: ")
+	builder.WriteString(fmt.Sprintf("0x%02X (", llc.dsap))
+	if llc.dsap == 0xFF {
+		builder.WriteString("Global")
+	} else if name := LLC_SAP_NAME[llc.dsap&^0x01]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	if llc.dsap&0x01 != 0 {
+		builder.WriteString(", group")
+	} else {
+		builder.WriteString(", individual")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("SSAP: ")
+	builder.WriteString(fmt.Sprintf("0x%02X (", llc.ssap))
+	if name := LLC_SAP_NAME[llc.ssap&^0x01]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	if llc.ssap&0x01 != 0 {
+		builder.WriteString(", response")
+	} else {
+		builder.WriteString(", command")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Control: ")
+	if llc.format == LLC_FORMAT_U {
+		builder.WriteString(fmt.Sprintf("0x%02X (", llc.control))
+	} else {
+		builder.WriteString(fmt.Sprintf("0x%04X (", llc.control))
+	}
+	builder.WriteString(LLC_FORMAT_NAME[llc.format])
+	builder.WriteString(", ")
+	builder.WriteString(llc.Command())
+	switch llc.format {
+	case LLC_FORMAT_I:
+		builder.WriteString(", N(S) = ")
+		builder.WriteString(strconv.Itoa(int(llc.control >> 9)))
+		builder.WriteString(", N(R) = ")
+		builder.WriteString(strconv.Itoa(int(llc.control>>1) & 0x7F))
+	case LLC_FORMAT_S:
+		builder.WriteString(", N(R) = ")
+		builder.WriteString(strconv.Itoa(int(llc.control>>1) & 0x7F))
+	}
+	if llc.pollFinal() {
+		if llc.ssap&0x01 != 0 {
+			builder.WriteString(", final")
+		} else {
+			builder.WriteString(", poll")
+		}
+	}
+	builder.WriteString(")\n")
+
+	if llc.format == LLC_FORMAT_U && uint8(llc.control)&^0x10 == LLC_U_XID && len(llc.info) >= 3 {
+		builder.Write(tabs)
+		builder.WriteString("XID format: ")
+		builder.WriteString(fmt.Sprintf("0x%02X", llc.info[0]))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("LLC types: ")
+		types := []string{}
+		for i := range 3 {
+			if llc.info[1]&(1<<i) != 0 {
+				types = append(types, "Type "+strconv.Itoa(i+1))
+			}
+		}
+		builder.WriteString(strings.Join(types, ", "))
+		builder.WriteByte('\n')
+
+		if len(llc.info) >= 4 {
+			builder.Write(tabs)
+			builder.WriteString("Receive window: ")
+			builder.WriteString(strconv.Itoa(int(llc.info[3] >> 1)))
+			builder.WriteByte('\n')
+		}
+	} else if llc.data != nil {
+		builder.Write(tabs)
+		builder.WriteString("Data: {\n")
+		builder.WriteString(llc.data.ToReadableString(indent + 1))
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	} else if len(llc.info) != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Information(HEX): ")
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(llc.info)))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(llc.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 IEEE 802.2 LLC，packet 从 DSAP 开始
+func LLCResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 3 {
+		return nil
+	}
+
+	llc := new(LLC)
+	llc.dsap = packet[0]
+	llc.ssap = packet[1]
+	header := 3
+	if packet[2]&0x03 == 0x03 {
+		llc.format = LLC_FORMAT_U
+		llc.control = uint16(packet[2])
+	} else {
+		if length < 4 {
+			return nil
+		}
+		llc.format = packet[2] & 0x01
+		llc.control = utils.ExtractUint16BE(packet, 2)
+		header = 4
+	}
+	llc.info = make([]byte, length-header)
+	copy(llc.info, packet[header:])
+
+	// 只有 UI 帧与 I 帧承载上层协议
+	if llc.format == LLC_FORMAT_I || (llc.format == LLC_FORMAT_U && uint8(llc.control)&^0x10 == LLC_U_UI) {
+		if llc.dsap == LLC_SAP_SNAP && llc.ssap&^0x01 == LLC_SAP_SNAP {
+			llc.data = SNAPResolve(llc.info)
+		} else if resolve := LLCResolvers[LLC_SAP_NAME[llc.dsap&^0x01]]; resolve != nil {
+			llc.data = resolve(llc.info)
+		}
+	}
+	llc.raw = make([]byte, length)
+	copy(llc.raw, packet)
+
+	return llc
+}
+
+// SNAP 组织唯一标识符
+const (
+	SNAP_OUI_ENCAPSULATED uint32 = 0x000000
+	SNAP_OUI_CISCO        uint32 = 0x00000C
+	SNAP_OUI_APPLE        uint32 = 0x080007
+	SNAP_OUI_IEEE_8021    uint32 = 0x0080C2
+)
+
+var SNAP_OUI_NAME = map[uint32]string{
+	SNAP_OUI_ENCAPSULATED: "Encapsulated Ethernet",
+	SNAP_OUI_CISCO:        "Cisco",
+	SNAP_OUI_APPLE:        "Apple",
+	SNAP_OUI_IEEE_8021:    "IEEE 802.1",
+}
+
+// 组织代码不为 0 时的协议，键为组织代码 << 16 | 协议号
+var SNAP_PROTOCOL_NAME = map[uint64]string{
+	uint64(SNAP_OUI_CISCO)<<16 | 0x0104: "PAgP",
+	uint64(SNAP_OUI_CISCO)<<16 | 0x010B: "PVST+",
+	uint64(SNAP_OUI_CISCO)<<16 | 0x0111: "UDLD",
+	uint64(SNAP_OUI_CISCO)<<16 | 0x2000: "CDP",
+	uint64(SNAP_OUI_CISCO)<<16 | 0x2003: "VTP",
+	uint64(SNAP_OUI_CISCO)<<16 | 0x2004: "DTP",
+	uint64(SNAP_OUI_APPLE)<<16 | 0x809B: "AppleTalk",
+}
+
+// IEEE 802 SNAP 子网访问协议
+type SNAP struct {
+	resolver.IPacket
+	raw  []byte           // 原始数据
+	oui  uint32           // 组织唯一标识符（3 字节）
+	pid  uint16           // 协议号，组织代码为 0 时为以太网帧类型
+	data resolver.IPacket // 上层协议数据
+}
+
+func (snap *SNAP) Raw() []byte {
+	return snap.raw
+}
+
+func (snap *SNAP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(snap.raw))
+}
+
+func (snap *SNAP) Inner() resolver.IPacket {
+	return snap.data
+}
+
+// 协议的名称，未知时返回空字符串
+func (snap *SNAP) protocolName() string {
+	if snap.oui == SNAP_OUI_ENCAPSULATED {
+		return ETHERNET_PROTOCOL_NAME[snap.pid]
+	}
+	return SNAP_PROTOCOL_NAME[uint64(snap.oui)<<16|uint64(snap.pid)]
+}
+
+func (snap *SNAP) Fields() []resolver.Field {
+	return []resolver.Field{
+		{Name: "snap.oui", Value: fmt.Sprintf("0x%06X", snap.oui)},
+		{Name: "snap.pid", Value: fmt.Sprintf("0x%04X", snap.pid)},
+	}
+}
+
+func (snap *SNAP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: SNAP (Datalink)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Organization code: ")
+	builder.WriteString(fmt.Sprintf("0x%06X (", snap.oui))
+	if name := SNAP_OUI_NAME[snap.oui]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol ID: ")
+	builder.WriteString(fmt.Sprintf("0x%04X (", snap.pid))
+	if name := snap.protocolName(); name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if snap.data != nil {
+		builder.WriteString(snap.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(snap.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 SNAP，packet 从组织代码开始
+func SNAPResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 5 {
+		return nil
+	}
+
+	snap := new(SNAP)
+	snap.oui = uint32(packet[0])<<16 | uint32(utils.ExtractUint16BE(packet, 1))
+	snap.pid = utils.ExtractUint16BE(packet, 3)
+	if snap.oui == SNAP_OUI_ENCAPSULATED {
+		snap.data = EtherTypeResolve(snap.pid, packet[5:length])
+	} else if resolve := SNAPResolvers[snap.protocolName()]; resolve != nil {
+		snap.data = resolve(packet[5:length])
+	}
+	snap.raw = make([]byte, length)
+	copy(snap.raw, packet)
+
+	return snap
+}
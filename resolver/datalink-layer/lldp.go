@@ -0,0 +1,661 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LLDP TLV 类型
+const (
+	LLDP_TLV_END                 uint8 = 0
+	LLDP_TLV_CHASSIS_ID          uint8 = 1
+	LLDP_TLV_PORT_ID             uint8 = 2
+	LLDP_TLV_TTL                 uint8 = 3
+	LLDP_TLV_PORT_DESCRIPTION    uint8 = 4
+	LLDP_TLV_SYSTEM_NAME         uint8 = 5
+	LLDP_TLV_SYSTEM_DESCRIPTION  uint8 = 6
+	LLDP_TLV_SYSTEM_CAPABILITIES uint8 = 7
+	LLDP_TLV_MANAGEMENT_ADDRESS  uint8 = 8
+	LLDP_TLV_ORGANIZATION        uint8 = 127
+)
+
+var LLDP_TLV_NAME = map[uint8]string{
+	LLDP_TLV_END:                 "End of LLDPDU",
+	LLDP_TLV_CHASSIS_ID:          "Chassis ID",
+	LLDP_TLV_PORT_ID:             "Port ID",
+	LLDP_TLV_TTL:                 "Time to live",
+	LLDP_TLV_PORT_DESCRIPTION:    "Port description",
+	LLDP_TLV_SYSTEM_NAME:         "System name",
+	LLDP_TLV_SYSTEM_DESCRIPTION:  "System description",
+	LLDP_TLV_SYSTEM_CAPABILITIES: "System capabilities",
+	LLDP_TLV_MANAGEMENT_ADDRESS:  "Management address",
+	LLDP_TLV_ORGANIZATION:        "Organization specific",
+}
+
+// Chassis ID 的子类型
+var LLDP_CHASSIS_ID_SUBTYPE_NAME = map[uint8]string{
+	1: "Chassis component",
+	2: "Interface alias",
+	3: "Port component",
+	4: "MAC address",
+	5: "Network address",
+	6: "Interface name",
+	7: "Locally assigned",
+}
+
+// Port ID 的子类型
+var LLDP_PORT_ID_SUBTYPE_NAME = map[uint8]string{
+	1: "Interface alias",
+	2: "Port component",
+	3: "MAC address",
+	4: "Network address",
+	5: "Interface name",
+	6: "Agent circuit ID",
+	7: "Locally assigned",
+}
+
+// 系统能力，按位表示
+var LLDP_CAPABILITY_NAME = map[uint16]string{
+	0x0001: "Other",
+	0x0002: "Repeater",
+	0x0004: "Bridge",
+	0x0008: "WLAN access point",
+	0x0010: "Router",
+	0x0020: "Telephone",
+	0x0040: "DOCSIS cable device",
+	0x0080: "Station only",
+	0x0100: "C-VLAN component",
+	0x0200: "S-VLAN component",
+	0x0400: "Two-port MAC relay",
+}
+
+// 管理地址的地址族（IANA Address Family Numbers）
+const (
+	ADDRESS_FAMILY_IPv4 uint8 = 1
+	ADDRESS_FAMILY_IPv6 uint8 = 2
+	ADDRESS_FAMILY_MAC  uint8 = 6
+)
+
+// 管理地址的接口编号方式
+var LLDP_INTERFACE_NUMBERING_NAME = map[uint8]string{
+	1: "Unknown",
+	2: "ifIndex",
+	3: "System port number",
+}
+
+// 组织特定 TLV 的组织唯一标识符
+const (
+	LLDP_OUI_IEEE_8021 uint32 = 0x0080C2
+	LLDP_OUI_IEEE_8023 uint32 = 0x00120F
+	LLDP_OUI_TIA_MED   uint32 = 0x0012BB
+	LLDP_OUI_PROFINET  uint32 = 0x000ECF
+)
+
+var LLDP_OUI_NAME = map[uint32]string{
+	LLDP_OUI_IEEE_8021: "IEEE 802.1",
+	LLDP_OUI_IEEE_8023: "IEEE 802.3",
+	LLDP_OUI_TIA_MED:   "TIA TR-41 (LLDP-MED)",
+	LLDP_OUI_PROFINET:  "PROFINET",
+}
+
+// IEEE 802.1 组织特定 TLV 的子类型
+const (
+	LLDP_8021_PORT_VLAN_ID         uint8 = 1
+	LLDP_8021_PROTOCOL_VLAN_ID     uint8 = 2
+	LLDP_8021_VLAN_NAME            uint8 = 3
+	LLDP_8021_PROTOCOL_IDENTITY    uint8 = 4
+	LLDP_8021_VID_USAGE_DIGEST     uint8 = 5
+	LLDP_8021_MANAGEMENT_VID       uint8 = 6
+	LLDP_8021_LINK_AGGREGATION     uint8 = 7
+	LLDP_8021_CONGESTION           uint8 = 8
+	LLDP_8021_ETS_CONFIGURATION    uint8 = 9
+	LLDP_8021_ETS_RECOMMENDATION   uint8 = 10
+	LLDP_8021_PFC                  uint8 = 11
+	LLDP_8021_APPLICATION_PRIORITY uint8 = 12
+	LLDP_8021_EVB                  uint8 = 13
+	LLDP_8021_CDCP                 uint8 = 14
+	LLDP_8021_APPLICATION_VLAN     uint8 = 16
+)
+
+var LLDP_8021_SUBTYPE_NAME = map[uint8]string{
+	LLDP_8021_PORT_VLAN_ID:         "Port VLAN ID",
+	LLDP_8021_PROTOCOL_VLAN_ID:     "Port and protocol VLAN ID",
+	LLDP_8021_VLAN_NAME:            "VLAN name",
+	LLDP_8021_PROTOCOL_IDENTITY:    "Protocol identity",
+	LLDP_8021_VID_USAGE_DIGEST:     "VID usage digest",
+	LLDP_8021_MANAGEMENT_VID:       "Management VID",
+	LLDP_8021_LINK_AGGREGATION:     "Link aggregation",
+	LLDP_8021_CONGESTION:           "Congestion notification",
+	LLDP_8021_ETS_CONFIGURATION:    "ETS configuration",
+	LLDP_8021_ETS_RECOMMENDATION:   "ETS recommendation",
+	LLDP_8021_PFC:                  "Priority-based flow control configuration",
+	LLDP_8021_APPLICATION_PRIORITY: "Application priority",
+	LLDP_8021_EVB:                  "EVB",
+	LLDP_8021_CDCP:                 "CDCP",
+	LLDP_8021_APPLICATION_VLAN:     "Application VLAN",
+}
+
+// IEEE 802.3 组织特定 TLV 的子类型
+const (
+	LLDP_8023_MAC_PHY                    uint8 = 1
+	LLDP_8023_POWER_VIA_MDI              uint8 = 2
+	LLDP_8023_LINK_AGGREGATION           uint8 = 3
+	LLDP_8023_MAX_FRAME_SIZE             uint8 = 4
+	LLDP_8023_EEE                        uint8 = 5
+	LLDP_8023_EEE_FAST_WAKE              uint8 = 6
+	LLDP_8023_ADDITIONAL_CAPS            uint8 = 7
+	LLDP_8023_POWER_VIA_MDI_MEASUREMENTS uint8 = 8
+)
+
+var LLDP_8023_SUBTYPE_NAME = map[uint8]string{
+	LLDP_8023_MAC_PHY:                    "MAC/PHY configuration/status",
+	LLDP_8023_POWER_VIA_MDI:              "Power via MDI",
+	LLDP_8023_LINK_AGGREGATION:           "Link aggregation",
+	LLDP_8023_MAX_FRAME_SIZE:             "Maximum frame size",
+	LLDP_8023_EEE:                        "Energy-Efficient Ethernet",
+	LLDP_8023_EEE_FAST_WAKE:              "EEE fast wake",
+	LLDP_8023_ADDITIONAL_CAPS:            "Additional Ethernet capabilities",
+	LLDP_8023_POWER_VIA_MDI_MEASUREMENTS: "Power via MDI measurements",
+}
+
+// 运行中的 MAU 类型（RFC 4836 dot3MauType）
+var LLDP_MAU_TYPE_NAME = map[uint16]string{
+	10: "10BASE-T half duplex",
+	11: "10BASE-T full duplex",
+	15: "100BASE-TX half duplex",
+	16: "100BASE-TX full duplex",
+	29: "1000BASE-T half duplex",
+	30: "1000BASE-T full duplex",
+}
+
+// 一个 LLDP TLV
+type LLDPTLV struct {
+	Type  uint8  // 类型（7 bit）
+	Value []byte // 值（长度 9 bit）
+}
+
+// 组织唯一标识符，仅对组织特定 TLV 有效
+func (tlv *LLDPTLV) OUI() uint32 {
+	if tlv.Type != LLDP_TLV_ORGANIZATION || len(tlv.Value) < 4 {
+		return 0
+	}
+	return uint32(tlv.Value[0])<<16 | uint32(utils.ExtractUint16BE(tlv.Value, 1))
+}
+
+// 组织定义的子类型，仅对组织特定 TLV 有效
+func (tlv *LLDPTLV) Subtype() uint8 {
+	if tlv.Type != LLDP_TLV_ORGANIZATION || len(tlv.Value) < 4 {
+		return 0
+	}
+	return tlv.Value[3]
+}
+
+// 链路层发现协议（IEEE 802.1AB）
+type LLDP struct {
+	resolver.IPacket
+	raw  []byte    // 原始数据，到 End of LLDPDU 为止
+	tlvs []LLDPTLV // 所有 TLV，不含 End of LLDPDU
+}
+
+func (lldp *LLDP) Raw() []byte {
+	return lldp.raw
+}
+
+func (lldp *LLDP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(lldp.raw))
+}
+
+func (lldp *LLDP) Inner() resolver.IPacket {
+	return nil
+}
+
+func (lldp *LLDP) TLVs() []LLDPTLV {
+	return lldp.tlvs
+}
+
+// 第一个指定类型的 TLV 的值，不存在时返回 nil
+func (lldp *LLDP) value(tlvType uint8) []byte {
+	for _, tlv := range lldp.tlvs {
+		if tlv.Type == tlvType {
+			return tlv.Value
+		}
+	}
+	return nil
+}
+
+// 机箱 ID
+func (lldp *LLDP) ChassisID() string {
+	value := lldp.value(LLDP_TLV_CHASSIS_ID)
+	if len(value) < 2 {
+		return ""
+	}
+	return lldpID(value[1:], value[0] == 4, value[0] == 5)
+}
+
+// 端口 ID
+func (lldp *LLDP) PortID() string {
+	value := lldp.value(LLDP_TLV_PORT_ID)
+	if len(value) < 2 {
+		return ""
+	}
+	return lldpID(value[1:], value[0] == 3, value[0] == 4)
+}
+
+// 生存时间（秒），0 表示邻居即将关闭
+func (lldp *LLDP) TTL() uint16 {
+	value := lldp.value(LLDP_TLV_TTL)
+	if len(value) < 2 {
+		return 0
+	}
+	return utils.ExtractUint16BE(value, 0)
+}
+
+func (lldp *LLDP) PortDescription() string {
+	return string(lldp.value(LLDP_TLV_PORT_DESCRIPTION))
+}
+
+func (lldp *LLDP) SystemName() string {
+	return string(lldp.value(LLDP_TLV_SYSTEM_NAME))
+}
+
+func (lldp *LLDP) SystemDescription() string {
+	return string(lldp.value(LLDP_TLV_SYSTEM_DESCRIPTION))
+}
+
+// 系统支持的能力与已启用的能力
+func (lldp *LLDP) Capabilities() (uint16, uint16) {
+	value := lldp.value(LLDP_TLV_SYSTEM_CAPABILITIES)
+	if len(value) < 4 {
+		return 0, 0
+	}
+	return utils.ExtractUint16BE(value, 0), utils.ExtractUint16BE(value, 2)
+}
+
+// 所有管理地址
+func (lldp *LLDP) ManagementAddresses() []string {
+	addresses := []string{}
+	for _, tlv := range lldp.tlvs {
+		if tlv.Type != LLDP_TLV_MANAGEMENT_ADDRESS || len(tlv.Value) < 2 {
+			continue
+		}
+		length := int(tlv.Value[0])
+		if length < 1 || 1+length > len(tlv.Value) {
+			continue
+		}
+		addresses = append(addresses, familyAddress(tlv.Value[1], tlv.Value[2:1+length]))
+	}
+	return addresses
+}
+
+// 端口 VLAN ID（IEEE 802.1 组织特定 TLV），不存在时返回 0
+func (lldp *LLDP) PortVLAN() uint16 {
+	for _, tlv := range lldp.tlvs {
+		if tlv.OUI() == LLDP_OUI_IEEE_8021 && tlv.Subtype() == LLDP_8021_PORT_VLAN_ID && len(tlv.Value) >= 6 {
+			return utils.ExtractUint16BE(tlv.Value, 4)
+		}
+	}
+	return 0
+}
+
+// 按位表示的能力的名称列表
+func LLDPCapabilityNames(capabilities uint16) []string {
+	names := []string{}
+	for bit := uint16(1); bit != 0 && bit <= 0x0400; bit <<= 1 {
+		if capabilities&bit != 0 {
+			names = append(names, LLDP_CAPABILITY_NAME[bit])
+		}
+	}
+	return names
+}
+
+// 按地址族格式化地址，未知的地址族以 16 进制表示
+func familyAddress(family uint8, address []byte) string {
+	switch {
+	case family == ADDRESS_FAMILY_IPv4 && len(address) == 4:
+		ip := types.IPv4{}
+		ip.Parse([4]byte(address))
+		return ip.ToString()
+	case family == ADDRESS_FAMILY_IPv6 && len(address) == 16:
+		ip := types.IPv6{}
+		ip.Parse([16]byte(address))
+		return ip.ToString()
+	case family == ADDRESS_FAMILY_MAC && len(address) == 6:
+		mac := types.Mac{}
+		mac.Parse([6]byte(address))
+		return mac.ToString()
+	}
+	return strings.ToUpper(hex.EncodeToString(address))
+}
+
+// 可打印的字符串原样返回，否则以 16 进制表示
+func printable(value []byte) string {
+	for _, r := range string(value) {
+		if !unicode.IsPrint(r) {
+			return strings.ToUpper(hex.EncodeToString(value))
+		}
+	}
+	return string(value)
+}
+
+// 格式化机箱 ID 或端口 ID，mac 与 network 表示该子类型为 MAC 地址或网络地址
+func lldpID(id []byte, mac bool, network bool) string {
+	switch {
+	case mac && len(id) == 6:
+		return familyAddress(ADDRESS_FAMILY_MAC, id)
+	case network && len(id) >= 2:
+		return familyAddress(id[0], id[1:])
+	}
+	return printable(id)
+}
+
+func (lldp *LLDP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "lldp.chassis.id", Value: lldp.ChassisID()},
+		{Name: "lldp.port.id", Value: lldp.PortID()},
+		{Name: "lldp.ttl", Value: strconv.Itoa(int(lldp.TTL()))},
+	}
+	if name := lldp.SystemName(); name != "" {
+		fields = append(fields, resolver.Field{Name: "lldp.sysname", Value: name})
+	}
+	for _, address := range lldp.ManagementAddresses() {
+		fields = append(fields, resolver.Field{Name: "lldp.mgmt.addr", Value: address})
+	}
+	if vlan := lldp.PortVLAN(); vlan != 0 {
+		fields = append(fields, resolver.Field{Name: "lldp.port.vid", Value: strconv.Itoa(int(vlan))})
+	}
+	return fields
+}
+
+func (lldp *LLDP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: LLDP (Datalink)\n")
+
+	for _, tlv := range lldp.tlvs {
+		builder.Write(tabs)
+		if name := LLDP_TLV_NAME[tlv.Type]; name != "" {
+			builder.WriteString(name)
+		} else {
+			builder.WriteString("TLV type " + strconv.Itoa(int(tlv.Type)))
+		}
+		builder.WriteString(": ")
+		lldpTLVString(builder, tabs, tlv)
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(lldp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 输出一个 TLV 的值，已输出 TLV 名称
+func lldpTLVString(builder *strings.Builder, tabs []byte, tlv LLDPTLV) {
+	value := tlv.Value
+	switch tlv.Type {
+	case LLDP_TLV_CHASSIS_ID, LLDP_TLV_PORT_ID:
+		if len(value) < 2 {
+			break
+		}
+		names := LLDP_CHASSIS_ID_SUBTYPE_NAME
+		mac, network := value[0] == 4, value[0] == 5
+		if tlv.Type == LLDP_TLV_PORT_ID {
+			names = LLDP_PORT_ID_SUBTYPE_NAME
+			mac, network = value[0] == 3, value[0] == 4
+		}
+		builder.WriteString(lldpID(value[1:], mac, network))
+		builder.WriteString(" (")
+		if name := names[value[0]]; name != "" {
+			builder.WriteString(name)
+		} else {
+			builder.WriteString("Subtype " + strconv.Itoa(int(value[0])))
+		}
+		builder.WriteString(")\n")
+		return
+	case LLDP_TLV_TTL:
+		if len(value) < 2 {
+			break
+		}
+		builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(value, 0))))
+		builder.WriteString(" s\n")
+		return
+	case LLDP_TLV_PORT_DESCRIPTION, LLDP_TLV_SYSTEM_NAME, LLDP_TLV_SYSTEM_DESCRIPTION:
+		builder.WriteString(strconv.Quote(string(value)))
+		builder.WriteByte('\n')
+		return
+	case LLDP_TLV_SYSTEM_CAPABILITIES:
+		if len(value) < 4 {
+			break
+		}
+		supported, enabled := utils.ExtractUint16BE(value, 0), utils.ExtractUint16BE(value, 2)
+		builder.WriteString(fmt.Sprintf("supported 0x%04X (", supported))
+		builder.WriteString(strings.Join(LLDPCapabilityNames(supported), ", "))
+		builder.WriteString(fmt.Sprintf("), enabled 0x%04X (", enabled))
+		builder.WriteString(strings.Join(LLDPCapabilityNames(enabled), ", "))
+		builder.WriteString(")\n")
+		return
+	case LLDP_TLV_MANAGEMENT_ADDRESS:
+		length := 0
+		if len(value) >= 1 {
+			length = int(value[0])
+		}
+		// 地址字符串长度（含地址族）、地址族、地址、接口编号方式、接口编号、OID 长度、OID
+		if length < 1 || 1+length+6 > len(value) {
+			break
+		}
+		builder.WriteString(familyAddress(value[1], value[2:1+length]))
+		offset := 1 + length
+		numbering := value[offset]
+		builder.WriteString(" (interface ")
+		builder.WriteString(strconv.FormatUint(uint64(utils.ExtractUint32BE(value, offset+1)), 10))
+		if name := LLDP_INTERFACE_NUMBERING_NAME[numbering]; name != "" {
+			builder.WriteString(", ")
+			builder.WriteString(name)
+		}
+		oid := int(value[offset+5])
+		if oid != 0 && offset+6+oid <= len(value) {
+			builder.WriteString(", OID ")
+			builder.WriteString(strings.ToUpper(hex.EncodeToString(value[offset+6 : offset+6+oid])))
+		}
+		builder.WriteString(")\n")
+		return
+	case LLDP_TLV_ORGANIZATION:
+		if len(value) < 4 {
+			break
+		}
+		oui, subtype := tlv.OUI(), tlv.Subtype()
+		builder.WriteString(fmt.Sprintf("0x%06X (", oui))
+		if name := LLDP_OUI_NAME[oui]; name != "" {
+			builder.WriteString(name)
+		} else {
+			builder.WriteString("Unknown")
+		}
+		builder.WriteString("), subtype ")
+		builder.WriteString(strconv.Itoa(int(subtype)))
+		var name string
+		switch oui {
+		case LLDP_OUI_IEEE_8021:
+			name = LLDP_8021_SUBTYPE_NAME[subtype]
+		case LLDP_OUI_IEEE_8023:
+			name = LLDP_8023_SUBTYPE_NAME[subtype]
+		}
+		if name != "" {
+			builder.WriteString(" (")
+			builder.WriteString(name)
+			builder.WriteString(")")
+		}
+		builder.WriteString(" {\n")
+		lines := []string{}
+		switch oui {
+		case LLDP_OUI_IEEE_8021:
+			lines = lldp8021Lines(subtype, value[4:])
+		case LLDP_OUI_IEEE_8023:
+			lines = lldp8023Lines(subtype, value[4:])
+		}
+		if len(lines) == 0 && len(value) > 4 {
+			lines = append(lines, "Data(HEX): "+strings.ToUpper(hex.EncodeToString(value[4:])))
+		}
+		for _, line := range lines {
+			builder.Write(tabs)
+			builder.WriteByte('\t')
+			builder.WriteString(line)
+			builder.WriteByte('\n')
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+		return
+	}
+	builder.WriteString(strings.ToUpper(hex.EncodeToString(value)))
+	builder.WriteString(" (malformed)\n")
+}
+
+// IEEE 802.1 组织特定 TLV 的内容，data 从子类型之后开始，无法解析时返回空
+func lldp8021Lines(subtype uint8, data []byte) []string {
+	switch subtype {
+	case LLDP_8021_PORT_VLAN_ID, LLDP_8021_MANAGEMENT_VID:
+		if len(data) >= 2 {
+			return []string{"VLAN ID: " + strconv.Itoa(int(utils.ExtractUint16BE(data, 0)))}
+		}
+	case LLDP_8021_PROTOCOL_VLAN_ID:
+		if len(data) >= 3 {
+			return []string{
+				fmt.Sprintf("Flags: 0x%02X (supported %t, enabled %t)", data[0], data[0]&0x02 != 0, data[0]&0x04 != 0),
+				"Protocol VLAN ID: " + strconv.Itoa(int(utils.ExtractUint16BE(data, 1))),
+			}
+		}
+	case LLDP_8021_VLAN_NAME:
+		if len(data) >= 3 && 3+int(data[2]) <= len(data) {
+			return []string{
+				"VLAN ID: " + strconv.Itoa(int(utils.ExtractUint16BE(data, 0))),
+				"VLAN name: " + strconv.Quote(string(data[3:3+int(data[2])])),
+			}
+		}
+	case LLDP_8021_PROTOCOL_IDENTITY:
+		if len(data) >= 1 && 1+int(data[0]) <= len(data) {
+			return []string{"Protocol identity(HEX): " + strings.ToUpper(hex.EncodeToString(data[1:1+int(data[0])]))}
+		}
+	case LLDP_8021_LINK_AGGREGATION:
+		return linkAggregationLines(data)
+	case LLDP_8021_PFC:
+		if len(data) >= 2 {
+			enabled := []string{}
+			for priority := range 8 {
+				if data[1]&(1<<priority) != 0 {
+					enabled = append(enabled, strconv.Itoa(priority))
+				}
+			}
+			return []string{
+				fmt.Sprintf("Willing: %t, MACsec bypass capability: %t, PFC capability: %d", data[0]&0x80 != 0, data[0]&0x40 != 0, data[0]&0x0F),
+				"PFC enabled priorities: " + strings.Join(enabled, ", "),
+			}
+		}
+	}
+	return nil
+}
+
+// IEEE 802.3 组织特定 TLV 的内容，data 从子类型之后开始，无法解析时返回空
+func lldp8023Lines(subtype uint8, data []byte) []string {
+	switch subtype {
+	case LLDP_8023_MAC_PHY:
+		if len(data) >= 5 {
+			mau := utils.ExtractUint16BE(data, 3)
+			name := LLDP_MAU_TYPE_NAME[mau]
+			if name == "" {
+				name = "Unknown"
+			}
+			return []string{
+				fmt.Sprintf("Auto-negotiation: supported %t, enabled %t", data[0]&0x01 != 0, data[0]&0x02 != 0),
+				fmt.Sprintf("Advertised capabilities: 0x%04X", utils.ExtractUint16BE(data, 1)),
+				fmt.Sprintf("Operational MAU type: %d (%s)", mau, name),
+			}
+		}
+	case LLDP_8023_POWER_VIA_MDI:
+		if len(data) >= 3 {
+			class := "PD"
+			if data[0]&0x01 != 0 {
+				class = "PSE"
+			}
+			lines := []string{
+				fmt.Sprintf("MDI power support: 0x%02X (port class %s, supported %t, enabled %t)", data[0], class, data[0]&0x02 != 0, data[0]&0x04 != 0),
+				"PSE power pair: " + strconv.Itoa(int(data[1])),
+				"Power class: " + strconv.Itoa(int(data[2])-1),
+			}
+			if len(data) >= 8 {
+				lines = append(lines,
+					fmt.Sprintf("PD requested power: %.1f W", float64(utils.ExtractUint16BE(data, 4))/10),
+					fmt.Sprintf("PSE allocated power: %.1f W", float64(utils.ExtractUint16BE(data, 6))/10),
+				)
+			}
+			return lines
+		}
+	case LLDP_8023_LINK_AGGREGATION:
+		return linkAggregationLines(data)
+	case LLDP_8023_MAX_FRAME_SIZE:
+		if len(data) >= 2 {
+			return []string{"Maximum frame size: " + strconv.Itoa(int(utils.ExtractUint16BE(data, 0)))}
+		}
+	case LLDP_8023_EEE:
+		if len(data) >= 10 {
+			return []string{
+				fmt.Sprintf("Transmit Tw: %d us, receive Tw: %d us", utils.ExtractUint16BE(data, 0), utils.ExtractUint16BE(data, 2)),
+				fmt.Sprintf("Fallback receive Tw: %d us", utils.ExtractUint16BE(data, 4)),
+				fmt.Sprintf("Echo transmit Tw: %d us, echo receive Tw: %d us", utils.ExtractUint16BE(data, 6), utils.ExtractUint16BE(data, 8)),
+			}
+		}
+	}
+	return nil
+}
+
+// 链路聚合 TLV（802.1 与已废弃的 802.3 子类型格式相同）
+func linkAggregationLines(data []byte) []string {
+	if len(data) < 5 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("Aggregation status: 0x%02X (capable %t, enabled %t)", data[0], data[0]&0x01 != 0, data[0]&0x02 != 0),
+		"Aggregated port ID: " + strconv.FormatUint(uint64(utils.ExtractUint32BE(data, 1)), 10),
+	}
+}
+
+// 解析 LLDPDU，packet 从第一个 TLV 开始，End of LLDPDU 之后的数据（如以太网填充）不属于该报文
+func LLDPResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	lldp := new(LLDP)
+	offset := 0
+	for offset+2 <= length {
+		header := utils.ExtractUint16BE(packet, offset)
+		tlv := LLDPTLV{Type: uint8(header >> 9)}
+		size := int(header & 0x01FF)
+		if offset+2+size > length {
+			return nil
+		}
+		tlv.Value = make([]byte, size)
+		copy(tlv.Value, packet[offset+2:offset+2+size])
+		offset += 2 + size
+		if tlv.Type == LLDP_TLV_END {
+			break
+		}
+		lldp.tlvs = append(lldp.tlvs, tlv)
+	}
+	// 前三个 TLV 必须依次为 Chassis ID、Port ID 与 TTL
+	if len(lldp.tlvs) < 3 || lldp.tlvs[0].Type != LLDP_TLV_CHASSIS_ID ||
+		lldp.tlvs[1].Type != LLDP_TLV_PORT_ID || lldp.tlvs[2].Type != LLDP_TLV_TTL {
+		return nil
+	}
+	lldp.raw = make([]byte, offset)
+	copy(lldp.raw, packet)
+
+	return lldp
+}
@@ -0,0 +1,144 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"packet-inspector/resolver"
+	networklayer "packet-inspector/resolver/network-layer"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// 环回接口报文头中的地址族，IPv6 的取值因操作系统而异
+var LOOPBACK_FAMILY_NAME = map[uint32]string{
+	2:  "IPv4",
+	10: "IPv6", // Linux
+	23: "IPv6", // Windows
+	24: "IPv6", // NetBSD、OpenBSD
+	28: "IPv6", // FreeBSD
+	30: "IPv6", // Darwin
+}
+
+// BSD 环回封装（LINKTYPE_NULL 与 LINKTYPE_LOOP），报文头只有 4 字节的地址族
+type Loopback struct {
+	resolver.IPacket
+	raw       []byte           // 原始报文
+	family    uint32           // 地址族
+	bigEndian bool             // 地址族是否为网络字节序，LINKTYPE_LOOP 总是网络字节序，LINKTYPE_NULL 为抓包主机的字节序
+	data      resolver.IPacket // 载荷的数据
+}
+
+func (loopback *Loopback) Raw() []byte {
+	return loopback.raw
+}
+
+func (loopback *Loopback) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(loopback.raw))
+}
+
+func (loopback *Loopback) Inner() resolver.IPacket {
+	return loopback.data
+}
+
+func (loopback *Loopback) Fields() []resolver.Field {
+	return []resolver.Field{
+		{Name: "null.family", Value: strconv.FormatUint(uint64(loopback.family), 10)},
+	}
+}
+
+func (loopback *Loopback) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: Loopback (Datalink)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Address family: ")
+	builder.WriteString(strconv.FormatUint(uint64(loopback.family), 10))
+	builder.WriteString(" (")
+	if name := LOOPBACK_FAMILY_NAME[loopback.family]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Byte order: ")
+	if loopback.bigEndian {
+		builder.WriteString("big-endian\n")
+	} else {
+		builder.WriteString("little-endian\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if loopback.data != nil {
+		builder.WriteString(loopback.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(loopback.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 LINKTYPE_NULL 帧，地址族的字节序由取值大小推断
+func NullResolve(packet []byte) resolver.IPacket {
+	if len(packet) < 4 {
+		return nil
+	}
+	return loopbackResolve(packet, utils.ExtractUint32LE(packet, 0) > 0xFFFF)
+}
+
+// 解析 LINKTYPE_LOOP 帧
+func LoopResolve(packet []byte) resolver.IPacket {
+	if len(packet) < 4 {
+		return nil
+	}
+	return loopbackResolve(packet, true)
+}
+
+func loopbackResolve(packet []byte, bigEndian bool) resolver.IPacket {
+	length := len(packet)
+
+	loopback := new(Loopback)
+	loopback.bigEndian = bigEndian
+	if bigEndian {
+		loopback.family = utils.ExtractUint32BE(packet, 0)
+	} else {
+		loopback.family = utils.ExtractUint32LE(packet, 0)
+	}
+	if resolve := networklayer.Resolvers[LOOPBACK_FAMILY_NAME[loopback.family]]; resolve != nil {
+		loopback.data = resolve(packet[4:length])
+	}
+	loopback.raw = make([]byte, length)
+	copy(loopback.raw, packet)
+
+	return loopback
+}
+
+// 解析没有链路层报文头的帧（LINKTYPE_RAW），按版本号区分 IPv4 与 IPv6
+func RawIPResolve(packet []byte) resolver.IPacket {
+	if len(packet) < 1 {
+		return nil
+	}
+	switch packet[0] >> 4 {
+	case 4:
+		return networklayer.IPv4Resolve(packet)
+	case 6:
+		return networklayer.IPv6Resolve(packet)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"packet-inspector/resolver"
+	networklayer "packet-inspector/resolver/network-layer"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// 保留标签（RFC 3032、RFC 7274）
+var MPLS_LABEL_NAME = map[uint32]string{
+	0:  "IPv4 Explicit NULL",
+	1:  "Router Alert",
+	2:  "IPv6 Explicit NULL",
+	3:  "Implicit NULL",
+	7:  "Entropy Label Indicator",
+	13: "Generic Associated Channel",
+	14: "OAM Alert",
+	15: "Extension",
+}
+
+// 标签栈之后的载荷类型，MPLS 报文头中没有该信息，只能由栈底标签或载荷内容推断
+const (
+	MPLS_PAYLOAD_UNKNOWN uint8 = iota
+	MPLS_PAYLOAD_IPv4
+	MPLS_PAYLOAD_IPv6
+	MPLS_PAYLOAD_ETHERNET           // 不带控制字的以太网伪线
+	MPLS_PAYLOAD_ETHERNET_CW        // 带控制字的以太网伪线
+	MPLS_PAYLOAD_ASSOCIATED_CHANNEL // 伪线关联通道（ACH）
+)
+
+var MPLS_PAYLOAD_NAME = map[uint8]string{
+	MPLS_PAYLOAD_UNKNOWN:            "Unknown",
+	MPLS_PAYLOAD_IPv4:               "IPv4",
+	MPLS_PAYLOAD_IPv6:               "IPv6",
+	MPLS_PAYLOAD_ETHERNET:           "Ethernet pseudowire",
+	MPLS_PAYLOAD_ETHERNET_CW:        "Ethernet pseudowire with control word",
+	MPLS_PAYLOAD_ASSOCIATED_CHANNEL: "Pseudowire associated channel",
+}
+
+// 标签栈中的一个条目
+type MPLSLabel struct {
+	Label  uint32 // 标签（20 bit）
+	TC     uint8  // 流量类别（3 bit）
+	Bottom bool   // 是否为栈底（S）
+	TTL    uint8  // 生存时间
+}
+
+// MPLS 标签栈（RFC 3032）
+type MPLS struct {
+	resolver.IPacket
+	raw         []byte           // 原始数据，从第一个标签开始
+	labels      []MPLSLabel      // 标签栈，由外向内
+	payloadType uint8            // 载荷类型
+	explicit    bool             // 载荷类型是否由栈底的显式空标签确定，否则为推断
+	controlWord uint32           // 伪线控制字
+	data        resolver.IPacket // 载荷的数据
+}
+
+func (mpls *MPLS) Raw() []byte {
+	return mpls.raw
+}
+
+func (mpls *MPLS) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(mpls.raw))
+}
+
+func (mpls *MPLS) Inner() resolver.IPacket {
+	return mpls.data
+}
+
+// 标签栈，由外向内
+func (mpls *MPLS) Labels() []MPLSLabel {
+	return mpls.labels
+}
+
+func (mpls *MPLS) Fields() []resolver.Field {
+	fields := []resolver.Field{}
+	for _, label := range mpls.labels {
+		fields = append(fields,
+			resolver.Field{Name: "mpls.label", Value: strconv.FormatUint(uint64(label.Label), 10)},
+			resolver.Field{Name: "mpls.tc", Value: strconv.Itoa(int(label.TC))},
+			resolver.Field{Name: "mpls.ttl", Value: strconv.Itoa(int(label.TTL))},
+		)
+	}
+	fields = append(fields, resolver.Field{Name: "mpls.payload", Value: MPLS_PAYLOAD_NAME[mpls.payloadType]})
+	return fields
+}
+
+func (mpls *MPLS) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: MPLS (Datalink)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Label stack: {\n")
+	for _, label := range mpls.labels {
+		builder.Write(tabs)
+		builder.WriteString("\tLabel: ")
+		builder.WriteString(strconv.FormatUint(uint64(label.Label), 10))
+		if name := MPLS_LABEL_NAME[label.Label]; name != "" {
+			builder.WriteString(" (")
+			builder.WriteString(name)
+			builder.WriteString(")")
+		}
+		builder.WriteString(", TC: ")
+		builder.WriteString(strconv.Itoa(int(label.TC)))
+		builder.WriteString(", S: ")
+		if label.Bottom {
+			builder.WriteString("1")
+		} else {
+			builder.WriteString("0")
+		}
+		builder.WriteString(", TTL: ")
+		builder.WriteString(strconv.Itoa(int(label.TTL)))
+		builder.WriteByte('\n')
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Payload type: ")
+	builder.WriteString(MPLS_PAYLOAD_NAME[mpls.payloadType])
+	if mpls.explicit {
+		builder.WriteString(" (explicit null label)\n")
+	} else if mpls.payloadType != MPLS_PAYLOAD_UNKNOWN {
+		builder.WriteString(" (heuristic)\n")
+	} else {
+		builder.WriteByte('\n')
+	}
+
+	if mpls.payloadType == MPLS_PAYLOAD_ETHERNET_CW {
+		builder.Write(tabs)
+		builder.WriteString("Control word sequence number: ")
+		builder.WriteString(strconv.Itoa(int(mpls.controlWord & 0xFFFF)))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if mpls.data != nil {
+		builder.WriteString(mpls.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(mpls.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 载荷是否像一个 IPv4 报文：版本号为 4 且报文头长度合理，截断由 IPv4Resolve 处理
+func mplsLooksLikeIPv4(payload []byte) bool {
+	return len(payload) > 0 && payload[0]>>4 == 4 && payload[0]&0x0F >= 5
+}
+
+// 载荷是否像一个 IPv6 报文：版本号为 6，截断由 IPv6Resolve 处理
+func mplsLooksLikeIPv6(payload []byte) bool {
+	return len(payload) > 0 && payload[0]>>4 == 6
+}
+
+// 完整的 IPv4 报文头是否与数据矛盾：总长度小于报文头长度，或报文头校验和错误
+func mplsIPv4Inconsistent(payload []byte) bool {
+	headerLength := int(payload[0]&0x0F) * 4
+	if len(payload) < headerLength {
+		return false
+	}
+	if int(utils.ExtractUint16BE(payload, 2)) < headerLength {
+		return true
+	}
+	checksum := utils.ExtractUint16BE(payload, 10)
+	return checksum != 0 && utils.InternetChecksum(payload[:headerLength]) != 0
+}
+
+// 完整的 IPv6 报文头是否与数据矛盾：载荷长度超过剩余数据
+func mplsIPv6Inconsistent(payload []byte) bool {
+	return len(payload) >= 40 && 40+int(utils.ExtractUint16BE(payload, 4)) > len(payload)
+}
+
+// 载荷是否像一个以太网帧：帧类型为已知协议
+func mplsLooksLikeEthernet(payload []byte) bool {
+	return len(payload) >= 14 && ETHERNET_PROTOCOL_NAME[utils.ExtractUint16BE(payload, 12)] != ""
+}
+
+// 载荷是否以伪线控制字开头：首 4 bit 为 0，长度字段为 0，或（载荷不足 64 字节时）为控制字与以太网帧的长度
+func mplsLooksLikeControlWord(payload []byte) bool {
+	if len(payload) < 4+14 || payload[0]>>4 != 0 {
+		return false
+	}
+	size := int(payload[1] & 0x3F)
+	return size == 0 || (size >= 4+14 && size <= len(payload))
+}
+
+func MPLSResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+
+	mpls := new(MPLS)
+	offset := 0
+	for {
+		if offset+4 > length {
+			return nil
+		}
+		entry := utils.ExtractUint32BE(packet, offset)
+		label := MPLSLabel{
+			Label:  entry >> 12,
+			TC:     uint8(entry>>9) & 0x07,
+			Bottom: (entry & 0x100) == 0x100,
+			TTL:    uint8(entry),
+		}
+		mpls.labels = append(mpls.labels, label)
+		offset += 4
+		if label.Bottom {
+			break
+		}
+	}
+
+	payload := packet[offset:length]
+	bottom := mpls.labels[len(mpls.labels)-1].Label
+	switch {
+	case bottom == 0 && mplsLooksLikeIPv4(payload):
+		mpls.payloadType, mpls.explicit = MPLS_PAYLOAD_IPv4, true
+	case bottom == 2 && mplsLooksLikeIPv6(payload):
+		mpls.payloadType, mpls.explicit = MPLS_PAYLOAD_IPv6, true
+	case len(payload) == 0:
+		mpls.payloadType = MPLS_PAYLOAD_UNKNOWN
+	// 按版本号判断 IP 报文，仅当报文头与数据矛盾且载荷像以太网帧时才视为以太网伪线
+	case mplsLooksLikeIPv4(payload) && !(mplsIPv4Inconsistent(payload) && mplsLooksLikeEthernet(payload)):
+		mpls.payloadType = MPLS_PAYLOAD_IPv4
+	case mplsLooksLikeIPv6(payload) && !(mplsIPv6Inconsistent(payload) && mplsLooksLikeEthernet(payload)):
+		mpls.payloadType = MPLS_PAYLOAD_IPv6
+	// 首 4 bit 为 0 的是控制字（RFC 4385），为 1 的是关联通道头
+	case mplsLooksLikeControlWord(payload):
+		mpls.payloadType = MPLS_PAYLOAD_ETHERNET_CW
+	case payload[0]>>4 == 1:
+		mpls.payloadType = MPLS_PAYLOAD_ASSOCIATED_CHANNEL
+	case len(payload) >= 14:
+		mpls.payloadType = MPLS_PAYLOAD_ETHERNET
+	}
+
+	switch mpls.payloadType {
+	case MPLS_PAYLOAD_IPv4:
+		mpls.data = networklayer.IPv4Resolve(payload)
+	case MPLS_PAYLOAD_IPv6:
+		mpls.data = networklayer.IPv6Resolve(payload)
+	case MPLS_PAYLOAD_ETHERNET_CW:
+		mpls.controlWord = utils.ExtractUint32BE(payload, 0)
+		if size := int(payload[1] & 0x3F); size != 0 {
+			payload = payload[:size]
+		}
+		offset += 4
+		mpls.data = EthernetResolve(payload[4:])
+	case MPLS_PAYLOAD_ETHERNET:
+		mpls.data = EthernetResolve(payload)
+	}
+	// 上层协议没有用完的数据（如以太网填充）不属于该标签栈
+	if mpls.data != nil {
+		length = min(length, offset+len(mpls.data.Raw()))
+	}
+	mpls.raw = make([]byte, length)
+	copy(mpls.raw, packet)
+
+	return mpls
+}
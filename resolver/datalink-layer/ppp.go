@@ -0,0 +1,149 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	networklayer "packet-inspector/resolver/network-layer"
+	"packet-inspector/utils"
+	"strings"
+)
+
+const (
+	PPP_PROTOCOL_IPv4   uint16 = 0x0021
+	PPP_PROTOCOL_IPv6   uint16 = 0x0057
+	PPP_PROTOCOL_MPLS   uint16 = 0x0281
+	PPP_PROTOCOL_IPCP   uint16 = 0x8021
+	PPP_PROTOCOL_IPv6CP uint16 = 0x8057
+	PPP_PROTOCOL_LCP    uint16 = 0xC021
+	PPP_PROTOCOL_PAP    uint16 = 0xC023
+	PPP_PROTOCOL_CHAP   uint16 = 0xC223
+)
+
+var PPP_PROTOCOL_NAME = map[uint16]string{
+	PPP_PROTOCOL_IPv4:   "IPv4",
+	PPP_PROTOCOL_IPv6:   "IPv6",
+	PPP_PROTOCOL_MPLS:   "MPLS",
+	PPP_PROTOCOL_IPCP:   "IPCP",
+	PPP_PROTOCOL_IPv6CP: "IPv6CP",
+	PPP_PROTOCOL_LCP:    "LCP",
+	PPP_PROTOCOL_PAP:    "PAP",
+	PPP_PROTOCOL_CHAP:   "CHAP",
+}
+
+// PPP 协议（RFC 1661），地址与控制字段（RFC 1662）可能被省略，协议字段可能被压缩为 1 字节
+type PPP struct {
+	resolver.IPacket
+	raw        []byte           // 原始报文
+	hdlc       bool             // 是否带有地址与控制字段（0xFF 0x03）
+	compressed bool             // 协议字段是否被压缩为 1 字节
+	protocol   uint16           // 上层协议
+	data       resolver.IPacket // 载荷的数据
+}
+
+func (ppp *PPP) Raw() []byte {
+	return ppp.raw
+}
+
+func (ppp *PPP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(ppp.raw))
+}
+
+func (ppp *PPP) Inner() resolver.IPacket {
+	return ppp.data
+}
+
+func (ppp *PPP) Fields() []resolver.Field {
+	return []resolver.Field{
+		{Name: "ppp.protocol", Value: fmt.Sprintf("0x%04X", ppp.protocol)},
+	}
+}
+
+func (ppp *PPP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: PPP (Datalink)\n")
+
+	if ppp.hdlc {
+		builder.Write(tabs)
+		builder.WriteString("Address: 0xFF\n")
+
+		builder.Write(tabs)
+		builder.WriteString("Control: 0x03\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol type: ")
+	builder.WriteString(fmt.Sprintf("0x%04X (", ppp.protocol))
+	if name := PPP_PROTOCOL_NAME[ppp.protocol]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")")
+	if ppp.compressed {
+		builder.WriteString(" [compressed]")
+	}
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if ppp.data != nil {
+		builder.WriteString(ppp.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(ppp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 PPP 帧（LINKTYPE_PPP 与 LINKTYPE_PPP_HDLC）
+func PPPResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	offset := 0
+
+	ppp := new(PPP)
+	if length >= 2 && packet[0] == 0xFF && packet[1] == 0x03 {
+		ppp.hdlc = true
+		offset = 2
+	}
+	if offset >= length {
+		return nil
+	}
+	// 协议字段的第一个字节为奇数时，协议字段被压缩为 1 字节
+	if packet[offset]&0x01 == 0x01 {
+		ppp.compressed = true
+		ppp.protocol = uint16(packet[offset])
+		offset++
+	} else {
+		if offset+2 > length {
+			return nil
+		}
+		ppp.protocol = utils.ExtractUint16BE(packet, offset)
+		offset += 2
+	}
+
+	name := PPP_PROTOCOL_NAME[ppp.protocol]
+	if resolve := EtherTypeResolvers[name]; resolve != nil {
+		ppp.data = resolve(packet[offset:length])
+	} else if resolve := networklayer.Resolvers[name]; resolve != nil {
+		ppp.data = resolve(packet[offset:length])
+	}
+	ppp.raw = make([]byte, length)
+	copy(ppp.raw, packet)
+
+	return ppp
+}
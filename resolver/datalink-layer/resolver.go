@@ -1,9 +1,75 @@
 package datalinklayer
 
-import "packet-inspector/resolver"
+import (
+	"packet-inspector/resolver"
+	networklayer "packet-inspector/resolver/network-layer"
+)
 
+// 链路层入口，键为 LINK_TYPE_NAME 中的名称
 var Resolvers = map[string]resolver.PacketResolver{}
 
+// 由以太网帧类型分派的链路层协议，键为 ETHERNET_PROTOCOL_NAME 中的名称
+var EtherTypeResolvers = map[string]resolver.PacketResolver{}
+
+// pcap 链路类型（LINKTYPE_*），见 https://www.tcpdump.org/linktypes.html
+const (
+	LINK_TYPE_NULL       uint16 = 0
+	LINK_TYPE_ETHERNET   uint16 = 1
+	LINK_TYPE_PPP        uint16 = 9
+	LINK_TYPE_DLT_RAW    uint16 = 12 // libpcap 读取 LINKTYPE_RAW 时返回的 DLT_RAW
+	LINK_TYPE_PPP_HDLC   uint16 = 50
+	LINK_TYPE_RAW        uint16 = 101
+	LINK_TYPE_LOOP       uint16 = 108
+	LINK_TYPE_LINUX_SLL  uint16 = 113
+	LINK_TYPE_IPv4       uint16 = 228
+	LINK_TYPE_IPv6       uint16 = 229
+	LINK_TYPE_LINUX_SLL2 uint16 = 276
+)
+
+var LINK_TYPE_NAME = map[uint16]string{
+	LINK_TYPE_NULL:       "null",
+	LINK_TYPE_ETHERNET:   "ethernet",
+	LINK_TYPE_PPP:        "ppp",
+	LINK_TYPE_DLT_RAW:    "raw",
+	LINK_TYPE_PPP_HDLC:   "ppp_hdlc",
+	LINK_TYPE_RAW:        "raw",
+	LINK_TYPE_LOOP:       "loop",
+	LINK_TYPE_LINUX_SLL:  "linux_sll",
+	LINK_TYPE_IPv4:       "ipv4",
+	LINK_TYPE_IPv6:       "ipv6",
+	LINK_TYPE_LINUX_SLL2: "linux_sll2",
+}
+
+// 按 pcap 链路类型解析一帧，不支持的链路类型返回 nil
+func LinkTypeResolve(linkType uint16, packet []byte) resolver.IPacket {
+	if resolve := Resolvers[LINK_TYPE_NAME[linkType]]; resolve != nil {
+		return resolve(packet)
+	}
+	return nil
+}
+
 func init() {
-	Resolvers["ethernet"] = EthernetResolve
+	Resolvers["ethernet"] = CapturedEthernetResolve
+	Resolvers["null"] = NullResolve
+	Resolvers["loop"] = LoopResolve
+	Resolvers["ppp"] = PPPResolve
+	Resolvers["ppp_hdlc"] = PPPResolve
+	Resolvers["raw"] = RawIPResolve
+	Resolvers["ipv4"] = networklayer.IPv4Resolve
+	Resolvers["ipv6"] = networklayer.IPv6Resolve
+	Resolvers["linux_sll"] = LinuxSLLResolve
+	Resolvers["linux_sll2"] = LinuxSLL2Resolve
+
+	EtherTypeResolvers["802.1Q"] = VLANResolve
+	EtherTypeResolvers["802.1ad"] = QinQResolve
+	EtherTypeResolvers["QinQ"] = LegacyQinQResolve
+	EtherTypeResolvers["MPLS"] = MPLSResolve
+	EtherTypeResolvers["MPLS multicast"] = MPLSResolve
+	EtherTypeResolvers["LLDP"] = LLDPResolve
+
+	LLCResolvers["STP"] = STPResolve
+	LLCResolvers["IP"] = networklayer.IPv4Resolve
+
+	SNAPResolvers["PVST+"] = PVSTResolve
+	SNAPResolvers["CDP"] = CDPResolve
 }
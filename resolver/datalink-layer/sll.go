@@ -0,0 +1,237 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// 报文方向
+var SLL_PACKET_TYPE_NAME = map[uint8]string{
+	0: "Unicast to us",
+	1: "Broadcast",
+	2: "Multicast",
+	3: "Unicast to another host",
+	4: "Sent by us",
+}
+
+// 链路层地址类型（ARPHRD_*）
+var SLL_HARDWARE_TYPE_NAME = map[uint16]string{
+	1:     "Ethernet",
+	512:   "PPP",
+	768:   "IPIP tunnel",
+	769:   "IPv6-in-IPv4 tunnel",
+	772:   "Loopback",
+	776:   "IPv6-in-IPv4",
+	778:   "GRE tunnel",
+	801:   "IEEE 802.11",
+	803:   "IEEE 802.11 + radiotap",
+	823:   "IPv6 GRE tunnel",
+	824:   "Netlink",
+	65534: "None",
+}
+
+// 载荷为 IEEE 802.2 LLC
+const SLL_PROTOCOL_LLC uint16 = 0x0004
+
+// 协议字段小于 0x600 时不是以太网帧类型
+var SLL_PROTOCOL_NAME = map[uint16]string{
+	0x0001:           "Novell 802.3",
+	0x0003:           "All protocols",
+	SLL_PROTOCOL_LLC: "802.2 LLC",
+}
+
+// Linux cooked capture（LINKTYPE_LINUX_SLL 与 LINKTYPE_LINUX_SLL2），在 any 等没有统一链路层的设备上抓包时使用
+type LinuxSLL struct {
+	resolver.IPacket
+	raw          []byte           // 原始报文
+	version      uint8            // 1 或 2
+	packetType   uint8            // 报文方向
+	hardwareType uint16           // 链路层地址类型
+	address      []byte           // 发送方的链路层地址
+	protocol     uint16           // 上层协议，通常为以太网帧类型
+	ifindex      uint32           // SLL2：接口序号
+	data         resolver.IPacket // 载荷的数据
+}
+
+func (sll *LinuxSLL) Raw() []byte {
+	return sll.raw
+}
+
+func (sll *LinuxSLL) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(sll.raw))
+}
+
+func (sll *LinuxSLL) Inner() resolver.IPacket {
+	return sll.data
+}
+
+// 发送方的链路层地址，6 字节时按 MAC 地址显示
+func (sll *LinuxSLL) addressString() string {
+	if len(sll.address) == 6 {
+		mac := types.Mac{}
+		mac.Parse([6]byte(sll.address))
+		return macString(mac)
+	}
+	if len(sll.address) == 0 {
+		return "(none)"
+	}
+	return strings.ToUpper(hex.EncodeToString(sll.address))
+}
+
+func (sll *LinuxSLL) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "sll.pkttype", Value: strconv.Itoa(int(sll.packetType))},
+		{Name: "sll.hatype", Value: strconv.Itoa(int(sll.hardwareType))},
+		{Name: "sll.protocol", Value: fmt.Sprintf("0x%04X", sll.protocol)},
+	}
+	if len(sll.address) == 6 {
+		mac := types.Mac{}
+		mac.Parse([6]byte(sll.address))
+		fields = append(fields, resolver.Field{Name: "sll.src", Value: mac.ToString()})
+	}
+	if sll.version == 2 {
+		fields = append(fields, resolver.Field{Name: "sll.ifindex", Value: strconv.FormatUint(uint64(sll.ifindex), 10)})
+	}
+	return fields
+}
+
+func (sll *LinuxSLL) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	if sll.version == 2 {
+		builder.WriteString("Protocol: Linux cooked capture v2 (Datalink)\n")
+	} else {
+		builder.WriteString("Protocol: Linux cooked capture (Datalink)\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Packet type: ")
+	builder.WriteString(strconv.Itoa(int(sll.packetType)))
+	builder.WriteString(" (")
+	if name := SLL_PACKET_TYPE_NAME[sll.packetType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	if sll.version == 2 {
+		builder.Write(tabs)
+		builder.WriteString("Interface index: ")
+		builder.WriteString(strconv.FormatUint(uint64(sll.ifindex), 10))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Link-layer address type: ")
+	builder.WriteString(strconv.Itoa(int(sll.hardwareType)))
+	builder.WriteString(" (")
+	if name := SLL_HARDWARE_TYPE_NAME[sll.hardwareType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Source address: ")
+	builder.WriteString(sll.addressString())
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol type: ")
+	builder.WriteString(fmt.Sprintf("0x%04X (", sll.protocol))
+	name := SLL_PROTOCOL_NAME[sll.protocol]
+	if sll.protocol >= 0x600 {
+		name = ETHERNET_PROTOCOL_NAME[sll.protocol]
+	}
+	if name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if sll.data != nil {
+		builder.WriteString(sll.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(sll.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 SLL 帧，报文头 16 字节
+func LinuxSLLResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 16 {
+		return nil
+	}
+
+	sll := new(LinuxSLL)
+	sll.version = 1
+	sll.packetType = uint8(utils.ExtractUint16BE(packet, 0))
+	sll.hardwareType = utils.ExtractUint16BE(packet, 2)
+	sll.address = sllAddress(packet[6:14], int(utils.ExtractUint16BE(packet, 4)))
+	sll.protocol = utils.ExtractUint16BE(packet, 14)
+	return sllResolve(sll, packet, 16)
+}
+
+// 解析 SLL2 帧，报文头 20 字节
+func LinuxSLL2Resolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 20 {
+		return nil
+	}
+
+	sll := new(LinuxSLL)
+	sll.version = 2
+	sll.protocol = utils.ExtractUint16BE(packet, 0)
+	sll.ifindex = utils.ExtractUint32BE(packet, 4)
+	sll.hardwareType = utils.ExtractUint16BE(packet, 8)
+	sll.packetType = packet[10]
+	sll.address = sllAddress(packet[12:20], int(packet[11]))
+	return sllResolve(sll, packet, 20)
+}
+
+// 地址字段固定 8 字节，超出部分被截断
+func sllAddress(field []byte, length int) []byte {
+	length = min(length, len(field))
+	address := make([]byte, length)
+	copy(address, field[:length])
+	return address
+}
+
+func sllResolve(sll *LinuxSLL, packet []byte, header int) resolver.IPacket {
+	length := len(packet)
+	// 协议字段小于 0x600 时为 Linux 内部的协议号，不是以太网帧类型
+	if sll.protocol >= 0x600 {
+		sll.data = EtherTypeResolve(sll.protocol, packet[header:length])
+	} else if sll.protocol == SLL_PROTOCOL_LLC {
+		sll.data = LLCResolve(packet[header:length])
+	}
+	sll.raw = make([]byte, length)
+	copy(sll.raw, packet)
+
+	return sll
+}
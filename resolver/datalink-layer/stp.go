@@ -0,0 +1,462 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// BPDU 协议版本
+const (
+	STP_VERSION_STP  uint8 = 0
+	STP_VERSION_RSTP uint8 = 2
+	STP_VERSION_MSTP uint8 = 3
+)
+
+var STP_VERSION_NAME = map[uint8]string{
+	STP_VERSION_STP:  "Spanning Tree",
+	STP_VERSION_RSTP: "Rapid Spanning Tree",
+	STP_VERSION_MSTP: "Multiple Spanning Tree",
+}
+
+// BPDU 类型
+const (
+	STP_TYPE_CONFIG uint8 = 0x00
+	STP_TYPE_RST    uint8 = 0x02
+	STP_TYPE_TCN    uint8 = 0x80
+)
+
+var STP_TYPE_NAME = map[uint8]string{
+	STP_TYPE_CONFIG: "Configuration",
+	STP_TYPE_RST:    "Rapid/Multiple Spanning Tree",
+	STP_TYPE_TCN:    "Topology Change Notification",
+}
+
+// 标志字段
+const (
+	STP_FLAG_TC         uint8 = 0x01 // 拓扑变化
+	STP_FLAG_PROPOSAL   uint8 = 0x02 // 提议
+	STP_FLAG_ROLE       uint8 = 0x0C // 端口角色（2 bit）
+	STP_FLAG_LEARNING   uint8 = 0x10 // 学习
+	STP_FLAG_FORWARDING uint8 = 0x20 // 转发
+	STP_FLAG_AGREEMENT  uint8 = 0x40 // 同意
+	STP_FLAG_TCA        uint8 = 0x80 // 拓扑变化确认
+)
+
+// 端口角色，RSTP 与 MSTP 的标志字段第 2、3 位
+var STP_PORT_ROLE_NAME = map[uint8]string{
+	0: "Unknown",
+	1: "Alternate/Backup",
+	2: "Root",
+	3: "Designated",
+}
+
+// 桥 ID：4 bit 优先级、12 bit 系统 ID 扩展（通常为 VLAN 或 MSTI）与 MAC 地址
+type BridgeID struct {
+	Priority uint16    // 优先级，4096 的倍数
+	System   uint16    // 系统 ID 扩展
+	Mac      types.Mac // 桥 MAC 地址
+}
+
+func (id *BridgeID) parse(packet []byte) {
+	temp := utils.ExtractUint16BE(packet, 0)
+	id.Priority = temp & 0xF000
+	id.System = temp & 0x0FFF
+	id.Mac.Parse([6]byte(packet[2:8]))
+}
+
+// 格式化为 "优先级 / 系统 ID 扩展 / MAC 地址"
+func (id *BridgeID) ToString() string {
+	return strconv.Itoa(int(id.Priority)) + " / " + strconv.Itoa(int(id.System)) + " / " + macString(id.Mac)
+}
+
+// MSTP 中每个生成树实例的配置
+type MSTIConfig struct {
+	Flags        uint8    // 标志，含义同 BPDU 的标志字段
+	RegionalRoot BridgeID // 域根桥，系统 ID 扩展为实例号
+	PathCost     uint32   // 到域根桥的内部路径开销
+	Priority     uint8    // 桥优先级（高 4 bit）
+	PortPriority uint8    // 端口优先级（高 4 bit）
+	Hops         uint8    // 剩余跳数
+}
+
+// 生成树协议（STP、RSTP、MSTP）的网桥协议数据单元
+type STP struct {
+	resolver.IPacket
+	raw          []byte       // 原始数据
+	protocol     uint16       // 协议标识，固定为 0
+	version      uint8        // 协议版本
+	bpduType     uint8        // BPDU 类型
+	flags        uint8        // 标志
+	root         BridgeID     // 根桥
+	rootPathCost uint32       // 到根桥的路径开销
+	bridge       BridgeID     // 发送者桥 ID
+	port         uint16       // 端口 ID，高 4 bit 为优先级
+	messageAge   uint16       // 消息寿命，单位 1/256 秒
+	maxAge       uint16       // 最大寿命，单位 1/256 秒
+	helloTime    uint16       // Hello 时间，单位 1/256 秒
+	forwardDelay uint16       // 转发延迟，单位 1/256 秒
+	mst          bool         // 是否带有 MSTP 扩展
+	mstName      string       // MST 配置名称
+	mstRevision  uint16       // MST 配置修订号
+	mstDigest    [16]byte     // MST 配置摘要
+	cistCost     uint32       // CIST 内部根路径开销
+	cistBridge   BridgeID     // CIST 桥 ID
+	cistHops     uint8        // CIST 剩余跳数
+	msti         []MSTIConfig // 各实例的配置
+	pvst         bool         // 是否为 Cisco PVST+
+	vlan         uint16       // PVST+ 的源 VLAN
+}
+
+func (stp *STP) Raw() []byte {
+	return stp.raw
+}
+
+func (stp *STP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(stp.raw))
+}
+
+func (stp *STP) Inner() resolver.IPacket {
+	return nil
+}
+
+// 根桥，TCN BPDU 没有该字段
+func (stp *STP) Root() BridgeID {
+	return stp.root
+}
+
+// 发送者桥 ID，TCN BPDU 没有该字段
+func (stp *STP) Bridge() BridgeID {
+	return stp.bridge
+}
+
+// 是否为拓扑变化通知或置位了拓扑变化标志
+func (stp *STP) TopologyChange() bool {
+	return stp.bpduType == STP_TYPE_TCN || stp.flags&STP_FLAG_TC != 0
+}
+
+func (stp *STP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "stp.version", Value: strconv.Itoa(int(stp.version))},
+		{Name: "stp.type", Value: fmt.Sprintf("0x%02X", stp.bpduType)},
+	}
+	if stp.bpduType == STP_TYPE_TCN {
+		return fields
+	}
+	tc := "0"
+	if stp.flags&STP_FLAG_TC != 0 {
+		tc = "1"
+	}
+	fields = append(fields,
+		resolver.Field{Name: "stp.flags.tc", Value: tc},
+		resolver.Field{Name: "stp.root.priority", Value: strconv.Itoa(int(stp.root.Priority))},
+		resolver.Field{Name: "stp.root.mac", Value: stp.root.Mac.ToString()},
+		resolver.Field{Name: "stp.root.cost", Value: strconv.FormatUint(uint64(stp.rootPathCost), 10)},
+		resolver.Field{Name: "stp.bridge.priority", Value: strconv.Itoa(int(stp.bridge.Priority))},
+		resolver.Field{Name: "stp.bridge.mac", Value: stp.bridge.Mac.ToString()},
+	)
+	if stp.bpduType == STP_TYPE_RST {
+		fields = append(fields, resolver.Field{Name: "stp.port.role", Value: STP_PORT_ROLE_NAME[(stp.flags&STP_FLAG_ROLE)>>2]})
+	}
+	if stp.pvst {
+		fields = append(fields, resolver.Field{Name: "stp.pvst.vlan", Value: strconv.Itoa(int(stp.vlan))})
+	}
+	return fields
+}
+
+// 以秒为单位的计时器
+func stpTime(value uint16) string {
+	return strconv.FormatFloat(float64(value)/256, 'f', -1, 64) + " s"
+}
+
+// 标志字段的可读形式，RSTP 与 MSTP 带有端口角色等标志
+func stpFlags(flags uint8, rapid bool) string {
+	names := []string{}
+	if flags&STP_FLAG_TCA != 0 {
+		names = append(names, "Topology Change Acknowledgment")
+	}
+	if rapid {
+		if flags&STP_FLAG_AGREEMENT != 0 {
+			names = append(names, "Agreement")
+		}
+		if flags&STP_FLAG_FORWARDING != 0 {
+			names = append(names, "Forwarding")
+		}
+		if flags&STP_FLAG_LEARNING != 0 {
+			names = append(names, "Learning")
+		}
+		names = append(names, "Port Role: "+STP_PORT_ROLE_NAME[(flags&STP_FLAG_ROLE)>>2])
+		if flags&STP_FLAG_PROPOSAL != 0 {
+			names = append(names, "Proposal")
+		}
+	}
+	if flags&STP_FLAG_TC != 0 {
+		names = append(names, "Topology Change")
+	}
+	return fmt.Sprintf("0x%02X (", flags) + strings.Join(names, ", ") + ")"
+}
+
+func (stp *STP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: ")
+	if name := STP_VERSION_NAME[stp.version]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Spanning Tree")
+	}
+	if stp.pvst {
+		builder.WriteString(" (PVST+)")
+	}
+	builder.WriteString(" (Datalink)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol version: ")
+	builder.WriteString(strconv.Itoa(int(stp.version)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("BPDU type: ")
+	builder.WriteString(fmt.Sprintf("0x%02X (", stp.bpduType))
+	if name := STP_TYPE_NAME[stp.bpduType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	if stp.bpduType != STP_TYPE_TCN {
+		builder.Write(tabs)
+		builder.WriteString("Flags: ")
+		builder.WriteString(stpFlags(stp.flags, stp.bpduType == STP_TYPE_RST))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Root bridge: ")
+		builder.WriteString(stp.root.ToString())
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Root path cost: ")
+		builder.WriteString(strconv.FormatUint(uint64(stp.rootPathCost), 10))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Bridge: ")
+		builder.WriteString(stp.bridge.ToString())
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Port: ")
+		builder.WriteString(fmt.Sprintf("0x%04X (priority %d, port %d)", stp.port, stp.port>>12<<4, stp.port&0x0FFF))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Message age: ")
+		builder.WriteString(stpTime(stp.messageAge))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Max age: ")
+		builder.WriteString(stpTime(stp.maxAge))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Hello time: ")
+		builder.WriteString(stpTime(stp.helloTime))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Forward delay: ")
+		builder.WriteString(stpTime(stp.forwardDelay))
+		builder.WriteByte('\n')
+	}
+
+	if stp.mst {
+		builder.Write(tabs)
+		builder.WriteString("MST config name: ")
+		builder.WriteString(strconv.Quote(stp.mstName))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("MST config revision: ")
+		builder.WriteString(strconv.Itoa(int(stp.mstRevision)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("MST config digest: ")
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(stp.mstDigest[:])))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("CIST internal root path cost: ")
+		builder.WriteString(strconv.FormatUint(uint64(stp.cistCost), 10))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("CIST bridge: ")
+		builder.WriteString(stp.cistBridge.ToString())
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("CIST remaining hops: ")
+		builder.WriteString(strconv.Itoa(int(stp.cistHops)))
+		builder.WriteByte('\n')
+
+		for _, msti := range stp.msti {
+			builder.Write(tabs)
+			builder.WriteString("MSTI ")
+			builder.WriteString(strconv.Itoa(int(msti.RegionalRoot.System)))
+			builder.WriteString(": {\n")
+
+			builder.Write(tabs)
+			builder.WriteString("\tFlags: ")
+			// MSTI 中角色 0 表示主端口
+			flags := stpFlags(msti.Flags, true)
+			if msti.Flags&STP_FLAG_ROLE == 0 {
+				flags = strings.Replace(flags, "Port Role: Unknown", "Port Role: Master", 1)
+			}
+			builder.WriteString(flags)
+			builder.WriteByte('\n')
+
+			builder.Write(tabs)
+			builder.WriteString("\tRegional root: ")
+			builder.WriteString(msti.RegionalRoot.ToString())
+			builder.WriteByte('\n')
+
+			builder.Write(tabs)
+			builder.WriteString("\tInternal root path cost: ")
+			builder.WriteString(strconv.FormatUint(uint64(msti.PathCost), 10))
+			builder.WriteByte('\n')
+
+			builder.Write(tabs)
+			builder.WriteString("\tBridge priority: ")
+			builder.WriteString(strconv.Itoa(int(msti.Priority>>4) << 12))
+			builder.WriteByte('\n')
+
+			builder.Write(tabs)
+			builder.WriteString("\tPort priority: ")
+			builder.WriteString(strconv.Itoa(int(msti.PortPriority>>4) << 4))
+			builder.WriteByte('\n')
+
+			builder.Write(tabs)
+			builder.WriteString("\tRemaining hops: ")
+			builder.WriteString(strconv.Itoa(int(msti.Hops)))
+			builder.WriteByte('\n')
+
+			builder.Write(tabs)
+			builder.WriteString("}\n")
+		}
+	}
+
+	if stp.pvst {
+		builder.Write(tabs)
+		builder.WriteString("Originating VLAN: ")
+		builder.WriteString(strconv.Itoa(int(stp.vlan)))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(stp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 IEEE 802.1D/802.1w/802.1s BPDU，packet 从协议标识开始
+func STPResolve(packet []byte) resolver.IPacket {
+	if stp := stpResolve(packet); stp != nil {
+		return stp
+	}
+	return nil
+}
+
+// 解析 Cisco PVST+ BPDU，BPDU 之后带有源 VLAN 的 TLV
+func PVSTResolve(packet []byte) resolver.IPacket {
+	stp := stpResolve(packet)
+	if stp == nil {
+		return nil
+	}
+	rest := packet[len(stp.raw):]
+	if len(rest) >= 6 && utils.ExtractUint16BE(rest, 0) == 0 && utils.ExtractUint16BE(rest, 2) == 2 {
+		stp.pvst = true
+		stp.vlan = utils.ExtractUint16BE(rest, 4)
+		stp.raw = append(stp.raw, rest[:6]...)
+	}
+	return stp
+}
+
+func stpResolve(packet []byte) *STP {
+	length := len(packet)
+	if length < 4 || utils.ExtractUint16BE(packet, 0) != 0 {
+		return nil
+	}
+
+	stp := new(STP)
+	stp.protocol = utils.ExtractUint16BE(packet, 0)
+	stp.version = packet[2]
+	stp.bpduType = packet[3]
+	used := 4
+	switch stp.bpduType {
+	case STP_TYPE_TCN:
+	case STP_TYPE_CONFIG, STP_TYPE_RST:
+		if length < 35 {
+			return nil
+		}
+		stp.flags = packet[4]
+		stp.root.parse(packet[5:13])
+		stp.rootPathCost = utils.ExtractUint32BE(packet, 13)
+		stp.bridge.parse(packet[17:25])
+		stp.port = utils.ExtractUint16BE(packet, 25)
+		stp.messageAge = utils.ExtractUint16BE(packet, 27)
+		stp.maxAge = utils.ExtractUint16BE(packet, 29)
+		stp.helloTime = utils.ExtractUint16BE(packet, 31)
+		stp.forwardDelay = utils.ExtractUint16BE(packet, 33)
+		used = 35
+		if stp.bpduType == STP_TYPE_RST && length >= 36 {
+			// 第 35 字节为 Version 1 Length，固定为 0
+			used = 36
+		}
+		// MSTP 扩展：Version 3 Length 之后为 MST 配置标识、CIST 信息与各实例的配置
+		if stp.bpduType == STP_TYPE_RST && stp.version >= STP_VERSION_MSTP && length >= 102 {
+			v3Length := int(utils.ExtractUint16BE(packet, 36))
+			if v3Length < 64 || 38+v3Length > length {
+				break
+			}
+			stp.mst = true
+			stp.mstName = strings.TrimRight(string(packet[39:71]), "\x00")
+			stp.mstRevision = utils.ExtractUint16BE(packet, 71)
+			copy(stp.mstDigest[:], packet[73:89])
+			stp.cistCost = utils.ExtractUint32BE(packet, 89)
+			stp.cistBridge.parse(packet[93:101])
+			stp.cistHops = packet[101]
+			for offset := 102; offset+16 <= 38+v3Length; offset += 16 {
+				msti := MSTIConfig{}
+				msti.Flags = packet[offset]
+				msti.RegionalRoot.parse(packet[offset+1 : offset+9])
+				msti.PathCost = utils.ExtractUint32BE(packet, offset+9)
+				msti.Priority = packet[offset+13]
+				msti.PortPriority = packet[offset+14]
+				msti.Hops = packet[offset+15]
+				stp.msti = append(stp.msti, msti)
+			}
+			used = 38 + v3Length
+		}
+	default:
+		return nil
+	}
+	stp.raw = make([]byte, used)
+	copy(stp.raw, packet)
+
+	return stp
+}
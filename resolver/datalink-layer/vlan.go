@@ -0,0 +1,179 @@
+package datalinklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+var VLAN_PRIORITY_NAME = map[uint8]string{
+	0: "Best Effort",
+	1: "Background",
+	2: "Excellent Effort",
+	3: "Critical Applications",
+	4: "Video",
+	5: "Voice",
+	6: "Internetwork Control",
+	7: "Network Control",
+}
+
+// IEEE 802.1Q VLAN 标签（QinQ 时可能层层嵌套）
+type VLAN struct {
+	resolver.IPacket
+	raw      []byte           // 原始数据，从标签控制信息开始
+	tpid     uint16           // 标签协议标识，位于上一层报文头中，0x8100 为 C-Tag，0x88A8 为 S-Tag
+	priority uint8            // 优先级 PCP（3 bit）
+	dei      bool             // 丢弃指示 DEI（1 bit）
+	id       uint16           // VLAN ID（12 bit）
+	etype    uint16           // 内层以太网帧类型
+	data     resolver.IPacket // 内层协议数据
+}
+
+func (vlan *VLAN) Raw() []byte {
+	return vlan.raw
+}
+
+func (vlan *VLAN) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(vlan.raw))
+}
+
+func (vlan *VLAN) Inner() resolver.IPacket {
+	return vlan.data
+}
+
+func (vlan *VLAN) Fields() []resolver.Field {
+	dei := "0"
+	if vlan.dei {
+		dei = "1"
+	}
+	return []resolver.Field{
+		{Name: "vlan.tpid", Value: fmt.Sprintf("0x%04X", vlan.tpid)},
+		{Name: "vlan.id", Value: strconv.Itoa(int(vlan.id))},
+		{Name: "vlan.priority", Value: strconv.Itoa(int(vlan.priority))},
+		{Name: "vlan.dei", Value: dei},
+		{Name: "vlan.etype", Value: fmt.Sprintf("0x%04X", vlan.etype)},
+	}
+}
+
+// VLAN ID
+func (vlan *VLAN) ID() uint16 {
+	return vlan.id
+}
+
+func (vlan *VLAN) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: ")
+	builder.WriteString(ETHERNET_PROTOCOL_NAME[vlan.tpid])
+	builder.WriteString(" VLAN (Datalink)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Priority: ")
+	builder.WriteString(strconv.Itoa(int(vlan.priority)))
+	builder.WriteString(" (")
+	builder.WriteString(VLAN_PRIORITY_NAME[vlan.priority])
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Drop eligible: ")
+	if vlan.dei {
+		builder.WriteString("1")
+	} else {
+		builder.WriteString("0")
+	}
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("ID: ")
+	builder.WriteString(strconv.Itoa(int(vlan.id)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	if vlan.etype <= 1500 {
+		builder.WriteString("Length: ")
+		builder.WriteString(strconv.Itoa(int(vlan.etype)))
+		builder.WriteByte('\n')
+	} else {
+		builder.WriteString("Protocol type: ")
+		builder.WriteString(fmt.Sprintf("0x%04X (", vlan.etype))
+		temp := ETHERNET_PROTOCOL_NAME[vlan.etype]
+		if temp != "" {
+			builder.WriteString(temp)
+		} else {
+			builder.WriteString("Unknown")
+		}
+		builder.WriteString(")\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if vlan.data != nil {
+		builder.WriteString(vlan.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(vlan.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 802.1Q C-Tag，packet 从标签控制信息开始
+func VLANResolve(packet []byte) resolver.IPacket {
+	return vlanResolve(ETHERNET_PROTOCOL_8021Q, packet)
+}
+
+// 解析 802.1ad S-Tag（QinQ 外层标签），packet 从标签控制信息开始
+func QinQResolve(packet []byte) resolver.IPacket {
+	return vlanResolve(ETHERNET_PROTOCOL_8021AD, packet)
+}
+
+// 解析旧式 QinQ 标签（TPID 0x9100），packet 从标签控制信息开始
+func LegacyQinQResolve(packet []byte) resolver.IPacket {
+	return vlanResolve(ETHERNET_PROTOCOL_QINQ, packet)
+}
+
+func vlanResolve(tpid uint16, packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 4 {
+		return nil
+	}
+
+	vlan := new(VLAN)
+	vlan.tpid = tpid
+	tci := utils.ExtractUint16BE(packet, 0)
+	vlan.priority = uint8(tci >> 13)
+	vlan.dei = (tci & 0x1000) != 0
+	vlan.id = tci & 0x0FFF
+	vlan.etype = utils.ExtractUint16BE(packet, 2)
+	if vlan.etype <= 1500 {
+		// 内层为 IEEE 802.3 帧，该字段为 LLC 的长度
+		if 4+int(vlan.etype) <= length {
+			vlan.data = LLCResolve(packet[4 : 4+int(vlan.etype)])
+		}
+	} else {
+		vlan.data = EtherTypeResolve(vlan.etype, packet[4:length])
+	}
+	// 上层协议没有用完的数据（如以太网填充）不属于该标签
+	if vlan.data != nil {
+		length = min(length, 4+len(vlan.data.Raw()))
+	}
+	vlan.raw = make([]byte, length)
+	copy(vlan.raw, packet)
+
+	return vlan
+}
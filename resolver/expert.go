@@ -0,0 +1,132 @@
+package resolver
+
+import "strings"
+
+// 专家信息的严重程度
+type Severity uint8
+
+const (
+	SEVERITY_CHAT  Severity = 0 // 正常流程中值得一提的事件
+	SEVERITY_NOTE  Severity = 1 // 不常见但可能正常的情况
+	SEVERITY_WARN  Severity = 2 // 很可能有问题
+	SEVERITY_ERROR Severity = 3 // 报文有错误
+)
+
+var SEVERITY_NAME = map[Severity]string{
+	SEVERITY_CHAT:  "chat",
+	SEVERITY_NOTE:  "note",
+	SEVERITY_WARN:  "warn",
+	SEVERITY_ERROR: "error",
+}
+
+// 专家信息的分组
+type Group uint8
+
+const (
+	GROUP_MALFORMED Group = 0 // 长度、校验和等格式错误
+	GROUP_SEQUENCE  Group = 1 // 序号、重传、窗口等连接状态问题
+	GROUP_PROTOCOL  Group = 2 // 不符合协议约定的取值或行为
+	GROUP_SECURITY  Group = 3 // 可能的攻击或欺骗
+)
+
+var GROUP_NAME = map[Group]string{
+	GROUP_MALFORMED: "malformed",
+	GROUP_SEQUENCE:  "sequence",
+	GROUP_PROTOCOL:  "protocol",
+	GROUP_SECURITY:  "security",
+}
+
+// 由解析器或分析器附加到报文上的专家信息
+type Expert struct {
+	Severity Severity
+	Group    Group
+	Protocol string // 产生该信息的协议，如 "IPv4"
+	Message  string // 不含具体取值的描述，同类信息的描述相同以便汇总
+}
+
+// 格式化为一行，如 "warn/sequence TCP: Retransmission"
+func (expert Expert) ToString() string {
+	return SEVERITY_NAME[expert.Severity] + "/" + GROUP_NAME[expert.Group] + " " + expert.Protocol + ": " + expert.Message
+}
+
+// 带有专家信息的报文
+type IExperts interface {
+	Experts() []Expert
+	AddExpert(severity Severity, group Group, protocol string, message string)
+}
+
+// ICMP 差错报文中引用的原始报文，其中的问题属于原始报文，不计入当前报文
+type IQuoted interface {
+	Quoted() bool
+}
+
+// 嵌入到报文结构体中，为报文提供专家信息
+type ExpertInfo struct {
+	experts []Expert
+}
+
+func (info *ExpertInfo) Experts() []Expert {
+	return info.experts
+}
+
+// 附加一条专家信息
+func (info *ExpertInfo) AddExpert(severity Severity, group Group, protocol string, message string) {
+	info.experts = append(info.experts, Expert{Severity: severity, Group: group, Protocol: protocol, Message: message})
+}
+
+// 由外向内依次收集各层报文的专家信息，不包括 ICMP 差错报文引用的原始报文
+func CollectExperts(packet IPacket) []Expert {
+	experts := []Expert{}
+	for packet != nil {
+		if p, ok := packet.(IQuoted); ok && p.Quoted() {
+			break
+		}
+		if p, ok := packet.(IExperts); ok {
+			experts = append(experts, p.Experts()...)
+		}
+		container, ok := packet.(IContainer)
+		if !ok {
+			break
+		}
+		packet = container.Inner()
+	}
+	return experts
+}
+
+// 专家信息对应的过滤字段，每条信息产生 "expert.severity"、"expert.group"、"expert.protocol" 与 "expert.message" 各一个
+func expertFields(experts []Expert) []Field {
+	fields := make([]Field, 0, 4*len(experts))
+	for _, expert := range experts {
+		fields = append(fields,
+			Field{Name: "expert.severity", Value: SEVERITY_NAME[expert.Severity]},
+			Field{Name: "expert.group", Value: GROUP_NAME[expert.Group]},
+			Field{Name: "expert.protocol", Value: expert.Protocol},
+			Field{Name: "expert.message", Value: expert.Message},
+		)
+	}
+	return fields
+}
+
+// 转换为可读字符串，没有专家信息时为空
+func ExpertsToReadableString(experts []Expert, indent int) string {
+	if len(experts) == 0 {
+		return ""
+	}
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Expert info: {\n")
+	for _, expert := range experts {
+		builder.Write(tabs)
+		builder.WriteByte('\t')
+		builder.WriteString(expert.ToString())
+		builder.WriteByte('\n')
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+	return builder.String()
+}
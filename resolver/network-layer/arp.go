@@ -0,0 +1,269 @@
+package networklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	ARP_HARDWARE_ETHERNET    uint16 = 1
+	ARP_OPCODE_REQUEST       uint16 = 1
+	ARP_OPCODE_REPLY         uint16 = 2
+	ARP_OPCODE_RARP_REQUEST  uint16 = 3
+	ARP_OPCODE_RARP_REPLY    uint16 = 4
+	ARP_OPCODE_INARP_REQUEST uint16 = 8
+	ARP_OPCODE_INARP_REPLY   uint16 = 9
+	ARP_PROTOCOL_IPv4        uint16 = 0x0800
+)
+
+var ARP_OPCODE_NAME = map[uint16]string{
+	ARP_OPCODE_REQUEST:       "request",
+	ARP_OPCODE_REPLY:         "reply",
+	ARP_OPCODE_RARP_REQUEST:  "reverse request",
+	ARP_OPCODE_RARP_REPLY:    "reverse reply",
+	ARP_OPCODE_INARP_REQUEST: "inverse request",
+	ARP_OPCODE_INARP_REPLY:   "inverse reply",
+}
+
+var ARP_HARDWARE_NAME = map[uint16]string{
+	ARP_HARDWARE_ETHERNET: "Ethernet",
+	6:                     "IEEE 802",
+	15:                    "Frame Relay",
+	16:                    "ATM",
+	32:                    "InfiniBand",
+}
+
+// ARP / RARP 报文
+type ARP struct {
+	resolver.IPacket
+	raw            []byte // 原始报文，不含链路层填充
+	hardwareType   uint16 // 硬件类型
+	protocolType   uint16 // 协议类型
+	hardwareLength uint8  // 硬件地址长度
+	protocolLength uint8  // 协议地址长度
+	opcode         uint16 // 操作码
+	senderHardware []byte // 发送方硬件地址
+	senderProtocol []byte // 发送方协议地址
+	targetHardware []byte // 目标硬件地址
+	targetProtocol []byte // 目标协议地址
+}
+
+func (arp *ARP) Raw() []byte {
+	return arp.raw
+}
+
+func (arp *ARP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(arp.raw))
+}
+
+func (arp *ARP) Opcode() uint16 {
+	return arp.opcode
+}
+
+// 是否为以太网 + IPv4 的 ARP 报文
+func (arp *ARP) IsEthernetIPv4() bool {
+	return arp.hardwareType == ARP_HARDWARE_ETHERNET && arp.protocolType == ARP_PROTOCOL_IPv4 &&
+		arp.hardwareLength == 6 && arp.protocolLength == 4
+}
+
+// 发送方 MAC 地址，仅当 IsEthernetIPv4 时有效
+func (arp *ARP) SenderMac() types.Mac {
+	return arpMac(arp.senderHardware)
+}
+
+// 发送方 IPv4 地址，仅当 IsEthernetIPv4 时有效
+func (arp *ARP) SenderIP() types.IPv4 {
+	return arpIPv4(arp.senderProtocol)
+}
+
+// 目标 MAC 地址，仅当 IsEthernetIPv4 时有效
+func (arp *ARP) TargetMac() types.Mac {
+	return arpMac(arp.targetHardware)
+}
+
+// 目标 IPv4 地址，仅当 IsEthernetIPv4 时有效
+func (arp *ARP) TargetIP() types.IPv4 {
+	return arpIPv4(arp.targetProtocol)
+}
+
+// 是否为免费 ARP：发送方与目标协议地址相同
+func (arp *ARP) IsGratuitous() bool {
+	return (arp.opcode == ARP_OPCODE_REQUEST || arp.opcode == ARP_OPCODE_REPLY) &&
+		string(arp.senderProtocol) == string(arp.targetProtocol)
+}
+
+// 是否为 ARP 探测（RFC 5227）：发送方协议地址全 0
+func (arp *ARP) IsProbe() bool {
+	return arp.opcode == ARP_OPCODE_REQUEST && strings.Trim(string(arp.senderProtocol), "\x00") == ""
+}
+
+func arpMac(address []byte) types.Mac {
+	mac := types.Mac{}
+	if len(address) == 6 {
+		mac.Parse([6]byte(address))
+	}
+	return mac
+}
+
+func arpIPv4(address []byte) types.IPv4 {
+	ip := types.IPv4{}
+	if len(address) == 4 {
+		ip.Parse([4]byte(address))
+	}
+	return ip
+}
+
+// 格式化硬件地址
+func (arp *ARP) hardwareString(address []byte) string {
+	if arp.hardwareType == ARP_HARDWARE_ETHERNET && len(address) == 6 {
+		mac := arpMac(address)
+		return mac.ToNamedString()
+	}
+	return strings.ToUpper(hex.EncodeToString(address))
+}
+
+// 格式化协议地址
+func (arp *ARP) protocolString(address []byte) string {
+	if arp.protocolType == ARP_PROTOCOL_IPv4 && len(address) == 4 {
+		return arpIPv4(address).ToString()
+	}
+	return strings.ToUpper(hex.EncodeToString(address))
+}
+
+func (arp *ARP) Fields() []resolver.Field {
+	gratuitous := "0"
+	if arp.IsGratuitous() {
+		gratuitous = "1"
+	}
+	return []resolver.Field{
+		{Name: "arp.opcode", Value: strconv.Itoa(int(arp.opcode))},
+		{Name: "arp.src.hw", Value: arp.hardwareString(arp.senderHardware)},
+		{Name: "arp.src.ip", Value: arp.protocolString(arp.senderProtocol)},
+		{Name: "arp.dst.hw", Value: arp.hardwareString(arp.targetHardware)},
+		{Name: "arp.dst.ip", Value: arp.protocolString(arp.targetProtocol)},
+		{Name: "arp.gratuitous", Value: gratuitous},
+	}
+}
+
+func (arp *ARP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	if arp.opcode == ARP_OPCODE_RARP_REQUEST || arp.opcode == ARP_OPCODE_RARP_REPLY {
+		builder.WriteString("Protocol: RARP (Network)\n")
+	} else {
+		builder.WriteString("Protocol: ARP (Network)\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Hardware type: ")
+	builder.WriteString(strconv.Itoa(int(arp.hardwareType)))
+	builder.WriteString(" (")
+	if name := ARP_HARDWARE_NAME[arp.hardwareType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol type: ")
+	builder.WriteString(fmt.Sprintf("0x%04X", arp.protocolType))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Hardware size: ")
+	builder.WriteString(strconv.Itoa(int(arp.hardwareLength)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol size: ")
+	builder.WriteString(strconv.Itoa(int(arp.protocolLength)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Opcode: ")
+	builder.WriteString(strconv.Itoa(int(arp.opcode)))
+	builder.WriteString(" (")
+	if name := ARP_OPCODE_NAME[arp.opcode]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")")
+	if arp.IsProbe() {
+		builder.WriteString(" [probe]")
+	} else if arp.IsGratuitous() {
+		builder.WriteString(" [gratuitous]")
+	}
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Sender hardware address: ")
+	builder.WriteString(arp.hardwareString(arp.senderHardware))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Sender protocol address: ")
+	builder.WriteString(arp.protocolString(arp.senderProtocol))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Target hardware address: ")
+	builder.WriteString(arp.hardwareString(arp.targetHardware))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Target protocol address: ")
+	builder.WriteString(arp.protocolString(arp.targetProtocol))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(arp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 ARP / RARP 报文，链路层填充被忽略
+func ARPResolve(packet []byte) resolver.IPacket {
+	if len(packet) < 8 {
+		return nil
+	}
+
+	arp := new(ARP)
+	arp.hardwareType = utils.ExtractUint16BE(packet, 0)
+	arp.protocolType = utils.ExtractUint16BE(packet, 2)
+	arp.hardwareLength = packet[4]
+	arp.protocolLength = packet[5]
+	arp.opcode = utils.ExtractUint16BE(packet, 6)
+	hardware, protocol := int(arp.hardwareLength), int(arp.protocolLength)
+	length := 8 + 2*hardware + 2*protocol
+	if len(packet) < length {
+		return nil
+	}
+
+	offset := 8
+	arp.senderHardware = make([]byte, hardware)
+	offset += copy(arp.senderHardware, packet[offset:])
+	arp.senderProtocol = make([]byte, protocol)
+	offset += copy(arp.senderProtocol, packet[offset:])
+	arp.targetHardware = make([]byte, hardware)
+	offset += copy(arp.targetHardware, packet[offset:])
+	arp.targetProtocol = make([]byte, protocol)
+	copy(arp.targetProtocol, packet[offset:])
+	arp.raw = make([]byte, length)
+	copy(arp.raw, packet)
+
+	return arp
+}
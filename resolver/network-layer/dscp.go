@@ -0,0 +1,85 @@
+package networklayer
+
+import "strconv"
+
+// 区分服务代码点（服务类型字段的高 6 位）
+const (
+	DSCP_CS0         uint8 = 0
+	DSCP_LE          uint8 = 1
+	DSCP_CS1         uint8 = 8
+	DSCP_AF11        uint8 = 10
+	DSCP_AF12        uint8 = 12
+	DSCP_AF13        uint8 = 14
+	DSCP_CS2         uint8 = 16
+	DSCP_AF21        uint8 = 18
+	DSCP_AF22        uint8 = 20
+	DSCP_AF23        uint8 = 22
+	DSCP_CS3         uint8 = 24
+	DSCP_AF31        uint8 = 26
+	DSCP_AF32        uint8 = 28
+	DSCP_AF33        uint8 = 30
+	DSCP_CS4         uint8 = 32
+	DSCP_AF41        uint8 = 34
+	DSCP_AF42        uint8 = 36
+	DSCP_AF43        uint8 = 38
+	DSCP_CS5         uint8 = 40
+	DSCP_VOICE_ADMIT uint8 = 44
+	DSCP_EF          uint8 = 46
+	DSCP_CS6         uint8 = 48
+	DSCP_CS7         uint8 = 56
+)
+
+var DSCP_NAME = map[uint8]string{
+	DSCP_CS0:         "CS0",
+	DSCP_LE:          "LE",
+	DSCP_CS1:         "CS1",
+	DSCP_AF11:        "AF11",
+	DSCP_AF12:        "AF12",
+	DSCP_AF13:        "AF13",
+	DSCP_CS2:         "CS2",
+	DSCP_AF21:        "AF21",
+	DSCP_AF22:        "AF22",
+	DSCP_AF23:        "AF23",
+	DSCP_CS3:         "CS3",
+	DSCP_AF31:        "AF31",
+	DSCP_AF32:        "AF32",
+	DSCP_AF33:        "AF33",
+	DSCP_CS4:         "CS4",
+	DSCP_AF41:        "AF41",
+	DSCP_AF42:        "AF42",
+	DSCP_AF43:        "AF43",
+	DSCP_CS5:         "CS5",
+	DSCP_VOICE_ADMIT: "VOICE-ADMIT",
+	DSCP_EF:          "EF",
+	DSCP_CS6:         "CS6",
+	DSCP_CS7:         "CS7",
+}
+
+// 显式拥塞通知代码点（服务类型字段的低 2 位）
+const (
+	ECN_NOT_ECT uint8 = 0
+	ECN_ECT1    uint8 = 1
+	ECN_ECT0    uint8 = 2
+	ECN_CE      uint8 = 3
+)
+
+var ECN_NAME = map[uint8]string{
+	ECN_NOT_ECT: "Not-ECT",
+	ECN_ECT1:    "ECT(1)",
+	ECN_ECT0:    "ECT(0)",
+	ECN_CE:      "CE",
+}
+
+// DSCP 的可读形式，如 "46 (EF)"
+func dscpString(dscp uint8) string {
+	name := DSCP_NAME[dscp]
+	if name == "" {
+		name = "Unknown"
+	}
+	return strconv.Itoa(int(dscp)) + " (" + name + ")"
+}
+
+// ECN 的可读形式，如 "2 (ECT(0))"
+func ecnString(ecn uint8) string {
+	return strconv.Itoa(int(ecn)) + " (" + ECN_NAME[ecn] + ")"
+}
@@ -0,0 +1,304 @@
+package networklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	ICMP_TYPE_ECHO_REPLY           uint8 = 0
+	ICMP_TYPE_UNREACHABLE          uint8 = 3
+	ICMP_TYPE_SOURCE_QUENCH        uint8 = 4
+	ICMP_TYPE_REDIRECT             uint8 = 5
+	ICMP_TYPE_ECHO_REQUEST         uint8 = 8
+	ICMP_TYPE_ROUTER_ADVERTISEMENT uint8 = 9
+	ICMP_TYPE_ROUTER_SOLICITATION  uint8 = 10
+	ICMP_TYPE_TIME_EXCEEDED        uint8 = 11
+	ICMP_TYPE_PARAMETER_PROBLEM    uint8 = 12
+	ICMP_TYPE_TIMESTAMP            uint8 = 13
+	ICMP_TYPE_TIMESTAMP_REPLY      uint8 = 14
+	ICMP_TYPE_INFO_REQUEST         uint8 = 15
+	ICMP_TYPE_INFO_REPLY           uint8 = 16
+	ICMP_TYPE_MASK_REQUEST         uint8 = 17
+	ICMP_TYPE_MASK_REPLY           uint8 = 18
+)
+
+var ICMP_TYPE_NAME = map[uint8]string{
+	ICMP_TYPE_ECHO_REPLY:           "Echo Reply",
+	ICMP_TYPE_UNREACHABLE:          "Destination Unreachable",
+	ICMP_TYPE_SOURCE_QUENCH:        "Source Quench",
+	ICMP_TYPE_REDIRECT:             "Redirect",
+	ICMP_TYPE_ECHO_REQUEST:         "Echo Request",
+	ICMP_TYPE_ROUTER_ADVERTISEMENT: "Router Advertisement",
+	ICMP_TYPE_ROUTER_SOLICITATION:  "Router Solicitation",
+	ICMP_TYPE_TIME_EXCEEDED:        "Time Exceeded",
+	ICMP_TYPE_PARAMETER_PROBLEM:    "Parameter Problem",
+	ICMP_TYPE_TIMESTAMP:            "Timestamp",
+	ICMP_TYPE_TIMESTAMP_REPLY:      "Timestamp Reply",
+	ICMP_TYPE_INFO_REQUEST:         "Information Request",
+	ICMP_TYPE_INFO_REPLY:           "Information Reply",
+	ICMP_TYPE_MASK_REQUEST:         "Address Mask Request",
+	ICMP_TYPE_MASK_REPLY:           "Address Mask Reply",
+}
+
+var ICMP_CODE_NAME = map[uint8]map[uint8]string{
+	ICMP_TYPE_UNREACHABLE: {
+		0:  "Network Unreachable",
+		1:  "Host Unreachable",
+		2:  "Protocol Unreachable",
+		3:  "Port Unreachable",
+		4:  "Fragmentation Needed and DF Set",
+		5:  "Source Route Failed",
+		6:  "Destination Network Unknown",
+		7:  "Destination Host Unknown",
+		8:  "Source Host Isolated",
+		9:  "Network Administratively Prohibited",
+		10: "Host Administratively Prohibited",
+		11: "Network Unreachable for TOS",
+		12: "Host Unreachable for TOS",
+		13: "Communication Administratively Prohibited",
+		14: "Host Precedence Violation",
+		15: "Precedence Cutoff in Effect",
+	},
+	ICMP_TYPE_REDIRECT: {
+		0: "Redirect for Network",
+		1: "Redirect for Host",
+		2: "Redirect for TOS and Network",
+		3: "Redirect for TOS and Host",
+	},
+	ICMP_TYPE_TIME_EXCEEDED: {
+		0: "TTL Exceeded in Transit",
+		1: "Fragment Reassembly Time Exceeded",
+	},
+	ICMP_TYPE_PARAMETER_PROBLEM: {
+		0: "Pointer Indicates the Error",
+		1: "Missing a Required Option",
+		2: "Bad Length",
+	},
+}
+
+// 携带原始报文的差错报文类型
+var icmpErrors = map[uint8]bool{
+	ICMP_TYPE_UNREACHABLE:       true,
+	ICMP_TYPE_SOURCE_QUENCH:     true,
+	ICMP_TYPE_REDIRECT:          true,
+	ICMP_TYPE_TIME_EXCEEDED:     true,
+	ICMP_TYPE_PARAMETER_PROBLEM: true,
+}
+
+type ICMP struct {
+	resolver.IPacket
+	raw           []byte           // 原始报文
+	icmpType      uint8            // 类型
+	code          uint8            // 代码
+	checksum      uint16           // 校验和
+	checksumValid bool             // 校验和是否正确
+	rest          [4]byte          // 报文头的后 4 字节，含义随类型而定
+	body          []byte           // 报文头之后的数据
+	original      resolver.IPacket // 差错报文引用的原始报文
+}
+
+func (icmp *ICMP) Raw() []byte {
+	return icmp.raw
+}
+
+func (icmp *ICMP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(icmp.raw))
+}
+
+func (icmp *ICMP) Inner() resolver.IPacket {
+	return icmp.original
+}
+
+// 是否为回显、时间戳等带有标识符与序号的报文
+func (icmp *ICMP) isQuery() bool {
+	switch icmp.icmpType {
+	case ICMP_TYPE_ECHO_REPLY, ICMP_TYPE_ECHO_REQUEST, ICMP_TYPE_TIMESTAMP, ICMP_TYPE_TIMESTAMP_REPLY,
+		ICMP_TYPE_INFO_REQUEST, ICMP_TYPE_INFO_REPLY, ICMP_TYPE_MASK_REQUEST, ICMP_TYPE_MASK_REPLY:
+		return true
+	}
+	return false
+}
+
+func (icmp *ICMP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "icmp.type", Value: strconv.Itoa(int(icmp.icmpType))},
+		{Name: "icmp.code", Value: strconv.Itoa(int(icmp.code))},
+	}
+	if icmp.isQuery() {
+		fields = append(fields,
+			resolver.Field{Name: "icmp.ident", Value: strconv.Itoa(int(utils.ExtractUint16BE(icmp.rest[:], 0)))},
+			resolver.Field{Name: "icmp.seq", Value: strconv.Itoa(int(utils.ExtractUint16BE(icmp.rest[:], 2)))})
+	}
+	return fields
+}
+
+func (icmp *ICMP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: ICMP (Network)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Type: ")
+	builder.WriteString(strconv.Itoa(int(icmp.icmpType)))
+	builder.WriteString(" (")
+	if name := ICMP_TYPE_NAME[icmp.icmpType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Code: ")
+	builder.WriteString(strconv.Itoa(int(icmp.code)))
+	if name := ICMP_CODE_NAME[icmp.icmpType][icmp.code]; name != "" {
+		builder.WriteString(" (")
+		builder.WriteString(name)
+		builder.WriteString(")")
+	}
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Checksum: ")
+	builder.WriteString(fmt.Sprintf("0x%04X", icmp.checksum))
+	if icmp.checksumValid {
+		builder.WriteString(" (correct)\n")
+	} else {
+		builder.WriteString(" (incorrect)\n")
+	}
+
+	rest := icmp.rest[:]
+	switch {
+	case icmp.isQuery():
+		builder.Write(tabs)
+		builder.WriteString("Identifier: ")
+		builder.WriteString(fmt.Sprintf("0x%04X", utils.ExtractUint16BE(rest, 0)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Sequence number: ")
+		builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(rest, 2))))
+		builder.WriteByte('\n')
+	case icmp.icmpType == ICMP_TYPE_UNREACHABLE && icmp.code == 4:
+		builder.Write(tabs)
+		builder.WriteString("Next-hop MTU: ")
+		builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(rest, 2))))
+		builder.WriteByte('\n')
+	case icmp.icmpType == ICMP_TYPE_REDIRECT:
+		gateway := types.IPv4{}
+		gateway.Parse(icmp.rest)
+		builder.Write(tabs)
+		builder.WriteString("Gateway address: ")
+		builder.WriteString(gateway.ToString())
+		builder.WriteByte('\n')
+	case icmp.icmpType == ICMP_TYPE_PARAMETER_PROBLEM:
+		builder.Write(tabs)
+		builder.WriteString("Pointer: ")
+		builder.WriteString(strconv.Itoa(int(rest[0])))
+		builder.WriteByte('\n')
+	case icmp.icmpType == ICMP_TYPE_ROUTER_ADVERTISEMENT:
+		builder.Write(tabs)
+		builder.WriteString("Lifetime: ")
+		builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(rest, 2))))
+		builder.WriteString(" s\n")
+		// 每个条目为地址与优先级，条目长度单位 4 字节
+		size := int(rest[1]) * 4
+		if size >= 8 {
+			builder.Write(tabs)
+			builder.WriteString("Router addresses: {\n")
+			for i := 0; i < int(rest[0]) && (i+1)*size <= len(icmp.body); i++ {
+				entry := icmp.body[i*size:]
+				address := types.IPv4{}
+				address.Parse([4]byte(entry[0:4]))
+				builder.Write(tabs)
+				builder.WriteString("\t")
+				builder.WriteString(address.ToString())
+				builder.WriteString(" (preference ")
+				builder.WriteString(strconv.Itoa(int(int32(utils.ExtractUint32BE(entry, 4)))))
+				builder.WriteString(")\n")
+			}
+			builder.Write(tabs)
+			builder.WriteString("}\n")
+		}
+	}
+
+	switch {
+	case (icmp.icmpType == ICMP_TYPE_TIMESTAMP || icmp.icmpType == ICMP_TYPE_TIMESTAMP_REPLY) && len(icmp.body) >= 12:
+		for i, name := range []string{"Originate", "Receive", "Transmit"} {
+			builder.Write(tabs)
+			builder.WriteString(name)
+			builder.WriteString(" timestamp: ")
+			builder.WriteString(strconv.FormatUint(uint64(utils.ExtractUint32BE(icmp.body, i*4)), 10))
+			builder.WriteString(" ms\n")
+		}
+	case (icmp.icmpType == ICMP_TYPE_MASK_REQUEST || icmp.icmpType == ICMP_TYPE_MASK_REPLY) && len(icmp.body) >= 4:
+		mask := types.IPv4{}
+		mask.Parse([4]byte(icmp.body[0:4]))
+		builder.Write(tabs)
+		builder.WriteString("Address mask: ")
+		builder.WriteString(mask.ToString())
+		builder.WriteByte('\n')
+	case icmpErrors[icmp.icmpType]:
+		builder.Write(tabs)
+		builder.WriteString("Original datagram: {\n")
+		if icmp.original != nil {
+			builder.WriteString(icmp.original.ToReadableString(indent + 1))
+		} else {
+			builder.Write(tabs)
+			builder.WriteString("\t(NOT RESOLVED)\n")
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	case icmp.icmpType != ICMP_TYPE_ROUTER_ADVERTISEMENT && len(icmp.body) != 0:
+		builder.Write(tabs)
+		builder.WriteString("Data(HEX): ")
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(icmp.body)))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(icmp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func ICMPResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 8 {
+		return nil
+	}
+
+	icmp := new(ICMP)
+	icmp.icmpType = packet[0]
+	icmp.code = packet[1]
+	icmp.checksum = utils.ExtractUint16BE(packet, 2)
+	icmp.checksumValid = utils.InternetChecksum(packet) == 0
+	copy(icmp.rest[:], packet[4:8])
+	icmp.body = make([]byte, length-8)
+	copy(icmp.body, packet[8:])
+	if icmpErrors[icmp.icmpType] {
+		original := icmp.body
+		// RFC 4884：长度字段（单位 4 字节）非 0 时，原始报文之后为扩展结构
+		if extended := int(icmp.rest[1]) * 4; icmp.icmpType != ICMP_TYPE_REDIRECT && extended != 0 && extended <= len(original) {
+			original = original[:extended]
+		}
+		icmp.original = IPv4QuotedResolve(original)
+	}
+	icmp.raw = make([]byte, length)
+	copy(icmp.raw, packet)
+
+	return icmp
+}
@@ -0,0 +1,243 @@
+package networklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	ICMPv6_TYPE_UNREACHABLE            uint8 = 1
+	ICMPv6_TYPE_PACKET_TOO_BIG         uint8 = 2
+	ICMPv6_TYPE_TIME_EXCEEDED          uint8 = 3
+	ICMPv6_TYPE_PARAMETER_PROBLEM      uint8 = 4
+	ICMPv6_TYPE_ECHO_REQUEST           uint8 = 128
+	ICMPv6_TYPE_ECHO_REPLY             uint8 = 129
+	ICMPv6_TYPE_MLD_QUERY              uint8 = 130
+	ICMPv6_TYPE_MLD_REPORT             uint8 = 131
+	ICMPv6_TYPE_MLD_DONE               uint8 = 132
+	ICMPv6_TYPE_ROUTER_SOLICITATION    uint8 = 133
+	ICMPv6_TYPE_ROUTER_ADVERTISEMENT   uint8 = 134
+	ICMPv6_TYPE_NEIGHBOR_SOLICITATION  uint8 = 135
+	ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT uint8 = 136
+	ICMPv6_TYPE_REDIRECT               uint8 = 137
+	ICMPv6_TYPE_MLDv2_REPORT           uint8 = 143
+)
+
+var ICMPv6_TYPE_NAME = map[uint8]string{
+	ICMPv6_TYPE_UNREACHABLE:            "Destination Unreachable",
+	ICMPv6_TYPE_PACKET_TOO_BIG:         "Packet Too Big",
+	ICMPv6_TYPE_TIME_EXCEEDED:          "Time Exceeded",
+	ICMPv6_TYPE_PARAMETER_PROBLEM:      "Parameter Problem",
+	ICMPv6_TYPE_ECHO_REQUEST:           "Echo Request",
+	ICMPv6_TYPE_ECHO_REPLY:             "Echo Reply",
+	ICMPv6_TYPE_MLD_QUERY:              "Multicast Listener Query",
+	ICMPv6_TYPE_MLD_REPORT:             "Multicast Listener Report",
+	ICMPv6_TYPE_MLD_DONE:               "Multicast Listener Done",
+	ICMPv6_TYPE_ROUTER_SOLICITATION:    "Router Solicitation",
+	ICMPv6_TYPE_ROUTER_ADVERTISEMENT:   "Router Advertisement",
+	ICMPv6_TYPE_NEIGHBOR_SOLICITATION:  "Neighbor Solicitation",
+	ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT: "Neighbor Advertisement",
+	ICMPv6_TYPE_REDIRECT:               "Redirect",
+	ICMPv6_TYPE_MLDv2_REPORT:           "Version 2 Multicast Listener Report",
+}
+
+var ICMPv6_CODE_NAME = map[uint8]map[uint8]string{
+	ICMPv6_TYPE_UNREACHABLE: {
+		0: "No Route to Destination",
+		1: "Communication Administratively Prohibited",
+		2: "Beyond Scope of Source Address",
+		3: "Address Unreachable",
+		4: "Port Unreachable",
+		5: "Source Address Failed Ingress/Egress Policy",
+		6: "Reject Route to Destination",
+		7: "Error in Source Routing Header",
+	},
+	ICMPv6_TYPE_TIME_EXCEEDED: {
+		0: "Hop Limit Exceeded in Transit",
+		1: "Fragment Reassembly Time Exceeded",
+	},
+	ICMPv6_TYPE_PARAMETER_PROBLEM: {
+		0: "Erroneous Header Field",
+		1: "Unrecognized Next Header Type",
+		2: "Unrecognized IPv6 Option",
+		3: "IPv6 First Fragment Has Incomplete Header Chain",
+	},
+}
+
+type ICMPv6 struct {
+	resolver.IPacket
+	raw      []byte           // 原始报文
+	icmpType uint8            // 类型
+	code     uint8            // 代码
+	checksum uint16           // 校验和（需要 IPv6 伪首部才能校验）
+	rest     [4]byte          // 报文头的后 4 字节，含义随类型而定
+	body     []byte           // 报文头之后的数据
+	original resolver.IPacket // 差错报文引用的原始报文
+	ndp      *NDP             // 邻居发现报文
+	mld      *MLD             // 组播侦听发现报文
+}
+
+func (icmp *ICMPv6) Raw() []byte {
+	return icmp.raw
+}
+
+func (icmp *ICMPv6) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(icmp.raw))
+}
+
+func (icmp *ICMPv6) Inner() resolver.IPacket {
+	return icmp.original
+}
+
+// 是否为差错报文
+func (icmp *ICMPv6) IsError() bool {
+	return icmp.icmpType < 128
+}
+
+func (icmp *ICMPv6) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "icmpv6.type", Value: strconv.Itoa(int(icmp.icmpType))},
+		{Name: "icmpv6.code", Value: strconv.Itoa(int(icmp.code))},
+	}
+	switch {
+	case icmp.icmpType == ICMPv6_TYPE_ECHO_REQUEST || icmp.icmpType == ICMPv6_TYPE_ECHO_REPLY:
+		fields = append(fields,
+			resolver.Field{Name: "icmpv6.echo.ident", Value: strconv.Itoa(int(utils.ExtractUint16BE(icmp.rest[:], 0)))},
+			resolver.Field{Name: "icmpv6.echo.seq", Value: strconv.Itoa(int(utils.ExtractUint16BE(icmp.rest[:], 2)))})
+	case icmp.ndp != nil:
+		fields = append(fields, icmp.ndp.fields()...)
+	case icmp.mld != nil:
+		fields = append(fields, icmp.mld.fields()...)
+	}
+	return fields
+}
+
+func (icmp *ICMPv6) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: ICMPv6 (Network)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Type: ")
+	builder.WriteString(strconv.Itoa(int(icmp.icmpType)))
+	builder.WriteString(" (")
+	if name := ICMPv6_TYPE_NAME[icmp.icmpType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Code: ")
+	builder.WriteString(strconv.Itoa(int(icmp.code)))
+	if name := ICMPv6_CODE_NAME[icmp.icmpType][icmp.code]; name != "" {
+		builder.WriteString(" (")
+		builder.WriteString(name)
+		builder.WriteString(")")
+	}
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Checksum: ")
+	builder.WriteString(fmt.Sprintf("0x%04X", icmp.checksum))
+	builder.WriteByte('\n')
+
+	switch {
+	case icmp.icmpType == ICMPv6_TYPE_ECHO_REQUEST || icmp.icmpType == ICMPv6_TYPE_ECHO_REPLY:
+		builder.Write(tabs)
+		builder.WriteString("Identifier: ")
+		builder.WriteString(fmt.Sprintf("0x%04X", utils.ExtractUint16BE(icmp.rest[:], 0)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Sequence number: ")
+		builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(icmp.rest[:], 2))))
+		builder.WriteByte('\n')
+
+		if len(icmp.body) != 0 {
+			builder.Write(tabs)
+			builder.WriteString("Data(HEX): ")
+			builder.WriteString(strings.ToUpper(hex.EncodeToString(icmp.body)))
+			builder.WriteByte('\n')
+		}
+	case icmp.IsError():
+		switch icmp.icmpType {
+		case ICMPv6_TYPE_PACKET_TOO_BIG:
+			builder.Write(tabs)
+			builder.WriteString("MTU: ")
+			builder.WriteString(strconv.FormatUint(uint64(utils.ExtractUint32BE(icmp.rest[:], 0)), 10))
+			builder.WriteByte('\n')
+		case ICMPv6_TYPE_PARAMETER_PROBLEM:
+			builder.Write(tabs)
+			builder.WriteString("Pointer: ")
+			builder.WriteString(strconv.FormatUint(uint64(utils.ExtractUint32BE(icmp.rest[:], 0)), 10))
+			builder.WriteByte('\n')
+		}
+
+		builder.Write(tabs)
+		builder.WriteString("Original datagram: {\n")
+		if icmp.original != nil {
+			builder.WriteString(icmp.original.ToReadableString(indent + 1))
+		} else {
+			builder.Write(tabs)
+			builder.WriteString("\t(NOT RESOLVED)\n")
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	case icmp.ndp != nil:
+		builder.WriteString(icmp.ndp.ToReadableString(indent))
+	case icmp.mld != nil:
+		builder.WriteString(icmp.mld.ToReadableString(indent))
+	case len(icmp.body) != 0 || icmp.rest != [4]byte{}:
+		builder.Write(tabs)
+		builder.WriteString("Data(HEX): ")
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(icmp.rest[:])))
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(icmp.body)))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(icmp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func ICMPv6Resolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 8 {
+		return nil
+	}
+
+	icmp := new(ICMPv6)
+	icmp.icmpType = packet[0]
+	icmp.code = packet[1]
+	icmp.checksum = utils.ExtractUint16BE(packet, 2)
+	copy(icmp.rest[:], packet[4:8])
+	icmp.body = make([]byte, length-8)
+	copy(icmp.body, packet[8:])
+	switch icmp.icmpType {
+	case ICMPv6_TYPE_UNREACHABLE, ICMPv6_TYPE_PACKET_TOO_BIG, ICMPv6_TYPE_TIME_EXCEEDED, ICMPv6_TYPE_PARAMETER_PROBLEM:
+		icmp.original = IPv6QuotedResolve(icmp.body)
+	case ICMPv6_TYPE_ROUTER_SOLICITATION, ICMPv6_TYPE_ROUTER_ADVERTISEMENT, ICMPv6_TYPE_NEIGHBOR_SOLICITATION,
+		ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT, ICMPv6_TYPE_REDIRECT:
+		icmp.ndp = NDPResolve(icmp.icmpType, packet)
+	case ICMPv6_TYPE_MLD_QUERY, ICMPv6_TYPE_MLD_REPORT, ICMPv6_TYPE_MLD_DONE, ICMPv6_TYPE_MLDv2_REPORT:
+		icmp.mld = MLDResolve(icmp.icmpType, packet)
+	}
+	icmp.raw = make([]byte, length)
+	copy(icmp.raw, packet)
+
+	return icmp
+}
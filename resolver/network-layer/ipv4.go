@@ -5,22 +5,57 @@ import (
 	"fmt"
 	"packet-inspector/resolver"
 	transportlayer "packet-inspector/resolver/transport-layer"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 const (
-	IPv4_PROTOCOL_TCP uint8 = 0x6
-	IPv4_PROTOCOL_UDP uint8 = 0x11
+	IPv4_PROTOCOL_ICMP   uint8 = 0x1
+	IPv4_PROTOCOL_IPIP   uint8 = 0x4 // IPv4-in-IP
+	IPv4_PROTOCOL_TCP    uint8 = 0x6
+	IPv4_PROTOCOL_UDP    uint8 = 0x11
+	IPv4_PROTOCOL_IPv6   uint8 = 0x29 // IPv6-in-IP
+	IPv4_PROTOCOL_GRE    uint8 = 0x2f
+	IPv4_PROTOCOL_ICMPv6 uint8 = 0x3a
+	IPv4_PROTOCOL_SCTP   uint8 = 0x84
 )
 
 var IPv4_PROTOCOL_NAME = map[uint8]string{
-	IPv4_PROTOCOL_TCP: "TCP",
-	IPv4_PROTOCOL_UDP: "UDP",
+	IPv4_PROTOCOL_ICMP:   "ICMP",
+	IPv4_PROTOCOL_IPIP:   "IPv4",
+	IPv4_PROTOCOL_TCP:    "TCP",
+	IPv4_PROTOCOL_UDP:    "UDP",
+	IPv4_PROTOCOL_IPv6:   "IPv6",
+	IPv4_PROTOCOL_GRE:    "GRE",
+	IPv4_PROTOCOL_ICMPv6: "ICMPv6",
+	IPv4_PROTOCOL_SCTP:   "SCTP",
 }
 
+// 按 IP 协议号解析上层协议，先查找网络层协议（如 ICMP），再查找传输层协议
+func ProtocolResolve(protocol uint8, payload []byte) resolver.IPacket {
+	name := IPv4_PROTOCOL_NAME[protocol]
+	if resolve := Resolvers[name]; resolve != nil {
+		return resolve(payload)
+	}
+	if resolve := transportlayer.Resolvers[name]; resolve != nil {
+		return resolve(payload)
+	}
+	return nil
+}
+
+// 标志字段（3 bit）
+const (
+	IPv4_FLAG_RESERVED       uint8 = 0x4 // 保留位，必须为 0
+	IPv4_FLAG_DONT_FRAGMENT  uint8 = 0x2
+	IPv4_FLAG_MORE_FRAGMENTS uint8 = 0x1
+)
+
 type IPv4 struct {
 	resolver.IPacket
+	resolver.ExpertInfo
 	raw            []byte           // 原始数据
 	version        uint8            // 版本，应当为 0b0100（4 bit）
 	headerLength   uint8            // 报文头长度，单位 4 字节（4 bit）
@@ -32,9 +67,11 @@ type IPv4 struct {
 	liveTime       uint8            // 可经过的路由数
 	innerProtocol  uint8            // 上层协议类型
 	checksum       uint16           // 报文头校验和
-	source         [4]byte          // 源 IP地址
-	destination    [4]byte          // 目的 IP 地址
-	options        []byte           // 选项字段
+	source         types.IPv4       // 源 IP 地址
+	destination    types.IPv4       // 目的 IP 地址
+	options        []IPv4Option     // 选项字段
+	truncated      bool             // 数据是否被截断（抓包长度不足，或 ICMP 差错报文中引用的原始报文）
+	quoted         bool             // 是否为 ICMP 差错报文中引用的原始报文
 	data           resolver.IPacket // 上层协议数据
 }
 
@@ -46,6 +83,91 @@ func (ipv4 *IPv4) Hex() string {
 	return strings.ToUpper(hex.EncodeToString(ipv4.raw))
 }
 
+// 源 IP 地址
+func (ipv4 *IPv4) Source() types.IPv4 {
+	return ipv4.source
+}
+
+// 目的 IP 地址
+func (ipv4 *IPv4) Destination() types.IPv4 {
+	return ipv4.destination
+}
+
+// 区分服务代码点
+func (ipv4 *IPv4) DSCP() uint8 {
+	return ipv4.serviceType >> 2
+}
+
+// 显式拥塞通知代码点
+func (ipv4 *IPv4) ECN() uint8 {
+	return ipv4.serviceType & 0x3
+}
+
+func (ipv4 *IPv4) DontFragment() bool {
+	return ipv4.flags&IPv4_FLAG_DONT_FRAGMENT != 0
+}
+
+func (ipv4 *IPv4) MoreFragments() bool {
+	return ipv4.flags&IPv4_FLAG_MORE_FRAGMENTS != 0
+}
+
+// 以字节为单位的片偏移
+func (ipv4 *IPv4) FragmentOffset() int {
+	return int(ipv4.fragment) * 8
+}
+
+func (ipv4 *IPv4) Options() []IPv4Option {
+	return ipv4.options
+}
+
+func (ipv4 *IPv4) Quoted() bool {
+	return ipv4.quoted
+}
+
+func (ipv4 *IPv4) Inner() resolver.IPacket {
+	return ipv4.data
+}
+
+func (ipv4 *IPv4) Tunnel() string {
+	return ipTunnel("IPv4", ipv4.innerProtocol, ipv4.data)
+}
+
+// 载荷为 IP 报文时的隧道名称，如 "IPv6-in-IPv4"，否则为空
+func ipTunnel(outer string, protocol uint8, data resolver.IPacket) string {
+	if data == nil || (protocol != IPv4_PROTOCOL_IPIP && protocol != IPv4_PROTOCOL_IPv6) {
+		return ""
+	}
+	return IPv4_PROTOCOL_NAME[protocol] + "-in-" + outer
+}
+
+func (ipv4 *IPv4) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "ip.src", Value: ipv4.source.ToString()},
+		{Name: "ip.dst", Value: ipv4.destination.ToString()},
+		{Name: "ip.proto", Value: strconv.Itoa(int(ipv4.innerProtocol))},
+		{Name: "ip.id", Value: fmt.Sprintf("0x%04X", ipv4.identification)},
+		{Name: "ip.ttl", Value: strconv.Itoa(int(ipv4.liveTime))},
+		{Name: "ip.dsfield.dscp", Value: strconv.Itoa(int(ipv4.DSCP()))},
+		{Name: "ip.dsfield.ecn", Value: strconv.Itoa(int(ipv4.ECN()))},
+		{Name: "ip.flags.df", Value: boolString(ipv4.DontFragment())},
+		{Name: "ip.flags.mf", Value: boolString(ipv4.MoreFragments())},
+		{Name: "ip.frag_offset", Value: strconv.Itoa(ipv4.FragmentOffset())},
+	}
+	for _, option := range ipv4.options {
+		if option.optionType != IPv4_OPTION_NOP && option.optionType != IPv4_OPTION_EOL {
+			fields = append(fields, resolver.Field{Name: "ip.opt.type", Value: strconv.Itoa(int(option.optionType))})
+		}
+	}
+	return fields
+}
+
+func boolString(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
 func (ipv4 *IPv4) ToReadableString(indent int) string {
 	builder := new(strings.Builder)
 	tabs := make([]byte, indent)
@@ -71,9 +193,24 @@ func (ipv4 *IPv4) ToReadableString(indent int) string {
 	builder.WriteString(fmt.Sprintf("0x%02X", ipv4.serviceType))
 	builder.WriteByte('\n')
 
+	builder.Write(tabs)
+	builder.WriteString("DSCP: ")
+	builder.WriteString(dscpString(ipv4.DSCP()))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("ECN: ")
+	builder.WriteString(ecnString(ipv4.ECN()))
+	builder.WriteByte('\n')
+
 	builder.Write(tabs)
 	builder.WriteString("Total length: ")
 	builder.WriteString(strconv.Itoa(int(ipv4.length)))
+	if ipv4.truncated {
+		builder.WriteString(" (truncated to ")
+		builder.WriteString(strconv.Itoa(len(ipv4.raw)))
+		builder.WriteString(")")
+	}
 	builder.WriteByte('\n')
 
 	builder.Write(tabs)
@@ -84,12 +221,29 @@ func (ipv4 *IPv4) ToReadableString(indent int) string {
 	builder.Write(tabs)
 	builder.WriteString("Flags: ")
 	builder.WriteString(fmt.Sprintf("0b%03b", ipv4.flags))
+	flags := []string{}
+	if ipv4.flags&IPv4_FLAG_RESERVED != 0 {
+		flags = append(flags, "Reserved")
+	}
+	if ipv4.DontFragment() {
+		flags = append(flags, "DF")
+	}
+	if ipv4.MoreFragments() {
+		flags = append(flags, "MF")
+	}
+	if len(flags) != 0 {
+		builder.WriteString(" (")
+		builder.WriteString(strings.Join(flags, ", "))
+		builder.WriteString(")")
+	}
 	builder.WriteByte('\n')
 
 	builder.Write(tabs)
 	builder.WriteString("Fragment offset: ")
 	builder.WriteString(fmt.Sprintf("0x%04X", ipv4.fragment))
-	builder.WriteByte('\n')
+	builder.WriteString(" (")
+	builder.WriteString(strconv.Itoa(ipv4.FragmentOffset()))
+	builder.WriteString(" bytes)\n")
 
 	builder.Write(tabs)
 	builder.WriteString("Live time: ")
@@ -115,34 +269,41 @@ func (ipv4 *IPv4) ToReadableString(indent int) string {
 
 	builder.Write(tabs)
 	builder.WriteString("Source address: ")
-	builder.WriteString(strconv.Itoa(int(ipv4.source[0])))
-	builder.WriteByte('.')
-	builder.WriteString(strconv.Itoa(int(ipv4.source[1])))
-	builder.WriteByte('.')
-	builder.WriteString(strconv.Itoa(int(ipv4.source[2])))
-	builder.WriteByte('.')
-	builder.WriteString(strconv.Itoa(int(ipv4.source[3])))
-	builder.WriteByte('\n')
+	builder.WriteString(ipv4.source.ToString())
+	builder.WriteString(" (")
+	builder.WriteString(ipv4.source.Classify())
+	builder.WriteString(")\n")
 
 	builder.Write(tabs)
 	builder.WriteString("Destination address: ")
-	builder.WriteString(strconv.Itoa(int(ipv4.source[0])))
-	builder.WriteByte('.')
-	builder.WriteString(strconv.Itoa(int(ipv4.source[1])))
-	builder.WriteByte('.')
-	builder.WriteString(strconv.Itoa(int(ipv4.source[2])))
-	builder.WriteByte('.')
-	builder.WriteString(strconv.Itoa(int(ipv4.source[3])))
-	builder.WriteByte('\n')
+	builder.WriteString(ipv4.destination.ToString())
+	builder.WriteString(" (")
+	builder.WriteString(ipv4.destination.Classify())
+	builder.WriteString(")\n")
 
 	builder.Write(tabs)
-	builder.WriteString("Options(HEX): ")
-	if ipv4.options == nil {
-		builder.WriteString("(No options)")
+	if len(ipv4.options) != 0 {
+		builder.WriteString("Options: {\n")
+		for _, option := range ipv4.options {
+			builder.Write(tabs)
+			builder.WriteString(fmt.Sprintf("\t0x%02X (", option.optionType))
+			if name := IPv4_OPTION_NAME[option.optionType]; name != "" {
+				builder.WriteString(name)
+			} else {
+				builder.WriteString("Unknown")
+			}
+			builder.WriteString(")")
+			if option.optionType != IPv4_OPTION_NOP && option.optionType != IPv4_OPTION_EOL {
+				builder.WriteString(": ")
+				builder.WriteString(option.ToString())
+			}
+			builder.WriteByte('\n')
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
 	} else {
-		builder.WriteString(strings.ToUpper(hex.EncodeToString(ipv4.options)))
+		builder.WriteString("Options: (No options)\n")
 	}
-	builder.WriteByte('\n')
 
 	builder.Write(tabs)
 	builder.WriteString("Data: {\n")
@@ -164,6 +325,21 @@ func (ipv4 *IPv4) ToReadableString(indent int) string {
 }
 
 func IPv4Resolve(packet []byte) resolver.IPacket {
+	if ipv4 := ipv4Resolve(packet, false); ipv4 != nil {
+		return ipv4
+	}
+	return nil
+}
+
+// 解析 ICMP 差错报文中引用的原始 IPv4 报文，允许数据被截断
+func IPv4QuotedResolve(packet []byte) resolver.IPacket {
+	if ipv4 := ipv4Resolve(packet, true); ipv4 != nil {
+		return ipv4
+	}
+	return nil
+}
+
+func ipv4Resolve(packet []byte, quoted bool) *IPv4 {
 	ipv4 := new(IPv4)
 	length := len(packet)
 	if length < 20 || length > 65535 {
@@ -180,27 +356,59 @@ func IPv4Resolve(packet []byte) resolver.IPacket {
 	}
 	ipv4.serviceType = packet[1]
 	ipv4.length = uint16(packet[2])<<8 | uint16(packet[3])
-	if uint16(length) != ipv4.length {
-		println(length, ipv4.length)
+	// 总长度之后的数据（如以太网填充）不属于该报文
+	if length > int(ipv4.length) {
+		length = int(ipv4.length)
+		packet = packet[:length]
+	}
+	if length < int(ipv4.headerLength)*4 {
 		return nil
 	}
+	ipv4.quoted = quoted
+	ipv4.truncated = length < int(ipv4.length)
+	if ipv4.truncated && !quoted {
+		ipv4.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "IPv4", "Total length exceeds captured data")
+	}
 	ipv4.identification = uint16(packet[4])<<8 | uint16(packet[5])
 	ipv4.flags = (packet[6] & 0xE0) >> 5
 	ipv4.fragment = (uint16(packet[6])&0x1F)<<8 | uint16(packet[7])
 	ipv4.liveTime = packet[8]
 	ipv4.innerProtocol = packet[9]
 	ipv4.checksum = uint16(packet[10])<<8 | uint16(packet[11])
-	copy(ipv4.source[:], packet[12:16])
-	copy(ipv4.destination[:], packet[16:20])
+	ipv4.source.Parse([4]byte(packet[12:16]))
+	ipv4.destination.Parse([4]byte(packet[16:20]))
+	// 校验和为 0 时通常是由网卡计算的发出报文，不做检查
+	if ipv4.checksum != 0 && utils.InternetChecksum(packet[:ipv4.headerLength*4]) != 0 {
+		ipv4.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "IPv4", "Bad header checksum")
+	}
+	if ipv4.source.IsMulticast() || ipv4.source.IsBroadcast() {
+		ipv4.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_SECURITY, "IPv4", "Source address is multicast or broadcast")
+	}
 	if ipv4.headerLength > 5 {
-		ipv4.options = make([]byte, ipv4.headerLength*4-20)
-		copy(ipv4.options, packet[20:ipv4.headerLength*4])
+		ipv4.options = ParseIPv4Options(slices.Clone(packet[20 : ipv4.headerLength*4]))
 	} else {
 		ipv4.options = nil
 	}
-	resolve := transportlayer.Resolvers[IPv4_PROTOCOL_NAME[ipv4.innerProtocol]]
-	if resolve != nil {
-		ipv4.data = resolve(packet[uint16(ipv4.headerLength)*4 : ipv4.length])
+	for _, option := range ipv4.options {
+		switch {
+		case option.malformed:
+			ipv4.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_MALFORMED, "IPv4", "Malformed option")
+		case option.optionType == IPv4_OPTION_LOOSE_SOURCE_ROUTE || option.optionType == IPv4_OPTION_STRICT_SOURCE_ROUTE:
+			ipv4.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_SECURITY, "IPv4", "Source route option")
+		}
+	}
+	if ipv4.flags&IPv4_FLAG_RESERVED != 0 {
+		ipv4.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_PROTOCOL, "IPv4", "Reserved flag set")
+	}
+	// 分片报文的数据不完整，由分片重组器重组后再交给上层协议解析
+	if ipv4.MoreFragments() || ipv4.fragment != 0 {
+		ipv4.raw = make([]byte, length)
+		copy(ipv4.raw, packet)
+		return ipv4
+	}
+	ipv4.data = ProtocolResolve(ipv4.innerProtocol, packet[int(ipv4.headerLength)*4:length])
+	if ipv4.data == nil && length > int(ipv4.headerLength)*4 && IPv4_PROTOCOL_NAME[ipv4.innerProtocol] != "" {
+		ipv4.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_MALFORMED, "IPv4", "Payload of a known protocol could not be resolved")
 	}
 	ipv4.raw = make([]byte, length)
 	copy(ipv4.raw, packet)
@@ -0,0 +1,258 @@
+package networklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// IPv4 选项类型（含复制位与类别）
+const (
+	IPv4_OPTION_EOL                 uint8 = 0x00
+	IPv4_OPTION_NOP                 uint8 = 0x01
+	IPv4_OPTION_RECORD_ROUTE        uint8 = 0x07
+	IPv4_OPTION_TIMESTAMP           uint8 = 0x44
+	IPv4_OPTION_SECURITY            uint8 = 0x82
+	IPv4_OPTION_LOOSE_SOURCE_ROUTE  uint8 = 0x83
+	IPv4_OPTION_COMMERCIAL_SECURITY uint8 = 0x86
+	IPv4_OPTION_STREAM_ID           uint8 = 0x88
+	IPv4_OPTION_STRICT_SOURCE_ROUTE uint8 = 0x89
+	IPv4_OPTION_ROUTER_ALERT        uint8 = 0x94
+)
+
+var IPv4_OPTION_NAME = map[uint8]string{
+	IPv4_OPTION_EOL:                 "End of Option List",
+	IPv4_OPTION_NOP:                 "No-Operation",
+	IPv4_OPTION_RECORD_ROUTE:        "Record Route",
+	IPv4_OPTION_TIMESTAMP:           "Timestamp",
+	IPv4_OPTION_SECURITY:            "Security",
+	IPv4_OPTION_LOOSE_SOURCE_ROUTE:  "Loose Source Route",
+	IPv4_OPTION_COMMERCIAL_SECURITY: "Commercial Security",
+	IPv4_OPTION_STREAM_ID:           "Stream ID",
+	IPv4_OPTION_STRICT_SOURCE_ROUTE: "Strict Source Route",
+	IPv4_OPTION_ROUTER_ALERT:        "Router Alert",
+}
+
+// 时间戳选项的标志
+const (
+	IPv4_TIMESTAMP_ONLY         uint8 = 0 // 只记录时间戳
+	IPv4_TIMESTAMP_ADDRESS      uint8 = 1 // 记录地址与时间戳
+	IPv4_TIMESTAMP_PRESPECIFIED uint8 = 3 // 只由预先指定的地址记录时间戳
+)
+
+var IPv4_TIMESTAMP_FLAG_NAME = map[uint8]string{
+	IPv4_TIMESTAMP_ONLY:         "timestamps only",
+	IPv4_TIMESTAMP_ADDRESS:      "address and timestamp",
+	IPv4_TIMESTAMP_PRESPECIFIED: "prespecified addresses",
+}
+
+// RFC 1108 安全选项的密级
+var IPv4_SECURITY_LEVEL_NAME = map[uint8]string{
+	0x01: "Reserved 4",
+	0x3D: "Top Secret",
+	0x5A: "Secret",
+	0x96: "Confidential",
+	0x66: "Reserved 3",
+	0xCC: "Reserved 2",
+	0xAB: "Unclassified",
+	0xF1: "Reserved 1",
+}
+
+// RFC 791 安全选项（11 字节）的密级
+var IPv4_SECURITY_791_NAME = map[uint16]string{
+	0x0000: "Unclassified",
+	0xF135: "Confidential",
+	0x789A: "EFTO",
+	0xBC4D: "MMMM",
+	0x5E26: "PROG",
+	0xAF13: "Restricted",
+	0xD788: "Secret",
+	0x6BC5: "Top Secret",
+}
+
+// IPv4 报文头中的选项
+type IPv4Option struct {
+	optionType uint8
+	length     uint8  // 选项总长度，EOL 与 NOP 为 1
+	data       []byte // 类型与长度之后的数据
+	malformed  bool   // 长度字段有误，data 为剩余的全部数据
+}
+
+func (option *IPv4Option) Type() uint8 {
+	return option.optionType
+}
+
+func (option *IPv4Option) Malformed() bool {
+	return option.malformed
+}
+
+// 选项的可读形式，不含类型
+func (option *IPv4Option) ToString() string {
+	if option.malformed {
+		return "malformed, length " + strconv.Itoa(int(option.length)) + ", " + strings.ToUpper(hex.EncodeToString(option.data))
+	}
+	switch option.optionType {
+	case IPv4_OPTION_RECORD_ROUTE, IPv4_OPTION_LOOSE_SOURCE_ROUTE, IPv4_OPTION_STRICT_SOURCE_ROUTE:
+		return option.routeString()
+	case IPv4_OPTION_TIMESTAMP:
+		return option.timestampString()
+	case IPv4_OPTION_ROUTER_ALERT:
+		if len(option.data) == 2 {
+			value := utils.ExtractUint16BE(option.data, 0)
+			if value == 0 {
+				return "0 (router shall examine packet)"
+			}
+			return strconv.Itoa(int(value))
+		}
+	case IPv4_OPTION_SECURITY:
+		return option.securityString()
+	case IPv4_OPTION_STREAM_ID:
+		if len(option.data) == 2 {
+			return strconv.Itoa(int(utils.ExtractUint16BE(option.data, 0)))
+		}
+	}
+	return strings.ToUpper(hex.EncodeToString(option.data))
+}
+
+// 记录路由与源路由选项：指针之前为已经过（记录）的地址，指针处为下一个地址
+func (option *IPv4Option) routeString() string {
+	if len(option.data) < 1 {
+		return "(empty)"
+	}
+	pointer := int(option.data[0])
+	builder := new(strings.Builder)
+	builder.WriteString("pointer ")
+	builder.WriteString(strconv.Itoa(pointer))
+	// 指针从选项开头算起，第一个地址位于第 4 字节
+	for offset := 1; offset+4 <= len(option.data); offset += 4 {
+		address := types.IPv4{}
+		address.Parse([4]byte(option.data[offset : offset+4]))
+		if offset == 1 {
+			builder.WriteString(": ")
+		} else {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(address.ToString())
+		if offset+3 == pointer {
+			builder.WriteString(" (next)")
+		}
+	}
+	if pointer > int(option.length) {
+		builder.WriteString(" (full)")
+	}
+	return builder.String()
+}
+
+// 时间戳选项：只列出指针之前已记录的项
+func (option *IPv4Option) timestampString() string {
+	if len(option.data) < 2 {
+		return strings.ToUpper(hex.EncodeToString(option.data))
+	}
+	pointer := int(option.data[0])
+	overflow := option.data[1] >> 4
+	flag := option.data[1] & 0x0F
+	builder := new(strings.Builder)
+	builder.WriteString(fmt.Sprintf("pointer %d, overflow %d, flag %d (", pointer, overflow, flag))
+	if name := IPv4_TIMESTAMP_FLAG_NAME[flag]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")")
+
+	size := 4
+	if flag == IPv4_TIMESTAMP_ADDRESS || flag == IPv4_TIMESTAMP_PRESPECIFIED {
+		size = 8
+	}
+	entries := []string{}
+	free := 0
+	// 第一项位于选项的第 5 字节（从 1 开始计数）
+	for offset := 2; offset+size <= len(option.data); offset += size {
+		if offset+3 >= pointer {
+			free++
+			continue
+		}
+		entry := ""
+		if size == 8 {
+			address := types.IPv4{}
+			address.Parse([4]byte(option.data[offset : offset+4]))
+			entry = address.ToString() + " at "
+		}
+		entry += timestampString(utils.ExtractUint32BE(option.data, offset+size-4))
+		entries = append(entries, entry)
+	}
+	if len(entries) != 0 {
+		builder.WriteString(": ")
+		builder.WriteString(strings.Join(entries, ", "))
+	}
+	if free != 0 {
+		builder.WriteString(fmt.Sprintf(", %d free", free))
+	}
+	return builder.String()
+}
+
+// 时间戳为从世界时零点起的毫秒数，最高位置 1 表示非标准时间
+func timestampString(value uint32) string {
+	if value&0x80000000 != 0 {
+		return fmt.Sprintf("0x%08X (non-standard)", value)
+	}
+	return strconv.FormatUint(uint64(value), 10) + " ms"
+}
+
+// 安全选项，长度 11 的为 RFC 791 格式，否则为 RFC 1108 格式
+func (option *IPv4Option) securityString() string {
+	if option.length == 11 {
+		level := utils.ExtractUint16BE(option.data, 0)
+		name := IPv4_SECURITY_791_NAME[level]
+		if name == "" {
+			name = "Unknown"
+		}
+		return fmt.Sprintf("0x%04X (%s), compartments 0x%04X, handling restrictions 0x%04X, TCC 0x%06X",
+			level, name, utils.ExtractUint16BE(option.data, 2), utils.ExtractUint16BE(option.data, 4),
+			uint32(option.data[6])<<16|uint32(utils.ExtractUint16BE(option.data, 7)))
+	}
+	if len(option.data) < 1 {
+		return "(empty)"
+	}
+	name := IPv4_SECURITY_LEVEL_NAME[option.data[0]]
+	if name == "" {
+		name = "Unknown"
+	}
+	result := fmt.Sprintf("level 0x%02X (%s)", option.data[0], name)
+	if len(option.data) > 1 {
+		result += ", protection authority " + strings.ToUpper(hex.EncodeToString(option.data[1:]))
+	}
+	return result
+}
+
+// 解析 IPv4 选项，遇到 EOL 后停止；长度有误的选项吸收剩余的全部数据
+func ParseIPv4Options(options []byte) []IPv4Option {
+	result := []IPv4Option{}
+	for offset := 0; offset < len(options); {
+		optionType := options[offset]
+		if optionType == IPv4_OPTION_EOL || optionType == IPv4_OPTION_NOP {
+			result = append(result, IPv4Option{optionType: optionType, length: 1})
+			offset++
+			if optionType == IPv4_OPTION_EOL {
+				break
+			}
+			continue
+		}
+		if offset+1 >= len(options) || options[offset+1] < 2 || offset+int(options[offset+1]) > len(options) {
+			option := IPv4Option{optionType: optionType, malformed: true}
+			if offset+1 < len(options) {
+				option.length = options[offset+1]
+				option.data = options[offset+2:]
+			}
+			result = append(result, option)
+			break
+		}
+		length := int(options[offset+1])
+		result = append(result, IPv4Option{optionType: optionType, length: uint8(length), data: options[offset+2 : offset+length]})
+		offset += length
+	}
+	return result
+}
@@ -4,7 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"packet-inspector/resolver"
-	transportlayer "packet-inspector/resolver/transport-layer"
+	"packet-inspector/types"
 	"strconv"
 	"strings"
 )
@@ -14,12 +14,16 @@ const (
 	IPv6_NEXT_HEADER_TCP      uint8 = 0x6
 	IPv6_NEXT_HEADER_UDP      uint8 = 0x11
 	IPv6_NEXT_HEADER_ROUTING  uint8 = 0x2b // 路由扩展头
-	IPv6_NEXT_HEADER_FRAGMENT uint8 = 0x2c
+	IPv6_NEXT_HEADER_FRAGMENT uint8 = 0x2c // 分片扩展头
+	IPv6_NEXT_HEADER_ESP      uint8 = 0x32 // 封装安全载荷，之后的内容已加密
+	IPv6_NEXT_HEADER_AH       uint8 = 0x33 // 认证扩展头
+	IPv6_NEXT_HEADER_NONE     uint8 = 0x3b // 无下一个报文头
 	IPv6_NEXT_HEADER_DO       uint8 = 0x3c // 目的选项扩展头
 )
 
 type IPv6 struct {
 	resolver.IPacket
+	resolver.ExpertInfo
 	raw           []byte           // 原始报文
 	version       uint8            // 版本（4 bit）
 	trafficType   uint8            // 流量类别
@@ -27,19 +31,55 @@ type IPv6 struct {
 	payloadLength uint16           // 数据部分的长度，包含扩展报文头
 	nextHeader    uint8            // 扩展报文头的类型
 	hopLimit      uint8            // 跳数限制
-	source        [16]byte         // 源 IP 地址
-	destination   [16]byte         // 目的 IP 地址
+	source        types.IPv6       // 源 IP 地址
+	destination   types.IPv6       // 目的 IP 地址
+	extensions    []IPv6Extension  // 扩展报文头链
+	truncated     bool             // 数据是否被截断（抓包长度不足，或 ICMPv6 差错报文中引用的原始报文）
+	quoted        bool             // 是否为 ICMPv6 差错报文中引用的原始报文
+	innerProtocol uint8            // 扩展报文头链之后的上层协议类型
 	data          resolver.IPacket // 上层协议的数据
 }
 
 func (ipv6 *IPv6) Hex() string {
-	return hex.EncodeToString(ipv6.raw)
+	return strings.ToUpper(hex.EncodeToString(ipv6.raw))
 }
 
 func (ipv6 *IPv6) Raw() []byte {
 	return ipv6.raw
 }
 
+// 源 IP 地址
+func (ipv6 *IPv6) Source() types.IPv6 {
+	return ipv6.source
+}
+
+// 目的 IP 地址
+func (ipv6 *IPv6) Destination() types.IPv6 {
+	return ipv6.destination
+}
+
+func (ipv6 *IPv6) Quoted() bool {
+	return ipv6.quoted
+}
+
+func (ipv6 *IPv6) Inner() resolver.IPacket {
+	return ipv6.data
+}
+
+func (ipv6 *IPv6) Tunnel() string {
+	return ipTunnel("IPv6", ipv6.innerProtocol, ipv6.data)
+}
+
+func (ipv6 *IPv6) Fields() []resolver.Field {
+	return []resolver.Field{
+		{Name: "ipv6.src", Value: ipv6.source.ToString()},
+		{Name: "ipv6.dst", Value: ipv6.destination.ToString()},
+		{Name: "ipv6.nxt", Value: strconv.Itoa(int(ipv6.innerProtocol))},
+		{Name: "ipv6.flow", Value: fmt.Sprintf("0x%05X", ipv6.flowLabel)},
+		{Name: "ipv6.hlim", Value: strconv.Itoa(int(ipv6.hopLimit))},
+	}
+}
+
 func (ipv6 *IPv6) ToReadableString(indent int) string {
 	builder := new(strings.Builder)
 	tabs := make([]byte, indent)
@@ -57,7 +97,7 @@ func (ipv6 *IPv6) ToReadableString(indent int) string {
 
 	builder.Write(tabs)
 	builder.WriteString("Traffic type: ")
-	builder.WriteString(fmt.Sprintf("0x%2X", ipv6.trafficType))
+	builder.WriteString(fmt.Sprintf("0x%02X", ipv6.trafficType))
 	builder.WriteByte('\n')
 
 	builder.Write(tabs)
@@ -68,12 +108,18 @@ func (ipv6 *IPv6) ToReadableString(indent int) string {
 	builder.Write(tabs)
 	builder.WriteString("Payload length: ")
 	builder.WriteString(strconv.Itoa(int(ipv6.payloadLength)))
+	if ipv6.truncated {
+		builder.WriteString(" (truncated to ")
+		builder.WriteString(strconv.Itoa(len(ipv6.raw) - 40))
+		builder.WriteString(")")
+	}
 	builder.WriteByte('\n')
 
 	builder.Write(tabs)
 	builder.WriteString("Next header: ")
-	builder.WriteString(fmt.Sprintf("0x%02X", ipv6.nextHeader))
-	builder.WriteByte('\n')
+	builder.WriteString(fmt.Sprintf("0x%02X (", ipv6.nextHeader))
+	builder.WriteString(ipv6HeaderName(ipv6.nextHeader))
+	builder.WriteString(")\n")
 
 	builder.Write(tabs)
 	builder.WriteString("Hop limit: ")
@@ -82,23 +128,33 @@ func (ipv6 *IPv6) ToReadableString(indent int) string {
 
 	builder.Write(tabs)
 	builder.WriteString("Source address: ")
-	for i := range ipv6.source {
-		if i == 0 {
-			builder.WriteString(fmt.Sprintf("%02X", ipv6.source[i]))
-		}
-		builder.WriteString(fmt.Sprintf(":%02X", ipv6.source[i]))
-	}
-	builder.WriteByte('\n')
+	builder.WriteString(ipv6.source.ToString())
+	builder.WriteString(" (")
+	builder.WriteString(ipv6.source.Classify())
+	builder.WriteString(")\n")
 
 	builder.Write(tabs)
 	builder.WriteString("Destination address: ")
-	for i := range ipv6.destination {
-		if i == 0 {
-			builder.WriteString(fmt.Sprintf("%02X", ipv6.destination[i]))
+	builder.WriteString(ipv6.destination.ToString())
+	builder.WriteString(" (")
+	builder.WriteString(ipv6.destination.Classify())
+	builder.WriteString(")\n")
+
+	if len(ipv6.extensions) != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Extension headers: {\n")
+		for _, extension := range ipv6.extensions {
+			builder.WriteString(extension.ToReadableString(indent + 1))
 		}
-		builder.WriteString(fmt.Sprintf(":%02X", ipv6.destination[i]))
+		builder.Write(tabs)
+		builder.WriteString("}\n")
 	}
-	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Upper layer protocol: ")
+	builder.WriteString(fmt.Sprintf("0x%02X (", ipv6.innerProtocol))
+	builder.WriteString(ipv6HeaderName(ipv6.innerProtocol))
+	builder.WriteString(")\n")
 
 	builder.Write(tabs)
 	builder.WriteString("Data: {\n")
@@ -113,43 +169,81 @@ func (ipv6 *IPv6) ToReadableString(indent int) string {
 
 	builder.Write(tabs)
 	builder.WriteString("Raw: ")
-	builder.Write(ipv6.raw)
+	builder.WriteString(ipv6.Hex())
 	builder.WriteByte('\n')
 
 	return builder.String()
 }
 
 func IPv6Resolve(packet []byte) resolver.IPacket {
+	if ipv6 := ipv6Resolve(packet, false); ipv6 != nil {
+		return ipv6
+	}
+	return nil
+}
+
+// 解析 ICMPv6 差错报文中引用的原始 IPv6 报文，允许数据被截断
+func IPv6QuotedResolve(packet []byte) resolver.IPacket {
+	if ipv6 := ipv6Resolve(packet, true); ipv6 != nil {
+		return ipv6
+	}
+	return nil
+}
+
+func ipv6Resolve(packet []byte, quoted bool) *IPv6 {
 	ipv6 := new(IPv6)
 	length := len(packet)
 	if length < 40 || length > 65575 {
 		return nil
 	}
-	if (packet[0] & 0x60) != 0x60 {
+	if packet[0]>>4 != 6 {
 		return nil
 	}
 	ipv6.version = 6
 	ipv6.trafficType = (packet[0]&0x0F)<<4 | (packet[1]&0xF0)>>4
 	ipv6.flowLabel = (uint32(packet[1]&0x0F) << 16) | (uint32(packet[2]) << 8) | uint32(packet[3])
 	ipv6.payloadLength = uint16(packet[4])<<8 | uint16(packet[5])
-	if 40+ipv6.payloadLength != uint16(length) {
-		return nil
+	// 载荷长度之后的数据（如以太网填充）不属于该报文
+	if length > 40+int(ipv6.payloadLength) {
+		length = 40 + int(ipv6.payloadLength)
+		packet = packet[:length]
+	}
+	ipv6.quoted = quoted
+	ipv6.truncated = length < 40+int(ipv6.payloadLength)
+	if ipv6.truncated && !quoted {
+		ipv6.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "IPv6", "Payload length exceeds captured data")
 	}
 	ipv6.nextHeader = packet[6]
 	ipv6.hopLimit = packet[7]
-	copy(ipv6.source[:], packet[8:24])
-	copy(ipv6.destination[:], packet[24:40])
-	switch ipv6.nextHeader {
-	case IPv6_NEXT_HEADER_TCP:
-		resolve := transportlayer.Resolvers["TCP"]
-		if resolve != nil {
-			ipv6.data = resolve(packet[40 : 40+ipv6.payloadLength])
+	ipv6.source.Parse([16]byte(packet[8:24]))
+	ipv6.destination.Parse([16]byte(packet[24:40]))
+	if ipv6.source.IsMulticast() {
+		ipv6.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_SECURITY, "IPv6", "Source address is multicast")
+	}
+	ipv6.raw = make([]byte, length)
+	copy(ipv6.raw, packet)
+
+	// 依次解析扩展报文头，直到遇到上层协议或无法解析的报文头
+	offset := 40
+	ipv6.innerProtocol = ipv6.nextHeader
+	fragmented := false
+	for {
+		extension := IPv6ExtensionResolve(ipv6.innerProtocol, packet[offset:])
+		if extension == nil {
+			break
 		}
-	case IPv4_PROTOCOL_UDP:
-		resolve := transportlayer.Resolvers["UDP"]
-		if resolve != nil {
-			ipv6.data = resolve(packet[40 : 40+ipv6.payloadLength])
+		if fragment, ok := extension.(*IPv6Fragment); ok && (fragment.offset != 0 || fragment.more) {
+			fragmented = true
 		}
+		ipv6.extensions = append(ipv6.extensions, extension)
+		ipv6.innerProtocol = extension.NextHeader()
+		offset += extension.Length()
+	}
+
+	// 分片报文的数据不完整，无法交给上层协议解析
+	if fragmented {
+		return ipv6
 	}
+	ipv6.data = ProtocolResolve(ipv6.innerProtocol, packet[offset:])
 	return ipv6
 }
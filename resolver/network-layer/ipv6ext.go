@@ -0,0 +1,466 @@
+package networklayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	IPv6_OPTION_PAD1           uint8 = 0x00
+	IPv6_OPTION_PADN           uint8 = 0x01
+	IPv6_OPTION_TUNNEL_LIMIT   uint8 = 0x04
+	IPv6_OPTION_ROUTER_ALERT   uint8 = 0x05
+	IPv6_OPTION_CALIPSO        uint8 = 0x07
+	IPv6_OPTION_IOAM           uint8 = 0x31
+	IPv6_OPTION_JUMBO_PAYLOAD  uint8 = 0xC2
+	IPv6_OPTION_HOME_ADDRESS   uint8 = 0xC9
+	IPv6_ROUTING_SOURCE_ROUTE  uint8 = 0 // 已废弃的源路由
+	IPv6_ROUTING_NIMROD        uint8 = 1
+	IPv6_ROUTING_MOBILE        uint8 = 2 // 移动 IPv6
+	IPv6_ROUTING_RPL           uint8 = 3
+	IPv6_ROUTING_SEGMENT_ROUTE uint8 = 4 // SRv6 段路由
+)
+
+var IPv6_OPTION_NAME = map[uint8]string{
+	IPv6_OPTION_PAD1:          "Pad1",
+	IPv6_OPTION_PADN:          "PadN",
+	IPv6_OPTION_TUNNEL_LIMIT:  "Tunnel Encapsulation Limit",
+	IPv6_OPTION_ROUTER_ALERT:  "Router Alert",
+	IPv6_OPTION_CALIPSO:       "CALIPSO",
+	IPv6_OPTION_IOAM:          "IOAM",
+	IPv6_OPTION_JUMBO_PAYLOAD: "Jumbo Payload",
+	IPv6_OPTION_HOME_ADDRESS:  "Home Address",
+}
+
+var IPv6_ROUTING_NAME = map[uint8]string{
+	IPv6_ROUTING_SOURCE_ROUTE:  "Source Route",
+	IPv6_ROUTING_NIMROD:        "Nimrod",
+	IPv6_ROUTING_MOBILE:        "Type 2 Routing Header",
+	IPv6_ROUTING_RPL:           "RPL Source Route",
+	IPv6_ROUTING_SEGMENT_ROUTE: "Segment Routing Header",
+}
+
+var IPv6_EXTENSION_NAME = map[uint8]string{
+	IPv6_NEXT_HEADER_HBH:      "Hop-by-Hop Options",
+	IPv6_NEXT_HEADER_ROUTING:  "Routing",
+	IPv6_NEXT_HEADER_FRAGMENT: "Fragment",
+	IPv6_NEXT_HEADER_DO:       "Destination Options",
+	IPv6_NEXT_HEADER_AH:       "Authentication Header",
+}
+
+// IPv6 扩展报文头
+type IPv6Extension interface {
+	Type() uint8       // 本扩展头的类型
+	NextHeader() uint8 // 下一个报文头的类型
+	Length() int       // 本扩展头的字节数
+	ToReadableString(indent int) string
+}
+
+// 扩展头的公共字段
+type BaseIPv6Extension struct {
+	headerType uint8  // 本扩展头的类型
+	nextHeader uint8  // 下一个报文头的类型
+	raw        []byte // 原始数据
+}
+
+func (extension *BaseIPv6Extension) Type() uint8 {
+	return extension.headerType
+}
+
+func (extension *BaseIPv6Extension) NextHeader() uint8 {
+	return extension.nextHeader
+}
+
+func (extension *BaseIPv6Extension) Length() int {
+	return len(extension.raw)
+}
+
+func (extension *BaseIPv6Extension) writeHeader(builder *strings.Builder, tabs []byte) {
+	builder.Write(tabs)
+	builder.WriteString("Extension header: ")
+	builder.WriteString(IPv6_EXTENSION_NAME[extension.headerType])
+	builder.WriteString(fmt.Sprintf(" (0x%02X)\n", extension.headerType))
+
+	builder.Write(tabs)
+	builder.WriteString("Next header: ")
+	builder.WriteString(fmt.Sprintf("0x%02X (", extension.nextHeader))
+	builder.WriteString(ipv6HeaderName(extension.nextHeader))
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Length: ")
+	builder.WriteString(strconv.Itoa(len(extension.raw)))
+	builder.WriteString(" byte\n")
+}
+
+// 报文头类型名称，既可以是扩展头，也可以是上层协议
+func ipv6HeaderName(header uint8) string {
+	if name := IPv6_EXTENSION_NAME[header]; name != "" {
+		return name
+	}
+	if name := IPv4_PROTOCOL_NAME[header]; name != "" {
+		return name
+	}
+	return "Unknown"
+}
+
+// 逐跳选项扩展头与目的选项扩展头中的选项
+type IPv6Option struct {
+	optionType uint8
+	data       []byte
+}
+
+// 逐跳选项扩展头或目的选项扩展头
+type IPv6Options struct {
+	BaseIPv6Extension
+	options []IPv6Option
+}
+
+func (extension *IPv6Options) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	extension.writeHeader(builder, tabs)
+
+	builder.Write(tabs)
+	builder.WriteString("Options: {\n")
+	for _, option := range extension.options {
+		builder.Write(tabs)
+		builder.WriteString(fmt.Sprintf("\t0x%02X (", option.optionType))
+		if name := IPv6_OPTION_NAME[option.optionType]; name != "" {
+			builder.WriteString(name)
+		} else {
+			builder.WriteString("Unknown")
+		}
+		builder.WriteString(")")
+		switch {
+		case option.optionType == IPv6_OPTION_ROUTER_ALERT && len(option.data) == 2:
+			builder.WriteString(": ")
+			builder.WriteString(strconv.Itoa(int(utils.ExtractUint16BE(option.data, 0))))
+		case option.optionType == IPv6_OPTION_JUMBO_PAYLOAD && len(option.data) == 4:
+			builder.WriteString(": ")
+			builder.WriteString(strconv.FormatUint(uint64(utils.ExtractUint32BE(option.data, 0)), 10))
+		case option.optionType == IPv6_OPTION_TUNNEL_LIMIT && len(option.data) == 1:
+			builder.WriteString(": ")
+			builder.WriteString(strconv.Itoa(int(option.data[0])))
+		case option.optionType == IPv6_OPTION_HOME_ADDRESS && len(option.data) == 16:
+			builder.WriteString(": ")
+			address := types.IPv6{}
+			address.Parse([16]byte(option.data))
+			builder.WriteString(address.ToString())
+		case len(option.data) != 0:
+			builder.WriteString(": ")
+			builder.WriteString(strings.ToUpper(hex.EncodeToString(option.data)))
+		}
+		builder.WriteByte('\n')
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+func IPv6OptionsResolve(headerType uint8, packet []byte) *IPv6Options {
+	if len(packet) < 8 {
+		return nil
+	}
+	length := (int(packet[1]) + 1) * 8
+	if length > len(packet) {
+		return nil
+	}
+
+	extension := new(IPv6Options)
+	extension.headerType = headerType
+	extension.nextHeader = packet[0]
+	for offset := 2; offset < length; {
+		option := IPv6Option{optionType: packet[offset]}
+		if option.optionType == IPv6_OPTION_PAD1 {
+			extension.options = append(extension.options, option)
+			offset++
+			continue
+		}
+		if offset+2 > length || offset+2+int(packet[offset+1]) > length {
+			return nil
+		}
+		option.data = make([]byte, packet[offset+1])
+		copy(option.data, packet[offset+2:offset+2+int(packet[offset+1])])
+		extension.options = append(extension.options, option)
+		offset += 2 + int(packet[offset+1])
+	}
+	extension.raw = make([]byte, length)
+	copy(extension.raw, packet)
+
+	return extension
+}
+
+// 路由扩展头
+type IPv6Routing struct {
+	BaseIPv6Extension
+	routingType  uint8        // 路由类型
+	segmentsLeft uint8        // 剩余段数
+	lastEntry    uint8        // 段列表最后一项的下标（仅 SRH）
+	flags        uint8        // 标志（仅 SRH）
+	tag          uint16       // 标签（仅 SRH）
+	addresses    []types.IPv6 // 地址列表；SRH 中为段列表，第 0 项为最后一段
+	data         []byte       // 无法解析的类型相关数据，SRH 中为段列表之后的 TLV
+}
+
+func (extension *IPv6Routing) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	extension.writeHeader(builder, tabs)
+
+	builder.Write(tabs)
+	builder.WriteString("Routing type: ")
+	builder.WriteString(strconv.Itoa(int(extension.routingType)))
+	builder.WriteString(" (")
+	if name := IPv6_ROUTING_NAME[extension.routingType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Segments left: ")
+	builder.WriteString(strconv.Itoa(int(extension.segmentsLeft)))
+	builder.WriteByte('\n')
+
+	if extension.routingType == IPv6_ROUTING_SEGMENT_ROUTE {
+		builder.Write(tabs)
+		builder.WriteString("Last entry: ")
+		builder.WriteString(strconv.Itoa(int(extension.lastEntry)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Flags: ")
+		builder.WriteString(fmt.Sprintf("0x%02X", extension.flags))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Tag: ")
+		builder.WriteString(fmt.Sprintf("0x%04X", extension.tag))
+		builder.WriteByte('\n')
+	}
+
+	if extension.addresses != nil {
+		builder.Write(tabs)
+		builder.WriteString("Addresses: {\n")
+		for i, address := range extension.addresses {
+			builder.Write(tabs)
+			builder.WriteString("\t[")
+			builder.WriteString(strconv.Itoa(i))
+			builder.WriteString("] ")
+			builder.WriteString(address.ToString())
+			if extension.routingType == IPv6_ROUTING_SEGMENT_ROUTE && i == int(extension.segmentsLeft) {
+				builder.WriteString(" (active)")
+			}
+			builder.WriteByte('\n')
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	}
+
+	if len(extension.data) != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Data(HEX): ")
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(extension.data)))
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+func IPv6RoutingResolve(packet []byte) *IPv6Routing {
+	if len(packet) < 8 {
+		return nil
+	}
+	length := (int(packet[1]) + 1) * 8
+	if length > len(packet) {
+		return nil
+	}
+
+	extension := new(IPv6Routing)
+	extension.headerType = IPv6_NEXT_HEADER_ROUTING
+	extension.nextHeader = packet[0]
+	extension.routingType = packet[2]
+	extension.segmentsLeft = packet[3]
+	data := packet[8:length]
+	switch extension.routingType {
+	case IPv6_ROUTING_SOURCE_ROUTE, IPv6_ROUTING_MOBILE:
+		for ; len(data) >= 16; data = data[16:] {
+			address := types.IPv6{}
+			address.Parse([16]byte(data[0:16]))
+			extension.addresses = append(extension.addresses, address)
+		}
+	case IPv6_ROUTING_SEGMENT_ROUTE:
+		extension.lastEntry = packet[4]
+		extension.flags = packet[5]
+		extension.tag = utils.ExtractUint16BE(packet, 6)
+		if (int(extension.lastEntry)+1)*16 > len(data) {
+			return nil
+		}
+		for i := 0; i <= int(extension.lastEntry); i++ {
+			address := types.IPv6{}
+			address.Parse([16]byte(data[i*16 : i*16+16]))
+			extension.addresses = append(extension.addresses, address)
+		}
+		data = data[(int(extension.lastEntry)+1)*16:]
+	default:
+		data = packet[4:length]
+	}
+	extension.data = make([]byte, len(data))
+	copy(extension.data, data)
+	extension.raw = make([]byte, length)
+	copy(extension.raw, packet)
+
+	return extension
+}
+
+// 分片扩展头
+type IPv6Fragment struct {
+	BaseIPv6Extension
+	offset         uint16 // 片偏移，单位 8 字节（13 bit）
+	more           bool   // 是否还有后续分片
+	identification uint32 // 标识符
+}
+
+func (extension *IPv6Fragment) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	extension.writeHeader(builder, tabs)
+
+	builder.Write(tabs)
+	builder.WriteString("Fragment offset: ")
+	builder.WriteString(strconv.Itoa(int(extension.offset)))
+	builder.WriteString(" (* 8 byte)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("More fragments: ")
+	if extension.more {
+		builder.WriteString("1")
+	} else {
+		builder.WriteString("0")
+	}
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Identification: ")
+	builder.WriteString(fmt.Sprintf("0x%08X", extension.identification))
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func IPv6FragmentResolve(packet []byte) *IPv6Fragment {
+	if len(packet) < 8 {
+		return nil
+	}
+
+	extension := new(IPv6Fragment)
+	extension.headerType = IPv6_NEXT_HEADER_FRAGMENT
+	extension.nextHeader = packet[0]
+	extension.offset = utils.ExtractUint16BE(packet, 2) >> 3
+	extension.more = (packet[3] & 0x1) == 0x1
+	extension.identification = utils.ExtractUint32BE(packet, 4)
+	extension.raw = make([]byte, 8)
+	copy(extension.raw, packet)
+
+	return extension
+}
+
+// 认证扩展头
+type IPv6AH struct {
+	BaseIPv6Extension
+	spi      uint32 // 安全参数索引
+	sequence uint32 // 序号
+	icv      []byte // 完整性校验值
+}
+
+func (extension *IPv6AH) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	extension.writeHeader(builder, tabs)
+
+	builder.Write(tabs)
+	builder.WriteString("SPI: ")
+	builder.WriteString(fmt.Sprintf("0x%08X", extension.spi))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Sequence number: ")
+	builder.WriteString(strconv.FormatUint(uint64(extension.sequence), 10))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("ICV: ")
+	builder.WriteString(strings.ToUpper(hex.EncodeToString(extension.icv)))
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func IPv6AHResolve(packet []byte) *IPv6AH {
+	if len(packet) < 12 {
+		return nil
+	}
+	// 长度字段单位为 4 字节，不含前 8 字节
+	length := (int(packet[1]) + 2) * 4
+	if length < 12 || length > len(packet) {
+		return nil
+	}
+
+	extension := new(IPv6AH)
+	extension.headerType = IPv6_NEXT_HEADER_AH
+	extension.nextHeader = packet[0]
+	extension.spi = utils.ExtractUint32BE(packet, 4)
+	extension.sequence = utils.ExtractUint32BE(packet, 8)
+	extension.icv = make([]byte, length-12)
+	copy(extension.icv, packet[12:length])
+	extension.raw = make([]byte, length)
+	copy(extension.raw, packet)
+
+	return extension
+}
+
+// 解析一个扩展头，headerType 不是扩展头或数据有误时返回 nil
+func IPv6ExtensionResolve(headerType uint8, packet []byte) IPv6Extension {
+	switch headerType {
+	case IPv6_NEXT_HEADER_HBH, IPv6_NEXT_HEADER_DO:
+		if extension := IPv6OptionsResolve(headerType, packet); extension != nil {
+			return extension
+		}
+	case IPv6_NEXT_HEADER_ROUTING:
+		if extension := IPv6RoutingResolve(packet); extension != nil {
+			return extension
+		}
+	case IPv6_NEXT_HEADER_FRAGMENT:
+		if extension := IPv6FragmentResolve(packet); extension != nil {
+			return extension
+		}
+	case IPv6_NEXT_HEADER_AH:
+		if extension := IPv6AHResolve(packet); extension != nil {
+			return extension
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package networklayer
+
+import (
+	"encoding/hex"
+	"packet-inspector/resolver"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+var MLD_RECORD_TYPE_NAME = map[uint8]string{
+	1: "MODE_IS_INCLUDE",
+	2: "MODE_IS_EXCLUDE",
+	3: "CHANGE_TO_INCLUDE_MODE",
+	4: "CHANGE_TO_EXCLUDE_MODE",
+	5: "ALLOW_NEW_SOURCES",
+	6: "BLOCK_OLD_SOURCES",
+}
+
+// 组播侦听发现报文（MLDv1 RFC 2710，MLDv2 RFC 3810）
+type MLD struct {
+	messageType uint8        // ICMPv6 类型
+	version     uint8        // 1 或 2
+	maxResponse uint32       // 查询：最大响应时间，单位毫秒
+	address     types.IPv6   // 组播地址
+	suppress    bool         // MLDv2 查询：禁止路由器侧处理标志
+	robustness  uint8        // MLDv2 查询：健壮性变量（3 bit）
+	interval    uint32       // MLDv2 查询：查询间隔，单位秒
+	sources     []types.IPv6 // MLDv2 查询：源地址列表
+	records     []MLDRecord  // MLDv2 报告：组播地址记录
+}
+
+// MLDv2 报告中的组播地址记录
+type MLDRecord struct {
+	recordType uint8        // 记录类型
+	address    types.IPv6   // 组播地址
+	sources    []types.IPv6 // 源地址列表
+	auxiliary  []byte       // 辅助数据
+}
+
+// MLDv2 的浮点编码：首位为 0 时为原值，否则为 (尾数 | 隐含位) << (指数 + 3)
+func mldFloat(code uint32, mantissaBits uint) uint32 {
+	threshold := uint32(1) << (mantissaBits + 3 + 3)
+	if code < threshold {
+		return code
+	}
+	mantissa := code & (1<<mantissaBits - 1)
+	exponent := (code >> mantissaBits) & 0x7
+	return (mantissa | 1<<mantissaBits) << (exponent + 3)
+}
+
+// 解析源地址列表
+func mldSources(packet []byte, offset int, count int) ([]types.IPv6, bool) {
+	if offset+count*16 > len(packet) {
+		return nil, false
+	}
+	sources := make([]types.IPv6, count)
+	for i := range sources {
+		sources[i].Parse([16]byte(packet[offset+i*16 : offset+i*16+16]))
+	}
+	return sources, true
+}
+
+func (mld *MLD) fields() []resolver.Field {
+	fields := []resolver.Field{{Name: "icmpv6.mld.version", Value: strconv.Itoa(int(mld.version))}}
+	if mld.messageType != ICMPv6_TYPE_MLDv2_REPORT {
+		fields = append(fields, resolver.Field{Name: "icmpv6.mld.address", Value: mld.address.ToString()})
+	}
+	for _, record := range mld.records {
+		fields = append(fields, resolver.Field{Name: "icmpv6.mld.address", Value: record.address.ToString()})
+	}
+	return fields
+}
+
+func (mld *MLD) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("MLD version: ")
+	builder.WriteString(strconv.Itoa(int(mld.version)))
+	builder.WriteByte('\n')
+
+	if mld.messageType == ICMPv6_TYPE_MLD_QUERY {
+		builder.Write(tabs)
+		builder.WriteString("Maximum response delay: ")
+		builder.WriteString(strconv.FormatUint(uint64(mld.maxResponse), 10))
+		builder.WriteString(" ms\n")
+	}
+
+	if mld.messageType != ICMPv6_TYPE_MLDv2_REPORT {
+		builder.Write(tabs)
+		builder.WriteString("Multicast address: ")
+		if mld.address.IsUnspecified() && mld.messageType == ICMPv6_TYPE_MLD_QUERY {
+			builder.WriteString(":: (general query)")
+		} else {
+			builder.WriteString(mld.address.ToString())
+		}
+		builder.WriteByte('\n')
+	}
+
+	if mld.messageType == ICMPv6_TYPE_MLD_QUERY && mld.version == 2 {
+		builder.Write(tabs)
+		builder.WriteString("Suppress router-side processing: ")
+		if mld.suppress {
+			builder.WriteString("1\n")
+		} else {
+			builder.WriteString("0\n")
+		}
+
+		builder.Write(tabs)
+		builder.WriteString("Robustness variable: ")
+		builder.WriteString(strconv.Itoa(int(mld.robustness)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Query interval: ")
+		builder.WriteString(strconv.FormatUint(uint64(mld.interval), 10))
+		builder.WriteString(" s\n")
+
+		writeMLDSources(builder, tabs, mld.sources)
+	}
+
+	if mld.messageType == ICMPv6_TYPE_MLDv2_REPORT {
+		builder.Write(tabs)
+		builder.WriteString("Records: {\n")
+		for _, record := range mld.records {
+			builder.Write(tabs)
+			builder.WriteString("\t")
+			builder.WriteString(record.address.ToString())
+			builder.WriteString(": ")
+			if name := MLD_RECORD_TYPE_NAME[record.recordType]; name != "" {
+				builder.WriteString(name)
+			} else {
+				builder.WriteString("Unknown (" + strconv.Itoa(int(record.recordType)) + ")")
+			}
+			builder.WriteByte('\n')
+			writeMLDSources(builder, append(tabs, '\t'), record.sources)
+			if len(record.auxiliary) != 0 {
+				builder.Write(tabs)
+				builder.WriteString("\tAuxiliary data(HEX): ")
+				builder.WriteString(strings.ToUpper(hex.EncodeToString(record.auxiliary)))
+				builder.WriteByte('\n')
+			}
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
+
+func writeMLDSources(builder *strings.Builder, tabs []byte, sources []types.IPv6) {
+	if len(sources) == 0 {
+		return
+	}
+	builder.Write(tabs)
+	builder.WriteString("Sources: {\n")
+	for _, source := range sources {
+		builder.Write(tabs)
+		builder.WriteString("\t")
+		builder.WriteString(source.ToString())
+		builder.WriteByte('\n')
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+}
+
+// 解析组播侦听发现报文，packet 从 ICMPv6 报文头开始；格式有误时返回 nil
+func MLDResolve(messageType uint8, packet []byte) *MLD {
+	mld := new(MLD)
+	mld.messageType = messageType
+	length := len(packet)
+
+	if messageType == ICMPv6_TYPE_MLDv2_REPORT {
+		if length < 8 {
+			return nil
+		}
+		mld.version = 2
+		offset := 8
+		for i := 0; i < int(utils.ExtractUint16BE(packet, 6)); i++ {
+			if offset+20 > length {
+				return nil
+			}
+			record := MLDRecord{recordType: packet[offset]}
+			auxiliary := int(packet[offset+1]) * 4
+			count := int(utils.ExtractUint16BE(packet, offset+2))
+			record.address.Parse([16]byte(packet[offset+4 : offset+20]))
+			sources, ok := mldSources(packet, offset+20, count)
+			if !ok {
+				return nil
+			}
+			record.sources = sources
+			offset += 20 + count*16
+			if offset+auxiliary > length {
+				return nil
+			}
+			record.auxiliary = make([]byte, auxiliary)
+			copy(record.auxiliary, packet[offset:offset+auxiliary])
+			offset += auxiliary
+			mld.records = append(mld.records, record)
+		}
+		return mld
+	}
+
+	if length < 24 {
+		return nil
+	}
+	mld.address.Parse([16]byte(packet[8:24]))
+	// 长度至少为 28 字节的查询为 MLDv2 查询
+	if messageType != ICMPv6_TYPE_MLD_QUERY || length < 28 {
+		mld.version = 1
+		mld.maxResponse = uint32(utils.ExtractUint16BE(packet, 4))
+		return mld
+	}
+
+	mld.version = 2
+	mld.maxResponse = mldFloat(uint32(utils.ExtractUint16BE(packet, 4)), 12)
+	mld.suppress = packet[24]&0x08 != 0
+	mld.robustness = packet[24] & 0x07
+	mld.interval = mldFloat(uint32(packet[25]), 4)
+	sources, ok := mldSources(packet, 28, int(utils.ExtractUint16BE(packet, 26)))
+	if !ok {
+		return nil
+	}
+	mld.sources = sources
+	return mld
+}
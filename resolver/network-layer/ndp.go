@@ -0,0 +1,388 @@
+package networklayer
+
+import (
+	"encoding/hex"
+	"packet-inspector/resolver"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	NDP_OPTION_SOURCE_LINK_ADDRESS uint8 = 1
+	NDP_OPTION_TARGET_LINK_ADDRESS uint8 = 2
+	NDP_OPTION_PREFIX_INFORMATION  uint8 = 3
+	NDP_OPTION_REDIRECTED_HEADER   uint8 = 4
+	NDP_OPTION_MTU                 uint8 = 5
+	NDP_OPTION_NONCE               uint8 = 14
+	NDP_OPTION_ROUTE_INFORMATION   uint8 = 24
+	NDP_OPTION_RDNSS               uint8 = 25
+	NDP_OPTION_DNSSL               uint8 = 31
+)
+
+var NDP_OPTION_NAME = map[uint8]string{
+	NDP_OPTION_SOURCE_LINK_ADDRESS: "Source Link-Layer Address",
+	NDP_OPTION_TARGET_LINK_ADDRESS: "Target Link-Layer Address",
+	NDP_OPTION_PREFIX_INFORMATION:  "Prefix Information",
+	NDP_OPTION_REDIRECTED_HEADER:   "Redirected Header",
+	NDP_OPTION_MTU:                 "MTU",
+	NDP_OPTION_NONCE:               "Nonce",
+	NDP_OPTION_ROUTE_INFORMATION:   "Route Information",
+	NDP_OPTION_RDNSS:               "Recursive DNS Server",
+	NDP_OPTION_DNSSL:               "DNS Search List",
+}
+
+// 路由器优先级（2 bit）
+var NDP_PREFERENCE_NAME = map[uint8]string{
+	0: "Medium",
+	1: "High",
+	2: "Reserved",
+	3: "Low",
+}
+
+// 邻居发现报文（RFC 4861）
+type NDP struct {
+	messageType    uint8       // ICMPv6 类型
+	hopLimit       uint8       // 路由器通告：建议的跳数限制
+	flags          uint8       // 路由器通告：M/O/H/Prf；邻居通告：R/S/O
+	routerLifetime uint16      // 路由器通告：路由器生存期，单位秒
+	reachableTime  uint32      // 路由器通告：可达时间，单位毫秒
+	retransTimer   uint32      // 路由器通告：重传间隔，单位毫秒
+	target         types.IPv6  // 邻居请求/通告、重定向：目标地址
+	destination    types.IPv6  // 重定向：目的地址
+	options        []NDPOption // 选项
+}
+
+// 邻居发现选项
+type NDPOption struct {
+	optionType uint8  // 选项类型
+	data       []byte // 选项数据，不含类型与长度字段
+}
+
+// 链路层地址选项中的地址，以太网地址以 MAC 格式显示
+func ndpLinkAddress(data []byte) string {
+	if len(data) == 6 {
+		mac := types.Mac{}
+		mac.Parse([6]byte(data))
+		if named := mac.ToNamedString(); named != mac.ToString() {
+			return named + " (" + mac.ToString() + ")"
+		}
+		return mac.ToString()
+	}
+	return strings.ToUpper(hex.EncodeToString(data))
+}
+
+// 生存期，全 1 表示无限
+func ndpLifetime(lifetime uint32) string {
+	if lifetime == 0xFFFFFFFF {
+		return "infinity"
+	}
+	return strconv.FormatUint(uint64(lifetime), 10) + " s"
+}
+
+// 解析 DNS 标签格式的域名列表
+func ndpDomainNames(data []byte) []string {
+	names := []string{}
+	labels := []string{}
+	for i := 0; i < len(data); {
+		length := int(data[i])
+		i++
+		if length == 0 {
+			if len(labels) != 0 {
+				names = append(names, strings.Join(labels, "."))
+				labels = labels[:0]
+			}
+			continue
+		}
+		if i+length > len(data) {
+			break
+		}
+		labels = append(labels, string(data[i:i+length]))
+		i += length
+	}
+	return names
+}
+
+func (option *NDPOption) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Option: ")
+	builder.WriteString(strconv.Itoa(int(option.optionType)))
+	builder.WriteString(" (")
+	if name := NDP_OPTION_NAME[option.optionType]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Unknown")
+	}
+	builder.WriteString(")\n")
+
+	data := option.data
+	switch {
+	case option.optionType == NDP_OPTION_SOURCE_LINK_ADDRESS || option.optionType == NDP_OPTION_TARGET_LINK_ADDRESS:
+		// 以太网地址之后为填充
+		if len(data) >= 6 {
+			data = data[:6]
+		}
+		builder.Write(tabs)
+		builder.WriteString("Link-layer address: ")
+		builder.WriteString(ndpLinkAddress(data))
+		builder.WriteByte('\n')
+	case option.optionType == NDP_OPTION_PREFIX_INFORMATION && len(data) >= 30:
+		prefix := types.IPv6{}
+		prefix.Parse([16]byte(data[14:30]))
+		builder.Write(tabs)
+		builder.WriteString("Prefix: ")
+		builder.WriteString(prefix.ToString())
+		builder.WriteString("/")
+		builder.WriteString(strconv.Itoa(int(data[0])))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Flags: ")
+		builder.WriteString(ndpFlags(data[1], []string{"L (on-link)", "A (autonomous)", "R (router address)"}))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Valid lifetime: ")
+		builder.WriteString(ndpLifetime(utils.ExtractUint32BE(data, 2)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Preferred lifetime: ")
+		builder.WriteString(ndpLifetime(utils.ExtractUint32BE(data, 6)))
+		builder.WriteByte('\n')
+	case option.optionType == NDP_OPTION_REDIRECTED_HEADER && len(data) >= 6:
+		builder.Write(tabs)
+		builder.WriteString("Redirected packet: {\n")
+		if original := IPv6QuotedResolve(data[6:]); original != nil {
+			builder.WriteString(original.ToReadableString(indent + 1))
+		} else {
+			builder.Write(tabs)
+			builder.WriteString("\t(NOT RESOLVED)\n")
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	case option.optionType == NDP_OPTION_MTU && len(data) >= 6:
+		builder.Write(tabs)
+		builder.WriteString("MTU: ")
+		builder.WriteString(strconv.FormatUint(uint64(utils.ExtractUint32BE(data, 2)), 10))
+		builder.WriteByte('\n')
+	case option.optionType == NDP_OPTION_ROUTE_INFORMATION && len(data) >= 6:
+		prefix := [16]byte{}
+		copy(prefix[:], data[6:])
+		address := types.IPv6{}
+		address.Parse(prefix)
+		builder.Write(tabs)
+		builder.WriteString("Prefix: ")
+		builder.WriteString(address.ToString())
+		builder.WriteString("/")
+		builder.WriteString(strconv.Itoa(int(data[0])))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Preference: ")
+		builder.WriteString(NDP_PREFERENCE_NAME[(data[1]>>3)&0x3])
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Route lifetime: ")
+		builder.WriteString(ndpLifetime(utils.ExtractUint32BE(data, 2)))
+		builder.WriteByte('\n')
+	case option.optionType == NDP_OPTION_RDNSS && len(data) >= 6:
+		builder.Write(tabs)
+		builder.WriteString("Lifetime: ")
+		builder.WriteString(ndpLifetime(utils.ExtractUint32BE(data, 2)))
+		builder.WriteByte('\n')
+		for i := 6; i+16 <= len(data); i += 16 {
+			server := types.IPv6{}
+			server.Parse([16]byte(data[i : i+16]))
+			builder.Write(tabs)
+			builder.WriteString("Server: ")
+			builder.WriteString(server.ToString())
+			builder.WriteByte('\n')
+		}
+	case option.optionType == NDP_OPTION_DNSSL && len(data) >= 6:
+		builder.Write(tabs)
+		builder.WriteString("Lifetime: ")
+		builder.WriteString(ndpLifetime(utils.ExtractUint32BE(data, 2)))
+		builder.WriteByte('\n')
+		for _, name := range ndpDomainNames(data[6:]) {
+			builder.Write(tabs)
+			builder.WriteString("Domain: ")
+			builder.WriteString(name)
+			builder.WriteByte('\n')
+		}
+	default:
+		builder.Write(tabs)
+		builder.WriteString("Data(HEX): ")
+		builder.WriteString(strings.ToUpper(hex.EncodeToString(data)))
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+// 按从高位到低位的顺序列出已置位的标志
+func ndpFlags(flags uint8, names []string) string {
+	set := []string{}
+	for i, name := range names {
+		if flags&(0x80>>i) != 0 {
+			set = append(set, name)
+		}
+	}
+	if len(set) == 0 {
+		return "(none)"
+	}
+	return strings.Join(set, ", ")
+}
+
+func (ndp *NDP) fields() []resolver.Field {
+	fields := []resolver.Field{}
+	switch ndp.messageType {
+	case ICMPv6_TYPE_NEIGHBOR_SOLICITATION, ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT, ICMPv6_TYPE_REDIRECT:
+		fields = append(fields, resolver.Field{Name: "icmpv6.nd.target", Value: ndp.target.ToString()})
+	}
+	for _, option := range ndp.options {
+		if (option.optionType == NDP_OPTION_SOURCE_LINK_ADDRESS || option.optionType == NDP_OPTION_TARGET_LINK_ADDRESS) && len(option.data) >= 6 {
+			fields = append(fields, resolver.Field{Name: "icmpv6.nd.lladdr", Value: ndpLinkAddress(option.data[:6])})
+		}
+		if option.optionType == NDP_OPTION_PREFIX_INFORMATION && len(option.data) >= 30 {
+			prefix := types.IPv6{}
+			prefix.Parse([16]byte(option.data[14:30]))
+			fields = append(fields, resolver.Field{Name: "icmpv6.nd.prefix", Value: prefix.ToString()})
+		}
+	}
+	return fields
+}
+
+func (ndp *NDP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	switch ndp.messageType {
+	case ICMPv6_TYPE_ROUTER_ADVERTISEMENT:
+		builder.Write(tabs)
+		builder.WriteString("Current hop limit: ")
+		builder.WriteString(strconv.Itoa(int(ndp.hopLimit)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Flags: ")
+		builder.WriteString(ndpFlags(ndp.flags, []string{"M (managed)", "O (other configuration)", "H (home agent)"}))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Router preference: ")
+		builder.WriteString(NDP_PREFERENCE_NAME[(ndp.flags>>3)&0x3])
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Router lifetime: ")
+		builder.WriteString(strconv.Itoa(int(ndp.routerLifetime)))
+		builder.WriteString(" s\n")
+
+		builder.Write(tabs)
+		builder.WriteString("Reachable time: ")
+		builder.WriteString(strconv.FormatUint(uint64(ndp.reachableTime), 10))
+		builder.WriteString(" ms\n")
+
+		builder.Write(tabs)
+		builder.WriteString("Retrans timer: ")
+		builder.WriteString(strconv.FormatUint(uint64(ndp.retransTimer), 10))
+		builder.WriteString(" ms\n")
+	case ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT:
+		builder.Write(tabs)
+		builder.WriteString("Flags: ")
+		builder.WriteString(ndpFlags(ndp.flags, []string{"R (router)", "S (solicited)", "O (override)"}))
+		builder.WriteByte('\n')
+	}
+
+	switch ndp.messageType {
+	case ICMPv6_TYPE_NEIGHBOR_SOLICITATION, ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT, ICMPv6_TYPE_REDIRECT:
+		builder.Write(tabs)
+		builder.WriteString("Target address: ")
+		builder.WriteString(ndp.target.ToString())
+		builder.WriteByte('\n')
+	}
+	if ndp.messageType == ICMPv6_TYPE_REDIRECT {
+		builder.Write(tabs)
+		builder.WriteString("Destination address: ")
+		builder.WriteString(ndp.destination.ToString())
+		builder.WriteByte('\n')
+	}
+
+	if len(ndp.options) != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Options: {\n")
+		for _, option := range ndp.options {
+			builder.WriteString(option.ToReadableString(indent + 1))
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
+
+// 解析邻居发现报文，packet 从 ICMPv6 报文头开始；格式有误时返回 nil
+func NDPResolve(messageType uint8, packet []byte) *NDP {
+	ndp := new(NDP)
+	ndp.messageType = messageType
+	offset := 0
+	switch messageType {
+	case ICMPv6_TYPE_ROUTER_SOLICITATION:
+		offset = 8
+	case ICMPv6_TYPE_ROUTER_ADVERTISEMENT:
+		offset = 16
+	case ICMPv6_TYPE_NEIGHBOR_SOLICITATION, ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT:
+		offset = 24
+	case ICMPv6_TYPE_REDIRECT:
+		offset = 40
+	default:
+		return nil
+	}
+	if len(packet) < offset {
+		return nil
+	}
+
+	switch messageType {
+	case ICMPv6_TYPE_ROUTER_ADVERTISEMENT:
+		ndp.hopLimit = packet[4]
+		ndp.flags = packet[5]
+		ndp.routerLifetime = utils.ExtractUint16BE(packet, 6)
+		ndp.reachableTime = utils.ExtractUint32BE(packet, 8)
+		ndp.retransTimer = utils.ExtractUint32BE(packet, 12)
+	case ICMPv6_TYPE_NEIGHBOR_ADVERTISEMENT:
+		ndp.flags = packet[4]
+		ndp.target.Parse([16]byte(packet[8:24]))
+	case ICMPv6_TYPE_NEIGHBOR_SOLICITATION:
+		ndp.target.Parse([16]byte(packet[8:24]))
+	case ICMPv6_TYPE_REDIRECT:
+		ndp.target.Parse([16]byte(packet[8:24]))
+		ndp.destination.Parse([16]byte(packet[24:40]))
+	}
+
+	// 选项长度单位 8 字节，包含类型与长度字段，长度为 0 的选项无效
+	for offset+2 <= len(packet) {
+		length := int(packet[offset+1]) * 8
+		if length == 0 || offset+length > len(packet) {
+			return nil
+		}
+		option := NDPOption{optionType: packet[offset]}
+		option.data = make([]byte, length-2)
+		copy(option.data, packet[offset+2:offset+length])
+		ndp.options = append(ndp.options, option)
+		offset += length
+	}
+
+	return ndp
+}
@@ -6,4 +6,9 @@ var Resolvers = map[string]resolver.PacketResolver{}
 
 func init() {
 	Resolvers["IPv4"] = IPv4Resolve
+	Resolvers["IPv6"] = IPv6Resolve
+	Resolvers["ARP"] = ARPResolve
+	Resolvers["RARP"] = ARPResolve
+	Resolvers["ICMP"] = ICMPResolve
+	Resolvers["ICMPv6"] = ICMPv6Resolve
 }
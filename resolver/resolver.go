@@ -8,3 +8,70 @@ type IPacket interface {
 }
 
 type PacketResolver func(packet []byte) IPacket
+
+// 从字节流开头切分出一条完整的消息，返回该消息的长度；无法切分（数据不完整或协议不符）时返回 0
+type MessageFramer func(stream []byte) int
+
+// 可用于过滤与统计的报文字段，Name 形如 "协议.字段"，如 "vlan.id"
+type Field struct {
+	Name  string
+	Value string
+}
+
+// 提供本层字段的报文
+type IFields interface {
+	Fields() []Field
+}
+
+// 封装了上层协议报文的报文
+type IContainer interface {
+	Inner() IPacket
+}
+
+// 隧道报文，其内层为另一个完整的链路层或网络层报文
+type ITunnel interface {
+	Tunnel() string // 隧道名称，如 "VXLAN 100"；不是隧道时为空
+}
+
+// 由外向内依次收集各层报文的字段。报文中有隧道时，每个隧道额外产生一个 "tunnel" 字段，
+// 最外层（第一个隧道之外）的字段另以 "outer." 为前缀出现一次，最内层（最后一个隧道之内）的字段另以 "inner." 为前缀出现一次。
+// 各层的专家信息最后以 "expert." 为前缀的字段出现
+func CollectFields(packet IPacket) []Field {
+	start := packet
+	fields := []Field{}
+	depths := []int{}
+	depth := 0
+	for packet != nil {
+		if p, ok := packet.(IFields); ok {
+			for _, field := range p.Fields() {
+				fields = append(fields, field)
+				depths = append(depths, depth)
+			}
+		}
+		if p, ok := packet.(ITunnel); ok {
+			if tunnel := p.Tunnel(); tunnel != "" {
+				fields = append(fields, Field{Name: "tunnel", Value: tunnel})
+				depths = append(depths, depth)
+				depth++
+			}
+		}
+		container, ok := packet.(IContainer)
+		if !ok {
+			break
+		}
+		packet = container.Inner()
+	}
+
+	if depth != 0 {
+		count := len(fields)
+		for i := range count {
+			if depths[i] == 0 && fields[i].Name != "tunnel" {
+				fields = append(fields, Field{Name: "outer." + fields[i].Name, Value: fields[i].Value})
+			}
+			if depths[i] == depth {
+				fields = append(fields, Field{Name: "inner." + fields[i].Name, Value: fields[i].Value})
+			}
+		}
+	}
+	return append(fields, expertFields(CollectExperts(start))...)
+}
@@ -4,7 +4,39 @@ import "packet-inspector/resolver"
 
 var Resolvers = map[string]resolver.PacketResolver{}
 
+// 由 UDP 端口分派的协议（如隧道），键为 UDP_PORT_NAME 中的名称，由实现这些协议的包在初始化时注册
+var PortResolvers = map[string]resolver.PacketResolver{}
+
+const (
+	UDP_PORT_VXLAN  uint16 = 4789
+	UDP_PORT_GENEVE uint16 = 6081
+)
+
+var UDP_PORT_NAME = map[uint16]string{
+	UDP_PORT_VXLAN:  "VXLAN",
+	UDP_PORT_GENEVE: "Geneve",
+}
+
+// UDP 端口对应的已注册协议，先查目的端口再查源端口，没有对应协议时返回空字符串
+func PortProtocol(source uint16, destination uint16) string {
+	for _, port := range []uint16{destination, source} {
+		if name := UDP_PORT_NAME[port]; PortResolvers[name] != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// 按 UDP 端口解析载荷，没有对应协议时返回 nil
+func PortResolve(source uint16, destination uint16, payload []byte) resolver.IPacket {
+	if name := PortProtocol(source, destination); name != "" {
+		return PortResolvers[name](payload)
+	}
+	return nil
+}
+
 func init() {
 	Resolvers["TCP"] = TCPResolve
 	Resolvers["UDP"] = UDPResolve
+	Resolvers["SCTP"] = SCTPResolve
 }
@@ -0,0 +1,311 @@
+package transportlayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	applicationlayer "packet-inspector/resolver/application-layer"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// 常见的 SCTP 载荷协议标识（IANA）
+var SCTP_PPID_NAME = map[uint32]string{
+	0:  "Unspecified",
+	1:  "IUA",
+	2:  "M2UA",
+	3:  "M3UA",
+	4:  "SUA",
+	5:  "M2PA",
+	6:  "V5UA",
+	7:  "H.248",
+	18: "S1AP",
+	19: "RUA",
+	20: "HNBAP",
+	25: "NBAP",
+	27: "X2AP",
+	46: "Diameter",
+	47: "Diameter DTLS",
+	51: "WebRTC String",
+	53: "WebRTC Binary",
+	60: "NGAP",
+	61: "XnAP",
+	62: "F1AP",
+}
+
+// 按载荷协议标识分派的应用层协议，键为载荷协议标识，值为 applicationlayer.Resolvers 中的名称
+var SCTP_PPID_PROTOCOL = map[uint32]string{}
+
+// 载荷协议标识的可读形式，如 "3 (M3UA)"
+func SCTPPPIDString(ppid uint32) string {
+	name := SCTP_PPID_PROTOCOL[ppid]
+	if name == "" {
+		name = SCTP_PPID_NAME[ppid]
+	}
+	if name == "" {
+		name = "Unknown"
+	}
+	return strconv.FormatUint(uint64(ppid), 10) + " (" + name + ")"
+}
+
+// 按载荷协议标识解析一条完整的用户消息：标识对应已注册的应用层协议时用该协议解析，
+// 未指定或未知的标识逐个尝试各应用层协议，已知但没有解析器的协议返回 nil
+func PPIDResolve(ppid uint32, payload []byte) resolver.IPacket {
+	if name := SCTP_PPID_PROTOCOL[ppid]; name != "" {
+		if resolve := applicationlayer.Resolvers[name]; resolve != nil {
+			return resolve(payload)
+		}
+		return nil
+	}
+	if ppid != 0 && SCTP_PPID_NAME[ppid] != "" {
+		return nil
+	}
+	message, _ := applicationlayer.Resolve(payload)
+	return message
+}
+
+type SCTP struct {
+	resolver.IPacket
+	resolver.ExpertInfo
+	raw             []byte             // 原始报文
+	source          uint16             // 源端口
+	destination     uint16             // 目的端口
+	verificationTag uint32             // 验证标签
+	checksum        uint32             // CRC-32C 校验和
+	chunks          []SCTPChunk        // 块
+	messages        []resolver.IPacket // 各 DATA 块中未分片的用户消息，无法解析或为分片时为 nil
+}
+
+func (sctp *SCTP) Raw() []byte {
+	return sctp.raw
+}
+
+func (sctp *SCTP) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(sctp.raw))
+}
+
+// 第一条解析出的用户消息
+func (sctp *SCTP) Inner() resolver.IPacket {
+	for _, message := range sctp.messages {
+		if message != nil {
+			return message
+		}
+	}
+	return nil
+}
+
+func (sctp *SCTP) SourcePort() uint16 {
+	return sctp.source
+}
+
+func (sctp *SCTP) DestinationPort() uint16 {
+	return sctp.destination
+}
+
+func (sctp *SCTP) VerificationTag() uint32 {
+	return sctp.verificationTag
+}
+
+func (sctp *SCTP) Chunks() []SCTPChunk {
+	return sctp.chunks
+}
+
+func (sctp *SCTP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "sctp.srcport", Value: strconv.Itoa(int(sctp.source))},
+		{Name: "sctp.dstport", Value: strconv.Itoa(int(sctp.destination))},
+		{Name: "sctp.verification_tag", Value: fmt.Sprintf("0x%08X", sctp.verificationTag)},
+	}
+	for _, chunk := range sctp.chunks {
+		fields = append(fields, resolver.Field{Name: "sctp.chunk_type", Value: strconv.Itoa(int(chunk.Type))})
+		if data, ok := chunk.Data(); ok {
+			fields = append(fields,
+				resolver.Field{Name: "sctp.data_tsn", Value: strconv.FormatUint(uint64(data.TSN), 10)},
+				resolver.Field{Name: "sctp.data_sid", Value: strconv.Itoa(int(data.Stream))},
+				resolver.Field{Name: "sctp.data_ssn", Value: strconv.Itoa(int(data.SSN))},
+				resolver.Field{Name: "sctp.data_payload_proto_id", Value: strconv.FormatUint(uint64(data.PPID), 10)},
+			)
+			if !data.Complete() {
+				fields = append(fields, resolver.Field{Name: "sctp.data_fragment", Value: "1"})
+			}
+		}
+		if (chunk.Type == SCTP_CHUNK_INIT || chunk.Type == SCTP_CHUNK_INIT_ACK) && !chunk.Malformed && len(chunk.Value) >= 16 {
+			fields = append(fields, resolver.Field{Name: "sctp.init_tag", Value: fmt.Sprintf("0x%08X", utils.ExtractUint32BE(chunk.Value, 0))})
+		}
+	}
+	return fields
+}
+
+func (sctp *SCTP) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: SCTP (Transport)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Source port: ")
+	builder.WriteString(strconv.Itoa(int(sctp.source)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Destination port: ")
+	builder.WriteString(strconv.Itoa(int(sctp.destination)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Verification tag: ")
+	builder.WriteString(fmt.Sprintf("0x%08X", sctp.verificationTag))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Checksum: ")
+	builder.WriteString(fmt.Sprintf("0x%08X", sctp.checksum))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	if len(sctp.chunks) != 0 {
+		builder.WriteString("Chunks: {\n")
+		for _, chunk := range sctp.chunks {
+			builder.Write(tabs)
+			builder.WriteByte('\t')
+			if name := SCTP_CHUNK_NAME[chunk.Type]; name != "" {
+				builder.WriteString(name)
+			} else {
+				builder.WriteString(fmt.Sprintf("Unknown chunk 0x%02X", chunk.Type))
+			}
+			builder.WriteString(fmt.Sprintf(" (flags 0x%02X)", chunk.Flags))
+			if text := chunk.ToString(); text != "" {
+				builder.WriteString(": ")
+				builder.WriteString(text)
+			}
+			builder.WriteByte('\n')
+			for _, parameter := range chunk.Parameters() {
+				builder.Write(tabs)
+				builder.WriteString("\t\t")
+				builder.WriteString(sctpParameterName(parameter.Type))
+				builder.WriteString(": ")
+				builder.WriteString(parameter.ToString())
+				builder.WriteByte('\n')
+			}
+			for _, cause := range chunk.Causes() {
+				builder.Write(tabs)
+				builder.WriteString("\t\t")
+				builder.WriteString(cause.Name())
+				if text := cause.ToString(); text != "" {
+					builder.WriteString(": ")
+					builder.WriteString(text)
+				}
+				builder.WriteByte('\n')
+			}
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	} else {
+		builder.WriteString("Chunks: (No chunks)\n")
+	}
+
+	// 每个 DATA 块对应一项：解析出的消息、无法解析，或等待重组的分片
+	if len(sctp.messages) != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Data: {\n")
+		index := 0
+		for _, chunk := range sctp.chunks {
+			data, ok := chunk.Data()
+			if !ok {
+				continue
+			}
+			message := sctp.messages[index]
+			index++
+			switch {
+			case message != nil:
+				builder.WriteString(message.ToReadableString(indent + 1))
+			case !data.Complete():
+				builder.Write(tabs)
+				builder.WriteString(fmt.Sprintf("\t(FRAGMENT of stream %d, TSN %d, reassembled separately)\n", data.Stream, data.TSN))
+			default:
+				builder.Write(tabs)
+				builder.WriteString("\t(NOT RESOLVED)\n")
+			}
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(sctp.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func SCTPResolve(packet []byte) resolver.IPacket {
+	sctp := new(SCTP)
+	length := len(packet)
+	if length < 12 {
+		return nil
+	}
+
+	sctp.raw = make([]byte, length)
+	copy(sctp.raw, packet)
+
+	sctp.source = utils.ExtractUint16BE(packet, 0)
+	sctp.destination = utils.ExtractUint16BE(packet, 2)
+	sctp.verificationTag = utils.ExtractUint32BE(packet, 4)
+	sctp.checksum = utils.ExtractUint32BE(packet, 8)
+	// 校验和按小端序存放，计算时校验和字段视为 0；为 0 时可能由网卡填写，不作检查
+	if sctp.checksum != 0 && utils.CRC32C(packet[:8], make([]byte, 4), packet[12:]) != utils.ExtractUint32LE(packet, 8) {
+		sctp.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "SCTP", "Bad checksum")
+	}
+	// 块的数据取自报文的副本，不引用调用方的缓冲区
+	sctp.chunks = ParseSCTPChunks(sctp.raw[12:])
+
+	for _, chunk := range sctp.chunks {
+		if chunk.Malformed {
+			sctp.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "SCTP", "Malformed chunk")
+			continue
+		}
+		switch chunk.Type {
+		case SCTP_CHUNK_DATA:
+			data, ok := chunk.Data()
+			if !ok {
+				sctp.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "SCTP", "DATA chunk too short")
+				continue
+			}
+			if len(data.UserData) == 0 {
+				sctp.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_PROTOCOL, "SCTP", "DATA chunk without user data")
+			}
+			var message resolver.IPacket
+			if data.Complete() && len(data.UserData) != 0 {
+				message = PPIDResolve(data.PPID, data.UserData)
+			}
+			sctp.messages = append(sctp.messages, message)
+		case SCTP_CHUNK_INIT, SCTP_CHUNK_INIT_ACK, SCTP_CHUNK_SHUTDOWN_COMPLETE:
+			// RFC 9260：这些块不能与其他块捆绑
+			if len(sctp.chunks) > 1 {
+				sctp.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_PROTOCOL, "SCTP", SCTP_CHUNK_NAME[chunk.Type]+" bundled with other chunks")
+			}
+			if chunk.Type == SCTP_CHUNK_INIT && sctp.verificationTag != 0 {
+				sctp.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_PROTOCOL, "SCTP", "INIT with non-zero verification tag")
+			}
+		case SCTP_CHUNK_ABORT:
+			sctp.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_SEQUENCE, "SCTP", "Association aborted")
+		case SCTP_CHUNK_ERROR:
+			sctp.AddExpert(resolver.SEVERITY_NOTE, resolver.GROUP_PROTOCOL, "SCTP", "Operation error reported")
+		default:
+			if SCTP_CHUNK_NAME[chunk.Type] == "" {
+				sctp.AddExpert(resolver.SEVERITY_NOTE, resolver.GROUP_PROTOCOL, "SCTP", "Unknown chunk type")
+			}
+		}
+	}
+	if sctp.verificationTag == 0 && (len(sctp.chunks) == 0 || sctp.chunks[0].Type != SCTP_CHUNK_INIT) {
+		sctp.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_PROTOCOL, "SCTP", "Zero verification tag outside INIT")
+	}
+
+	return sctp
+}
@@ -0,0 +1,646 @@
+package transportlayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// SCTP 块类型
+const (
+	SCTP_CHUNK_DATA              uint8 = 0
+	SCTP_CHUNK_INIT              uint8 = 1
+	SCTP_CHUNK_INIT_ACK          uint8 = 2
+	SCTP_CHUNK_SACK              uint8 = 3
+	SCTP_CHUNK_HEARTBEAT         uint8 = 4
+	SCTP_CHUNK_HEARTBEAT_ACK     uint8 = 5
+	SCTP_CHUNK_ABORT             uint8 = 6
+	SCTP_CHUNK_SHUTDOWN          uint8 = 7
+	SCTP_CHUNK_SHUTDOWN_ACK      uint8 = 8
+	SCTP_CHUNK_ERROR             uint8 = 9
+	SCTP_CHUNK_COOKIE_ECHO       uint8 = 10
+	SCTP_CHUNK_COOKIE_ACK        uint8 = 11
+	SCTP_CHUNK_ECNE              uint8 = 12
+	SCTP_CHUNK_CWR               uint8 = 13
+	SCTP_CHUNK_SHUTDOWN_COMPLETE uint8 = 14
+	SCTP_CHUNK_AUTH              uint8 = 15
+	SCTP_CHUNK_I_DATA            uint8 = 64
+	SCTP_CHUNK_ASCONF_ACK        uint8 = 128
+	SCTP_CHUNK_PKTDROP           uint8 = 129
+	SCTP_CHUNK_RE_CONFIG         uint8 = 130
+	SCTP_CHUNK_PAD               uint8 = 132
+	SCTP_CHUNK_FORWARD_TSN       uint8 = 192
+	SCTP_CHUNK_ASCONF            uint8 = 193
+	SCTP_CHUNK_I_FORWARD_TSN     uint8 = 194
+)
+
+var SCTP_CHUNK_NAME = map[uint8]string{
+	SCTP_CHUNK_DATA:              "DATA",
+	SCTP_CHUNK_INIT:              "INIT",
+	SCTP_CHUNK_INIT_ACK:          "INIT_ACK",
+	SCTP_CHUNK_SACK:              "SACK",
+	SCTP_CHUNK_HEARTBEAT:         "HEARTBEAT",
+	SCTP_CHUNK_HEARTBEAT_ACK:     "HEARTBEAT_ACK",
+	SCTP_CHUNK_ABORT:             "ABORT",
+	SCTP_CHUNK_SHUTDOWN:          "SHUTDOWN",
+	SCTP_CHUNK_SHUTDOWN_ACK:      "SHUTDOWN_ACK",
+	SCTP_CHUNK_ERROR:             "ERROR",
+	SCTP_CHUNK_COOKIE_ECHO:       "COOKIE_ECHO",
+	SCTP_CHUNK_COOKIE_ACK:        "COOKIE_ACK",
+	SCTP_CHUNK_ECNE:              "ECNE",
+	SCTP_CHUNK_CWR:               "CWR",
+	SCTP_CHUNK_SHUTDOWN_COMPLETE: "SHUTDOWN_COMPLETE",
+	SCTP_CHUNK_AUTH:              "AUTH",
+	SCTP_CHUNK_I_DATA:            "I_DATA",
+	SCTP_CHUNK_ASCONF_ACK:        "ASCONF_ACK",
+	SCTP_CHUNK_PKTDROP:           "PKTDROP",
+	SCTP_CHUNK_RE_CONFIG:         "RE_CONFIG",
+	SCTP_CHUNK_PAD:               "PAD",
+	SCTP_CHUNK_FORWARD_TSN:       "FORWARD_TSN",
+	SCTP_CHUNK_ASCONF:            "ASCONF",
+	SCTP_CHUNK_I_FORWARD_TSN:     "I_FORWARD_TSN",
+}
+
+// DATA 与 I_DATA 块的标志
+const (
+	SCTP_DATA_FLAG_END       uint8 = 0x1 // 用户消息的最后一个分片
+	SCTP_DATA_FLAG_BEGIN     uint8 = 0x2 // 用户消息的第一个分片
+	SCTP_DATA_FLAG_UNORDERED uint8 = 0x4 // 无序交付
+	SCTP_DATA_FLAG_IMMEDIATE uint8 = 0x8 // 要求立即发送 SACK（RFC 7053）
+)
+
+// ABORT 与 SHUTDOWN_COMPLETE 块的 T 标志：验证标签取自对端的验证标签
+const SCTP_FLAG_T uint8 = 0x1
+
+// 块中的参数类型
+const (
+	SCTP_PARAMETER_HEARTBEAT_INFO          uint16 = 0x0001
+	SCTP_PARAMETER_IPv4                    uint16 = 0x0005
+	SCTP_PARAMETER_IPv6                    uint16 = 0x0006
+	SCTP_PARAMETER_STATE_COOKIE            uint16 = 0x0007
+	SCTP_PARAMETER_UNRECOGNIZED            uint16 = 0x0008
+	SCTP_PARAMETER_COOKIE_PRESERVATIVE     uint16 = 0x0009
+	SCTP_PARAMETER_HOSTNAME                uint16 = 0x000B
+	SCTP_PARAMETER_SUPPORTED_ADDRESS_TYPES uint16 = 0x000C
+	SCTP_PARAMETER_OUTGOING_SSN_RESET      uint16 = 0x000D
+	SCTP_PARAMETER_INCOMING_SSN_RESET      uint16 = 0x000E
+	SCTP_PARAMETER_SSN_TSN_RESET           uint16 = 0x000F
+	SCTP_PARAMETER_RECONFIG_RESPONSE       uint16 = 0x0010
+	SCTP_PARAMETER_ADD_OUTGOING_STREAMS    uint16 = 0x0011
+	SCTP_PARAMETER_ADD_INCOMING_STREAMS    uint16 = 0x0012
+	SCTP_PARAMETER_ECN                     uint16 = 0x8000
+	SCTP_PARAMETER_RANDOM                  uint16 = 0x8002
+	SCTP_PARAMETER_CHUNK_LIST              uint16 = 0x8003
+	SCTP_PARAMETER_HMAC_ALGORITHMS         uint16 = 0x8004
+	SCTP_PARAMETER_PADDING                 uint16 = 0x8005
+	SCTP_PARAMETER_SUPPORTED_EXTENSIONS    uint16 = 0x8008
+	SCTP_PARAMETER_FORWARD_TSN             uint16 = 0xC000
+	SCTP_PARAMETER_ADD_IP                  uint16 = 0xC001
+	SCTP_PARAMETER_DELETE_IP               uint16 = 0xC002
+	SCTP_PARAMETER_ERROR_CAUSE_INDICATION  uint16 = 0xC003
+	SCTP_PARAMETER_SET_PRIMARY             uint16 = 0xC004
+	SCTP_PARAMETER_SUCCESS_INDICATION      uint16 = 0xC005
+	SCTP_PARAMETER_ADAPTATION_LAYER        uint16 = 0xC006
+)
+
+var SCTP_PARAMETER_NAME = map[uint16]string{
+	SCTP_PARAMETER_HEARTBEAT_INFO:          "Heartbeat info",
+	SCTP_PARAMETER_IPv4:                    "IPv4 address",
+	SCTP_PARAMETER_IPv6:                    "IPv6 address",
+	SCTP_PARAMETER_STATE_COOKIE:            "State cookie",
+	SCTP_PARAMETER_UNRECOGNIZED:            "Unrecognized parameter",
+	SCTP_PARAMETER_COOKIE_PRESERVATIVE:     "Cookie preservative",
+	SCTP_PARAMETER_HOSTNAME:                "Host name address",
+	SCTP_PARAMETER_SUPPORTED_ADDRESS_TYPES: "Supported address types",
+	SCTP_PARAMETER_OUTGOING_SSN_RESET:      "Outgoing SSN reset request",
+	SCTP_PARAMETER_INCOMING_SSN_RESET:      "Incoming SSN reset request",
+	SCTP_PARAMETER_SSN_TSN_RESET:           "SSN/TSN reset request",
+	SCTP_PARAMETER_RECONFIG_RESPONSE:       "Re-configuration response",
+	SCTP_PARAMETER_ADD_OUTGOING_STREAMS:    "Add outgoing streams request",
+	SCTP_PARAMETER_ADD_INCOMING_STREAMS:    "Add incoming streams request",
+	SCTP_PARAMETER_ECN:                     "ECN capable",
+	SCTP_PARAMETER_RANDOM:                  "Random",
+	SCTP_PARAMETER_CHUNK_LIST:              "Chunk list",
+	SCTP_PARAMETER_HMAC_ALGORITHMS:         "Requested HMAC algorithms",
+	SCTP_PARAMETER_PADDING:                 "Padding",
+	SCTP_PARAMETER_SUPPORTED_EXTENSIONS:    "Supported extensions",
+	SCTP_PARAMETER_FORWARD_TSN:             "Forward TSN supported",
+	SCTP_PARAMETER_ADD_IP:                  "Add IP address",
+	SCTP_PARAMETER_DELETE_IP:               "Delete IP address",
+	SCTP_PARAMETER_ERROR_CAUSE_INDICATION:  "Error cause indication",
+	SCTP_PARAMETER_SET_PRIMARY:             "Set primary address",
+	SCTP_PARAMETER_SUCCESS_INDICATION:      "Success indication",
+	SCTP_PARAMETER_ADAPTATION_LAYER:        "Adaptation layer indication",
+}
+
+// ABORT 与 ERROR 块中的错误原因
+const (
+	SCTP_CAUSE_INVALID_STREAM         uint16 = 0x0001
+	SCTP_CAUSE_MISSING_PARAMETER      uint16 = 0x0002
+	SCTP_CAUSE_STALE_COOKIE           uint16 = 0x0003
+	SCTP_CAUSE_OUT_OF_RESOURCE        uint16 = 0x0004
+	SCTP_CAUSE_UNRESOLVABLE_ADDRESS   uint16 = 0x0005
+	SCTP_CAUSE_UNRECOGNIZED_CHUNK     uint16 = 0x0006
+	SCTP_CAUSE_INVALID_PARAMETER      uint16 = 0x0007
+	SCTP_CAUSE_UNRECOGNIZED_PARAMETER uint16 = 0x0008
+	SCTP_CAUSE_NO_USER_DATA           uint16 = 0x0009
+	SCTP_CAUSE_COOKIE_WHILE_SHUTDOWN  uint16 = 0x000A
+	SCTP_CAUSE_RESTART_NEW_ADDRESSES  uint16 = 0x000B
+	SCTP_CAUSE_USER_ABORT             uint16 = 0x000C
+	SCTP_CAUSE_PROTOCOL_VIOLATION     uint16 = 0x000D
+	SCTP_CAUSE_DELETE_LAST_ADDRESS    uint16 = 0x00A0
+	SCTP_CAUSE_RESOURCE_SHORTAGE      uint16 = 0x00A1
+	SCTP_CAUSE_DELETE_SOURCE_ADDRESS  uint16 = 0x00A2
+	SCTP_CAUSE_ILLEGAL_ASCONF_ACK     uint16 = 0x00A3
+	SCTP_CAUSE_NO_AUTHORIZATION       uint16 = 0x00A4
+	SCTP_CAUSE_UNSUPPORTED_HMAC       uint16 = 0x0105
+)
+
+var SCTP_CAUSE_NAME = map[uint16]string{
+	SCTP_CAUSE_INVALID_STREAM:         "Invalid stream identifier",
+	SCTP_CAUSE_MISSING_PARAMETER:      "Missing mandatory parameter",
+	SCTP_CAUSE_STALE_COOKIE:           "Stale cookie",
+	SCTP_CAUSE_OUT_OF_RESOURCE:        "Out of resource",
+	SCTP_CAUSE_UNRESOLVABLE_ADDRESS:   "Unresolvable address",
+	SCTP_CAUSE_UNRECOGNIZED_CHUNK:     "Unrecognized chunk type",
+	SCTP_CAUSE_INVALID_PARAMETER:      "Invalid mandatory parameter",
+	SCTP_CAUSE_UNRECOGNIZED_PARAMETER: "Unrecognized parameters",
+	SCTP_CAUSE_NO_USER_DATA:           "No user data",
+	SCTP_CAUSE_COOKIE_WHILE_SHUTDOWN:  "Cookie received while shutting down",
+	SCTP_CAUSE_RESTART_NEW_ADDRESSES:  "Restart of an association with new addresses",
+	SCTP_CAUSE_USER_ABORT:             "User initiated abort",
+	SCTP_CAUSE_PROTOCOL_VIOLATION:     "Protocol violation",
+	SCTP_CAUSE_DELETE_LAST_ADDRESS:    "Request to delete last remaining IP address",
+	SCTP_CAUSE_RESOURCE_SHORTAGE:      "Operation refused due to resource shortage",
+	SCTP_CAUSE_DELETE_SOURCE_ADDRESS:  "Request to delete source IP address",
+	SCTP_CAUSE_ILLEGAL_ASCONF_ACK:     "Association aborted due to illegal ASCONF-ACK",
+	SCTP_CAUSE_NO_AUTHORIZATION:       "Request refused - no authorization",
+	SCTP_CAUSE_UNSUPPORTED_HMAC:       "Unsupported HMAC identifier",
+}
+
+// AUTH 块与请求的 HMAC 算法参数中的 HMAC 标识（RFC 4895）
+var SCTP_HMAC_NAME = map[uint16]string{
+	1: "SHA-1",
+	3: "SHA-256",
+}
+
+// 块或参数的名称，未知类型以十六进制表示
+func sctpChunkName(chunkType uint8) string {
+	if name := SCTP_CHUNK_NAME[chunkType]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("0x%02X", chunkType)
+}
+
+func sctpParameterName(parameterType uint16) string {
+	if name := SCTP_PARAMETER_NAME[parameterType]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", parameterType)
+}
+
+// 一个 SCTP 块
+type SCTPChunk struct {
+	Type      uint8
+	Flags     uint8
+	Length    uint16 // 长度，包含块头，不含填充
+	Value     []byte // 块头之后的数据
+	Malformed bool   // 长度字段非法或超出报文，Value 为剩余的全部数据
+}
+
+// DATA 块的内容
+type SCTPData struct {
+	TSN       uint32 // 传输序号
+	Stream    uint16 // 流标识
+	SSN       uint16 // 流序号
+	PPID      uint32 // 载荷协议标识
+	Unordered bool
+	Begin     bool   // 用户消息的第一个分片
+	End       bool   // 用户消息的最后一个分片
+	Immediate bool   // 要求立即发送 SACK
+	UserData  []byte // 用户数据
+}
+
+// 是否为未分片的完整用户消息
+func (data *SCTPData) Complete() bool {
+	return data.Begin && data.End
+}
+
+// 分片位置的可读形式
+func (data *SCTPData) Position() string {
+	switch {
+	case data.Begin && data.End:
+		return "unfragmented"
+	case data.Begin:
+		return "first fragment"
+	case data.End:
+		return "last fragment"
+	}
+	return "middle fragment"
+}
+
+// DATA 块的内容，不是格式正确的 DATA 块时返回 false
+func (chunk *SCTPChunk) Data() (SCTPData, bool) {
+	if chunk.Type != SCTP_CHUNK_DATA || chunk.Malformed || len(chunk.Value) < 12 {
+		return SCTPData{}, false
+	}
+	return SCTPData{
+		TSN:       utils.ExtractUint32BE(chunk.Value, 0),
+		Stream:    utils.ExtractUint16BE(chunk.Value, 4),
+		SSN:       utils.ExtractUint16BE(chunk.Value, 6),
+		PPID:      utils.ExtractUint32BE(chunk.Value, 8),
+		Unordered: chunk.Flags&SCTP_DATA_FLAG_UNORDERED != 0,
+		Begin:     chunk.Flags&SCTP_DATA_FLAG_BEGIN != 0,
+		End:       chunk.Flags&SCTP_DATA_FLAG_END != 0,
+		Immediate: chunk.Flags&SCTP_DATA_FLAG_IMMEDIATE != 0,
+		UserData:  chunk.Value[12:],
+	}, true
+}
+
+// 块中的参数，没有参数的块返回 nil
+func (chunk *SCTPChunk) Parameters() []SCTPParameter {
+	if chunk.Malformed {
+		return nil
+	}
+	offset := 0
+	switch chunk.Type {
+	case SCTP_CHUNK_INIT, SCTP_CHUNK_INIT_ACK:
+		offset = 16
+	case SCTP_CHUNK_ASCONF, SCTP_CHUNK_ASCONF_ACK:
+		offset = 4
+	case SCTP_CHUNK_HEARTBEAT, SCTP_CHUNK_HEARTBEAT_ACK, SCTP_CHUNK_RE_CONFIG:
+	default:
+		return nil
+	}
+	if len(chunk.Value) <= offset {
+		return nil
+	}
+	return ParseSCTPParameters(chunk.Value[offset:])
+}
+
+// ABORT 与 ERROR 块中的错误原因，其他块返回 nil
+func (chunk *SCTPChunk) Causes() []SCTPErrorCause {
+	if chunk.Malformed || (chunk.Type != SCTP_CHUNK_ABORT && chunk.Type != SCTP_CHUNK_ERROR) {
+		return nil
+	}
+	causes := []SCTPErrorCause{}
+	for _, parameter := range ParseSCTPParameters(chunk.Value) {
+		causes = append(causes, SCTPErrorCause(parameter))
+	}
+	return causes
+}
+
+// 可读形式，不含块类型，参数与错误原因另行列出
+func (chunk *SCTPChunk) ToString() string {
+	if chunk.Malformed {
+		return fmt.Sprintf("length %d (malformed) %s", chunk.Length, strings.ToUpper(hex.EncodeToString(chunk.Value)))
+	}
+	value := chunk.Value
+	switch chunk.Type {
+	case SCTP_CHUNK_DATA:
+		if data, ok := chunk.Data(); ok {
+			text := fmt.Sprintf("TSN %d, stream %d, SSN %d, PPID %s, %s, %d bytes",
+				data.TSN, data.Stream, data.SSN, SCTPPPIDString(data.PPID), data.Position(), len(data.UserData))
+			if data.Unordered {
+				text += ", unordered"
+			}
+			if data.Immediate {
+				text += ", SACK immediately"
+			}
+			return text
+		}
+	case SCTP_CHUNK_I_DATA:
+		if len(value) >= 16 {
+			text := fmt.Sprintf("TSN %d, stream %d, MID %d, ", utils.ExtractUint32BE(value, 0), utils.ExtractUint16BE(value, 4), utils.ExtractUint32BE(value, 8))
+			// 第一个分片的该字段为载荷协议标识，其余分片为分片序号
+			if chunk.Flags&SCTP_DATA_FLAG_BEGIN != 0 {
+				text += "PPID " + SCTPPPIDString(utils.ExtractUint32BE(value, 12))
+			} else {
+				text += "FSN " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 12)), 10)
+			}
+			position := SCTPData{Begin: chunk.Flags&SCTP_DATA_FLAG_BEGIN != 0, End: chunk.Flags&SCTP_DATA_FLAG_END != 0}
+			text += fmt.Sprintf(", %s, %d bytes", position.Position(), len(value)-16)
+			if chunk.Flags&SCTP_DATA_FLAG_UNORDERED != 0 {
+				text += ", unordered"
+			}
+			return text
+		}
+	case SCTP_CHUNK_INIT, SCTP_CHUNK_INIT_ACK:
+		if len(value) >= 16 {
+			return fmt.Sprintf("initiate tag 0x%08X, a_rwnd %d, outbound streams %d, inbound streams %d, initial TSN %d",
+				utils.ExtractUint32BE(value, 0), utils.ExtractUint32BE(value, 4), utils.ExtractUint16BE(value, 8),
+				utils.ExtractUint16BE(value, 10), utils.ExtractUint32BE(value, 12))
+		}
+	case SCTP_CHUNK_SACK:
+		if text, ok := sackString(value); ok {
+			return text
+		}
+	case SCTP_CHUNK_SHUTDOWN:
+		if len(value) == 4 {
+			return "cumulative TSN ack " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10)
+		}
+	case SCTP_CHUNK_ECNE, SCTP_CHUNK_CWR:
+		if len(value) == 4 {
+			return "lowest TSN " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10)
+		}
+	case SCTP_CHUNK_ABORT, SCTP_CHUNK_SHUTDOWN_COMPLETE:
+		if chunk.Flags&SCTP_FLAG_T != 0 {
+			return "T bit set (verification tag reflected)"
+		}
+		return ""
+	case SCTP_CHUNK_HEARTBEAT, SCTP_CHUNK_HEARTBEAT_ACK, SCTP_CHUNK_ERROR, SCTP_CHUNK_SHUTDOWN_ACK, SCTP_CHUNK_COOKIE_ACK,
+		SCTP_CHUNK_RE_CONFIG:
+		return ""
+	case SCTP_CHUNK_COOKIE_ECHO:
+		return "cookie " + strconv.Itoa(len(value)) + " bytes"
+	case SCTP_CHUNK_AUTH:
+		if len(value) >= 4 {
+			hmac := utils.ExtractUint16BE(value, 2)
+			name := SCTP_HMAC_NAME[hmac]
+			if name == "" {
+				name = "Unknown"
+			}
+			return fmt.Sprintf("shared key %d, HMAC %d (%s), %s",
+				utils.ExtractUint16BE(value, 0), hmac, name, strings.ToUpper(hex.EncodeToString(value[4:])))
+		}
+	case SCTP_CHUNK_ASCONF, SCTP_CHUNK_ASCONF_ACK:
+		if len(value) >= 4 {
+			return "serial number " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10)
+		}
+	case SCTP_CHUNK_PAD:
+		return strconv.Itoa(len(value)) + " bytes"
+	case SCTP_CHUNK_FORWARD_TSN:
+		if len(value) >= 4 && len(value)%4 == 0 {
+			text := "new cumulative TSN " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10)
+			for offset := 4; offset+4 <= len(value); offset += 4 {
+				text += fmt.Sprintf(", stream %d SSN %d", utils.ExtractUint16BE(value, offset), utils.ExtractUint16BE(value, offset+2))
+			}
+			return text
+		}
+	case SCTP_CHUNK_I_FORWARD_TSN:
+		if len(value) >= 4 && (len(value)-4)%8 == 0 {
+			text := "new cumulative TSN " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10)
+			for offset := 4; offset+8 <= len(value); offset += 8 {
+				text += fmt.Sprintf(", stream %d MID %d", utils.ExtractUint16BE(value, offset), utils.ExtractUint32BE(value, offset+4))
+				if value[offset+3]&0x1 != 0 {
+					text += " (unordered)"
+				}
+			}
+			return text
+		}
+	}
+	if len(value) == 0 {
+		return "length " + strconv.Itoa(int(chunk.Length))
+	}
+	return "length " + strconv.Itoa(int(chunk.Length)) + ", " + strings.ToUpper(hex.EncodeToString(value))
+}
+
+// SACK 块：间隔块以相对累计确认序号的偏移表示，输出为绝对的 TSN 范围
+func sackString(value []byte) (string, bool) {
+	if len(value) < 12 {
+		return "", false
+	}
+	cumulative := utils.ExtractUint32BE(value, 0)
+	gaps := int(utils.ExtractUint16BE(value, 8))
+	duplicates := int(utils.ExtractUint16BE(value, 10))
+	if 12+4*gaps+4*duplicates != len(value) {
+		return "", false
+	}
+	builder := new(strings.Builder)
+	builder.WriteString(fmt.Sprintf("cumulative TSN ack %d, a_rwnd %d", cumulative, utils.ExtractUint32BE(value, 4)))
+	if gaps != 0 {
+		blocks := []string{}
+		for i := range gaps {
+			start := cumulative + uint32(utils.ExtractUint16BE(value, 12+4*i))
+			end := cumulative + uint32(utils.ExtractUint16BE(value, 14+4*i))
+			blocks = append(blocks, fmt.Sprintf("%d-%d", start, end))
+		}
+		builder.WriteString(", gap blocks ")
+		builder.WriteString(strings.Join(blocks, ", "))
+	}
+	if duplicates != 0 {
+		tsns := []string{}
+		for i := range duplicates {
+			tsns = append(tsns, strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 12+4*gaps+4*i)), 10))
+		}
+		builder.WriteString(", duplicate TSNs ")
+		builder.WriteString(strings.Join(tsns, ", "))
+	}
+	return builder.String(), true
+}
+
+// 块中的一个参数
+type SCTPParameter struct {
+	Type      uint16
+	Length    uint16 // 长度，包含类型与长度字段，不含填充
+	Value     []byte // 类型与长度之后的数据
+	Malformed bool   // 长度字段非法或超出块，Value 为剩余的全部数据
+}
+
+// 可读形式，不含参数名称
+func (parameter *SCTPParameter) ToString() string {
+	if parameter.Malformed {
+		return fmt.Sprintf("length %d (malformed) %s", parameter.Length, strings.ToUpper(hex.EncodeToString(parameter.Value)))
+	}
+	value := parameter.Value
+	switch parameter.Type {
+	case SCTP_PARAMETER_IPv4:
+		if len(value) == 4 {
+			address := types.IPv4{}
+			address.Parse([4]byte(value))
+			return address.ToString()
+		}
+	case SCTP_PARAMETER_IPv6:
+		if len(value) == 16 {
+			address := types.IPv6{}
+			address.Parse([16]byte(value))
+			return address.ToString()
+		}
+	case SCTP_PARAMETER_HEARTBEAT_INFO, SCTP_PARAMETER_STATE_COOKIE, SCTP_PARAMETER_PADDING:
+		return strconv.Itoa(len(value)) + " bytes"
+	case SCTP_PARAMETER_COOKIE_PRESERVATIVE:
+		if len(value) == 4 {
+			return strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10) + " ms"
+		}
+	case SCTP_PARAMETER_HOSTNAME:
+		return strings.TrimRight(string(value), "\x00")
+	case SCTP_PARAMETER_SUPPORTED_ADDRESS_TYPES:
+		names := []string{}
+		for offset := 0; offset+2 <= len(value); offset += 2 {
+			names = append(names, sctpParameterName(utils.ExtractUint16BE(value, offset)))
+		}
+		return strings.Join(names, ", ")
+	case SCTP_PARAMETER_SUPPORTED_EXTENSIONS, SCTP_PARAMETER_CHUNK_LIST:
+		names := []string{}
+		for _, chunkType := range value {
+			names = append(names, sctpChunkName(chunkType))
+		}
+		return strings.Join(names, ", ")
+	case SCTP_PARAMETER_HMAC_ALGORITHMS:
+		names := []string{}
+		for offset := 0; offset+2 <= len(value); offset += 2 {
+			hmac := utils.ExtractUint16BE(value, offset)
+			if name := SCTP_HMAC_NAME[hmac]; name != "" {
+				names = append(names, name)
+			} else {
+				names = append(names, strconv.Itoa(int(hmac)))
+			}
+		}
+		return strings.Join(names, ", ")
+	case SCTP_PARAMETER_ECN, SCTP_PARAMETER_FORWARD_TSN:
+		if len(value) == 0 {
+			return "yes"
+		}
+	case SCTP_PARAMETER_UNRECOGNIZED:
+		if len(value) >= 4 {
+			return "type " + sctpParameterName(utils.ExtractUint16BE(value, 0))
+		}
+	case SCTP_PARAMETER_ADAPTATION_LAYER:
+		if len(value) == 4 {
+			return fmt.Sprintf("0x%08X", utils.ExtractUint32BE(value, 0))
+		}
+	case SCTP_PARAMETER_ADD_IP, SCTP_PARAMETER_DELETE_IP, SCTP_PARAMETER_SET_PRIMARY, SCTP_PARAMETER_ERROR_CAUSE_INDICATION,
+		SCTP_PARAMETER_SUCCESS_INDICATION:
+		// 以关联标识开头，其后为地址参数或错误原因
+		if len(value) >= 4 {
+			text := fmt.Sprintf("correlation ID 0x%08X", utils.ExtractUint32BE(value, 0))
+			for _, inner := range ParseSCTPParameters(value[4:]) {
+				if parameter.Type == SCTP_PARAMETER_ERROR_CAUSE_INDICATION {
+					cause := SCTPErrorCause(inner)
+					text += ", " + cause.Name()
+				} else {
+					text += ", " + sctpParameterName(inner.Type) + " " + inner.ToString()
+				}
+			}
+			return text
+		}
+	case SCTP_PARAMETER_OUTGOING_SSN_RESET, SCTP_PARAMETER_INCOMING_SSN_RESET, SCTP_PARAMETER_SSN_TSN_RESET,
+		SCTP_PARAMETER_ADD_OUTGOING_STREAMS, SCTP_PARAMETER_ADD_INCOMING_STREAMS:
+		if len(value) >= 4 {
+			return "request sequence " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10)
+		}
+	case SCTP_PARAMETER_RECONFIG_RESPONSE:
+		if len(value) >= 8 {
+			return fmt.Sprintf("response sequence %d, result %d", utils.ExtractUint32BE(value, 0), utils.ExtractUint32BE(value, 4))
+		}
+	}
+	if len(value) == 0 {
+		return "length " + strconv.Itoa(int(parameter.Length))
+	}
+	return strings.ToUpper(hex.EncodeToString(value))
+}
+
+// ABORT 与 ERROR 块中的错误原因，格式与参数相同
+type SCTPErrorCause SCTPParameter
+
+func (cause *SCTPErrorCause) Name() string {
+	if name := SCTP_CAUSE_NAME[cause.Type]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("Unknown cause 0x%04X", cause.Type)
+}
+
+// 可读形式，不含原因名称
+func (cause *SCTPErrorCause) ToString() string {
+	if cause.Malformed {
+		return fmt.Sprintf("length %d (malformed) %s", cause.Length, strings.ToUpper(hex.EncodeToString(cause.Value)))
+	}
+	value := cause.Value
+	switch cause.Type {
+	case SCTP_CAUSE_INVALID_STREAM:
+		if len(value) == 4 {
+			return "stream " + strconv.Itoa(int(utils.ExtractUint16BE(value, 0)))
+		}
+	case SCTP_CAUSE_MISSING_PARAMETER:
+		if len(value) >= 4 {
+			names := []string{}
+			for offset := 4; offset+2 <= len(value); offset += 2 {
+				names = append(names, sctpParameterName(utils.ExtractUint16BE(value, offset)))
+			}
+			return strings.Join(names, ", ")
+		}
+	case SCTP_CAUSE_STALE_COOKIE:
+		if len(value) == 4 {
+			return "staleness " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10) + " us"
+		}
+	case SCTP_CAUSE_NO_USER_DATA:
+		if len(value) == 4 {
+			return "TSN " + strconv.FormatUint(uint64(utils.ExtractUint32BE(value, 0)), 10)
+		}
+	case SCTP_CAUSE_UNRECOGNIZED_CHUNK:
+		if len(value) >= 1 {
+			return "chunk " + sctpChunkName(value[0])
+		}
+	case SCTP_CAUSE_UNRESOLVABLE_ADDRESS, SCTP_CAUSE_UNRECOGNIZED_PARAMETER, SCTP_CAUSE_RESTART_NEW_ADDRESSES,
+		SCTP_CAUSE_DELETE_LAST_ADDRESS, SCTP_CAUSE_RESOURCE_SHORTAGE, SCTP_CAUSE_DELETE_SOURCE_ADDRESS:
+		// 原因中带有出错的参数
+		parameters := []string{}
+		for _, parameter := range ParseSCTPParameters(value) {
+			parameters = append(parameters, sctpParameterName(parameter.Type)+" "+parameter.ToString())
+		}
+		if len(parameters) != 0 {
+			return strings.Join(parameters, ", ")
+		}
+	case SCTP_CAUSE_USER_ABORT, SCTP_CAUSE_PROTOCOL_VIOLATION:
+		// 附加信息通常为说明文字
+		if text := strings.TrimRight(string(value), "\x00"); text != "" && strings.IndexFunc(text, func(r rune) bool { return r < 0x20 || r > 0x7E }) < 0 {
+			return strconv.Quote(text)
+		}
+	}
+	if len(value) == 0 {
+		return ""
+	}
+	return strings.ToUpper(hex.EncodeToString(value))
+}
+
+// 解析参数或错误原因，各项填充到 4 字节对齐；长度有误的项吸收剩余的全部数据
+func ParseSCTPParameters(data []byte) []SCTPParameter {
+	result := []SCTPParameter{}
+	for offset := 0; offset+4 <= len(data); {
+		parameter := SCTPParameter{
+			Type:   utils.ExtractUint16BE(data, offset),
+			Length: utils.ExtractUint16BE(data, offset+2),
+		}
+		length := int(parameter.Length)
+		if length < 4 || offset+length > len(data) {
+			parameter.Malformed = true
+			parameter.Value = data[offset+4:]
+			result = append(result, parameter)
+			break
+		}
+		parameter.Value = data[offset+4 : offset+length]
+		result = append(result, parameter)
+		offset += (length + 3) &^ 3
+	}
+	return result
+}
+
+// 解析报文中的块，各块填充到 4 字节对齐；长度有误的块吸收剩余的全部数据
+func ParseSCTPChunks(data []byte) []SCTPChunk {
+	result := []SCTPChunk{}
+	for offset := 0; offset < len(data); {
+		if offset+4 > len(data) {
+			result = append(result, SCTPChunk{Type: data[offset], Value: data[offset:], Malformed: true})
+			break
+		}
+		chunk := SCTPChunk{
+			Type:   data[offset],
+			Flags:  data[offset+1],
+			Length: utils.ExtractUint16BE(data, offset+2),
+		}
+		length := int(chunk.Length)
+		if length < 4 || offset+length > len(data) {
+			chunk.Malformed = true
+			chunk.Value = data[offset+4:]
+			result = append(result, chunk)
+			break
+		}
+		chunk.Value = data[offset+4 : offset+length]
+		result = append(result, chunk)
+		offset += (length + 3) &^ 3
+	}
+	return result
+}
@@ -7,30 +7,82 @@ import (
 	"packet-inspector/utils"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// 按连接分析得到的 TCP 报文问题
+const (
+	TCP_ANALYSIS_RETRANSMISSION      uint8 = 0
+	TCP_ANALYSIS_FAST_RETRANSMISSION uint8 = 1
+	TCP_ANALYSIS_OUT_OF_ORDER        uint8 = 2
+	TCP_ANALYSIS_DUPLICATE_ACK       uint8 = 3
+	TCP_ANALYSIS_ZERO_WINDOW         uint8 = 4
+	TCP_ANALYSIS_WINDOW_FULL         uint8 = 5
+	TCP_ANALYSIS_KEEP_ALIVE          uint8 = 6
+	TCP_ANALYSIS_RST_AFTER_DATA      uint8 = 7
+)
+
+var TCP_ANALYSIS_NAME = map[uint8]string{
+	TCP_ANALYSIS_RETRANSMISSION:      "Retransmission",
+	TCP_ANALYSIS_FAST_RETRANSMISSION: "Fast retransmission",
+	TCP_ANALYSIS_OUT_OF_ORDER:        "Out-of-order segment",
+	TCP_ANALYSIS_DUPLICATE_ACK:       "Duplicate ACK",
+	TCP_ANALYSIS_ZERO_WINDOW:         "Zero window",
+	TCP_ANALYSIS_WINDOW_FULL:         "Window full",
+	TCP_ANALYSIS_KEEP_ALIVE:          "Keep-alive",
+	TCP_ANALYSIS_RST_AFTER_DATA:      "RST after data",
+}
+
+// 分析结果作为专家信息时的严重程度
+var TCP_ANALYSIS_SEVERITY = map[uint8]resolver.Severity{
+	TCP_ANALYSIS_RETRANSMISSION:      resolver.SEVERITY_NOTE,
+	TCP_ANALYSIS_FAST_RETRANSMISSION: resolver.SEVERITY_NOTE,
+	TCP_ANALYSIS_OUT_OF_ORDER:        resolver.SEVERITY_WARN,
+	TCP_ANALYSIS_DUPLICATE_ACK:       resolver.SEVERITY_NOTE,
+	TCP_ANALYSIS_ZERO_WINDOW:         resolver.SEVERITY_WARN,
+	TCP_ANALYSIS_WINDOW_FULL:         resolver.SEVERITY_WARN,
+	TCP_ANALYSIS_KEEP_ALIVE:          resolver.SEVERITY_NOTE,
+	TCP_ANALYSIS_RST_AFTER_DATA:      resolver.SEVERITY_WARN,
+}
+
+// 分析结果对应的字段名，以 "tcp.analysis." 为前缀
+var TCP_ANALYSIS_FIELD = map[uint8]string{
+	TCP_ANALYSIS_RETRANSMISSION:      "retransmission",
+	TCP_ANALYSIS_FAST_RETRANSMISSION: "fast_retransmission",
+	TCP_ANALYSIS_OUT_OF_ORDER:        "out_of_order",
+	TCP_ANALYSIS_DUPLICATE_ACK:       "duplicate_ack",
+	TCP_ANALYSIS_ZERO_WINDOW:         "zero_window",
+	TCP_ANALYSIS_WINDOW_FULL:         "window_full",
+	TCP_ANALYSIS_KEEP_ALIVE:          "keep_alive",
+	TCP_ANALYSIS_RST_AFTER_DATA:      "rst_after_data",
+}
+
 type TCP struct {
 	resolver.IPacket
-	raw            []byte // 原始报文
-	source         uint16 // 源端口
-	destination    uint16 // 目的端口
-	sequence       uint32 // 序号字段
-	acknowledgment uint32 // 确认序号
-	dataOffset     uint8  // 数据偏移（首部长度），单位 4 字节（4 bit）
-	reserved       uint8  // 保留位，全 0（4 bit）
-	cwr            bool   // 拥塞窗口减少标识
-	ece            bool   // ECN 回声标识
-	urg            bool   // 紧急指针有效标识
-	ack            bool   // 确认序号有效标识
-	psh            bool   // 尽快交付标识
-	rst            bool   // 重连标识
-	syn            bool   // 同步序号标识
-	fin            bool   // 结束标识
-	window         uint16 // 窗口
-	checksum       uint16 // 校验和
-	urgentPointer  uint16 // 紧急数据长度，仅在 urg 置 1 时有效
-	options        []byte // 选项字段
-	payload        []byte // 载荷
+	resolver.ExpertInfo
+	raw            []byte        // 原始报文
+	source         uint16        // 源端口
+	destination    uint16        // 目的端口
+	sequence       uint32        // 序号字段
+	acknowledgment uint32        // 确认序号
+	dataOffset     uint8         // 数据偏移（首部长度），单位 4 字节（4 bit）
+	reserved       uint8         // 保留位，全 0（4 bit）
+	cwr            bool          // 拥塞窗口减少标识
+	ece            bool          // ECN 回声标识
+	urg            bool          // 紧急指针有效标识
+	ack            bool          // 确认序号有效标识
+	psh            bool          // 尽快交付标识
+	rst            bool          // 重连标识
+	syn            bool          // 同步序号标识
+	fin            bool          // 结束标识
+	window         uint16        // 窗口
+	checksum       uint16        // 校验和
+	urgentPointer  uint16        // 紧急数据长度，仅在 urg 置 1 时有效
+	options        []TCPOption   // 选项字段
+	payload        []byte        // 载荷
+	scale          int           // 窗口扩大因子（移位数），由握手中的窗口扩大选项决定，-1 表示未知
+	analysis       []uint8       // 按连接分析得到的问题
+	rtt            time.Duration // 握手往返时间，仅在完成握手的 ACK 报文中非 0
 }
 
 func (tcp *TCP) Raw() []byte {
@@ -41,6 +93,140 @@ func (tcp *TCP) Hex() string {
 	return strings.ToUpper(hex.EncodeToString(tcp.raw))
 }
 
+func (tcp *TCP) Payload() []byte {
+	return tcp.payload
+}
+
+func (tcp *TCP) Sequence() uint32 {
+	return tcp.sequence
+}
+
+func (tcp *TCP) Acknowledgment() uint32 {
+	return tcp.acknowledgment
+}
+
+func (tcp *TCP) Window() uint16 {
+	return tcp.window
+}
+
+func (tcp *TCP) SYN() bool {
+	return tcp.syn
+}
+
+func (tcp *TCP) ACK() bool {
+	return tcp.ack
+}
+
+func (tcp *TCP) FIN() bool {
+	return tcp.fin
+}
+
+func (tcp *TCP) RST() bool {
+	return tcp.rst
+}
+
+func (tcp *TCP) Options() []TCPOption {
+	return tcp.options
+}
+
+// 第一个指定类型且格式正确的选项
+func (tcp *TCP) option(kind uint8) *TCPOption {
+	for i := range tcp.options {
+		if tcp.options[i].Kind == kind && !tcp.options[i].Malformed {
+			return &tcp.options[i]
+		}
+	}
+	return nil
+}
+
+// 窗口扩大选项中的移位数，超过 14 时按 14 处理
+func (tcp *TCP) WindowScaleOption() (uint8, bool) {
+	option := tcp.option(TCP_OPTION_WINDOW_SCALE)
+	if option == nil || len(option.Data) != 1 {
+		return 0, false
+	}
+	return min(option.Data[0], TCP_MAX_WINDOW_SCALE), true
+}
+
+// 设置由握手得到的窗口扩大因子，握手中未协商窗口扩大时为 0
+func (tcp *TCP) SetWindowScale(shift int) {
+	tcp.scale = shift
+}
+
+// 窗口字段乘以窗口扩大因子后的实际窗口大小，SYN 报文的窗口不扩大，因子未知时返回 false
+func (tcp *TCP) EffectiveWindow() (uint32, bool) {
+	if tcp.syn {
+		return uint32(tcp.window), true
+	}
+	if tcp.scale < 0 {
+		return 0, false
+	}
+	return uint32(tcp.window) << tcp.scale, true
+}
+
+// 设置按连接分析得到的问题与握手往返时间，每个问题同时作为一条专家信息
+func (tcp *TCP) SetAnalysis(analysis []uint8, rtt time.Duration) {
+	tcp.analysis = analysis
+	tcp.rtt = rtt
+	for _, kind := range analysis {
+		tcp.AddExpert(TCP_ANALYSIS_SEVERITY[kind], resolver.GROUP_SEQUENCE, "TCP", TCP_ANALYSIS_NAME[kind])
+	}
+}
+
+func (tcp *TCP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "tcp.srcport", Value: strconv.Itoa(int(tcp.source))},
+		{Name: "tcp.dstport", Value: strconv.Itoa(int(tcp.destination))},
+		{Name: "tcp.seq", Value: strconv.FormatUint(uint64(tcp.sequence), 10)},
+		{Name: "tcp.ack", Value: strconv.FormatUint(uint64(tcp.acknowledgment), 10)},
+		{Name: "tcp.window", Value: strconv.Itoa(int(tcp.window))},
+		{Name: "tcp.len", Value: strconv.Itoa(len(tcp.payload))},
+	}
+	if window, ok := tcp.EffectiveWindow(); ok {
+		fields = append(fields, resolver.Field{Name: "tcp.window_size", Value: strconv.FormatUint(uint64(window), 10)})
+	}
+	for _, analysis := range tcp.analysis {
+		fields = append(fields, resolver.Field{Name: "tcp.analysis." + TCP_ANALYSIS_FIELD[analysis], Value: "1"})
+	}
+	if tcp.rtt != 0 {
+		fields = append(fields, resolver.Field{Name: "tcp.analysis.initial_rtt_us", Value: strconv.FormatInt(tcp.rtt.Microseconds(), 10)})
+	}
+	for _, option := range tcp.options {
+		if option.Kind == TCP_OPTION_NOP || option.Kind == TCP_OPTION_EOL {
+			continue
+		}
+		fields = append(fields, resolver.Field{Name: "tcp.option.kind", Value: strconv.Itoa(int(option.Kind))})
+		if option.Malformed {
+			continue
+		}
+		switch {
+		case option.Kind == TCP_OPTION_MSS && len(option.Data) == 2:
+			fields = append(fields, resolver.Field{Name: "tcp.option.mss", Value: strconv.Itoa(int(utils.ExtractUint16BE(option.Data, 0)))})
+		case option.Kind == TCP_OPTION_WINDOW_SCALE && len(option.Data) == 1:
+			fields = append(fields, resolver.Field{Name: "tcp.option.wscale", Value: strconv.Itoa(int(option.Data[0]))})
+		case option.Kind == TCP_OPTION_SACK_PERMITTED:
+			fields = append(fields, resolver.Field{Name: "tcp.option.sack_perm", Value: "1"})
+		case option.Kind == TCP_OPTION_SACK:
+			for _, block := range option.SACKBlocks() {
+				fields = append(fields,
+					resolver.Field{Name: "tcp.option.sack.le", Value: strconv.FormatUint(uint64(block.Left), 10)},
+					resolver.Field{Name: "tcp.option.sack.re", Value: strconv.FormatUint(uint64(block.Right), 10)})
+			}
+		case option.Kind == TCP_OPTION_TIMESTAMPS && len(option.Data) == 8:
+			fields = append(fields,
+				resolver.Field{Name: "tcp.option.tsval", Value: strconv.FormatUint(uint64(utils.ExtractUint32BE(option.Data, 0)), 10)},
+				resolver.Field{Name: "tcp.option.tsecr", Value: strconv.FormatUint(uint64(utils.ExtractUint32BE(option.Data, 4)), 10)})
+		case option.IsFastOpen():
+			fields = append(fields, resolver.Field{Name: "tcp.option.tfo", Value: strings.ToUpper(hex.EncodeToString(option.FastOpenCookie()))})
+		case option.Kind == TCP_OPTION_MPTCP:
+			if subtype, ok := option.MPTCPSubtype(); ok {
+				fields = append(fields, resolver.Field{Name: "tcp.option.mptcp.subtype", Value: strconv.Itoa(int(subtype))})
+			}
+		}
+	}
+	return fields
+}
+
 func (tcp *TCP) ToReadableString(indent int) string {
 	builder := new(strings.Builder)
 	tabs := make([]byte, indent)
@@ -158,6 +344,23 @@ func (tcp *TCP) ToReadableString(indent int) string {
 	builder.WriteString(fmt.Sprintf("0x%04X", tcp.window))
 	builder.WriteByte('\n')
 
+	builder.Write(tabs)
+	builder.WriteString("Calculated window size: ")
+	if window, ok := tcp.EffectiveWindow(); !ok {
+		builder.WriteString("unknown (window scale not seen in handshake)")
+	} else if tcp.syn {
+		builder.WriteString(strconv.FormatUint(uint64(window), 10))
+		builder.WriteString(" (not scaled in SYN segments)")
+	} else {
+		builder.WriteString(strconv.FormatUint(uint64(window), 10))
+		builder.WriteString(" (")
+		builder.WriteString(strconv.Itoa(int(tcp.window)))
+		builder.WriteString(" * ")
+		builder.WriteString(strconv.Itoa(1 << tcp.scale))
+		builder.WriteString(")")
+	}
+	builder.WriteByte('\n')
+
 	builder.Write(tabs)
 	builder.WriteString("Checksum: ")
 	builder.WriteString(fmt.Sprintf("0x%04X", tcp.checksum))
@@ -169,13 +372,49 @@ func (tcp *TCP) ToReadableString(indent int) string {
 	builder.WriteByte('\n')
 
 	builder.Write(tabs)
-	builder.WriteString("Options(HEX): ")
-	if tcp.options != nil {
-		builder.WriteString(strings.ToUpper(hex.EncodeToString(tcp.options)))
+	if len(tcp.options) != 0 {
+		builder.WriteString("Options: {\n")
+		for _, option := range tcp.options {
+			builder.Write(tabs)
+			builder.WriteByte('\t')
+			if name := TCP_OPTION_NAME[option.Kind]; name != "" {
+				builder.WriteString(name)
+			} else {
+				builder.WriteString("Unknown option " + strconv.Itoa(int(option.Kind)))
+			}
+			if option.IsFastOpen() && option.Kind != TCP_OPTION_FAST_OPEN {
+				builder.WriteString(" (TCP Fast Open)")
+			}
+			if option.Kind != TCP_OPTION_NOP && option.Kind != TCP_OPTION_EOL {
+				builder.WriteString(": ")
+				builder.WriteString(option.ToString())
+			}
+			builder.WriteByte('\n')
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
 	} else {
-		builder.WriteString("(No options)")
+		builder.WriteString("Options: (No options)\n")
+	}
+
+	if len(tcp.analysis) != 0 || tcp.rtt != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Analysis: {\n")
+		for _, analysis := range tcp.analysis {
+			builder.Write(tabs)
+			builder.WriteByte('\t')
+			builder.WriteString(TCP_ANALYSIS_NAME[analysis])
+			builder.WriteByte('\n')
+		}
+		if tcp.rtt != 0 {
+			builder.Write(tabs)
+			builder.WriteString("\tInitial RTT: ")
+			builder.WriteString(tcp.rtt.String())
+			builder.WriteByte('\n')
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
 	}
-	builder.WriteByte('\n')
 
 	builder.Write(tabs)
 	builder.WriteString("Payload: ")
@@ -196,6 +435,7 @@ func TCPResolve(packet []byte) resolver.IPacket {
 	if length < 20 {
 		return nil
 	}
+	tcp.scale = -1
 
 	tcp.source = utils.ExtractUint16BE(packet, 0)
 	tcp.destination = utils.ExtractUint16BE(packet, 2)
@@ -214,16 +454,28 @@ func TCPResolve(packet []byte) resolver.IPacket {
 	tcp.window = utils.ExtractUint16BE(packet, 14)
 	tcp.checksum = utils.ExtractUint16BE(packet, 16)
 	tcp.urgentPointer = utils.ExtractUint16BE(packet, 18)
-	if tcp.dataOffset > 5 {
-		tcp.options = make([]byte, tcp.dataOffset*4-20)
-		copy(tcp.options, packet[20:tcp.dataOffset*4])
+	headerLength := int(tcp.dataOffset) * 4
+	if headerLength < 20 {
+		tcp.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "TCP", "Data offset less than minimum header length")
+		headerLength = 20
+	} else if headerLength > length {
+		tcp.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "TCP", "Data offset exceeds captured data")
+	}
+	if headerLength > 20 {
+		tcp.options = ParseTCPOptions(packet[20:min(headerLength, length)])
 	} else {
 		tcp.options = nil
 	}
+	for _, option := range tcp.options {
+		if option.Malformed {
+			tcp.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_MALFORMED, "TCP", "Malformed option")
+			break
+		}
+	}
 
-	if int(tcp.dataOffset)*4 < length {
-		tcp.payload = make([]byte, length-int(tcp.dataOffset)*4)
-		copy(tcp.payload, packet[tcp.dataOffset*4:length])
+	if headerLength < length {
+		tcp.payload = make([]byte, length-headerLength)
+		copy(tcp.payload, packet[headerLength:length])
 	} else {
 		tcp.payload = nil
 	}
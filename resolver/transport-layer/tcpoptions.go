@@ -0,0 +1,315 @@
+package transportlayer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/types"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// TCP 选项类型
+const (
+	TCP_OPTION_EOL            uint8 = 0
+	TCP_OPTION_NOP            uint8 = 1
+	TCP_OPTION_MSS            uint8 = 2
+	TCP_OPTION_WINDOW_SCALE   uint8 = 3
+	TCP_OPTION_SACK_PERMITTED uint8 = 4
+	TCP_OPTION_SACK           uint8 = 5
+	TCP_OPTION_TIMESTAMPS     uint8 = 8
+	TCP_OPTION_MD5            uint8 = 19
+	TCP_OPTION_USER_TIMEOUT   uint8 = 28
+	TCP_OPTION_AO             uint8 = 29
+	TCP_OPTION_MPTCP          uint8 = 30
+	TCP_OPTION_FAST_OPEN      uint8 = 34
+	TCP_OPTION_EXPERIMENT_1   uint8 = 253
+	TCP_OPTION_EXPERIMENT_2   uint8 = 254
+)
+
+var TCP_OPTION_NAME = map[uint8]string{
+	TCP_OPTION_EOL:            "End of option list",
+	TCP_OPTION_NOP:            "No-operation",
+	TCP_OPTION_MSS:            "Maximum segment size",
+	TCP_OPTION_WINDOW_SCALE:   "Window scale",
+	TCP_OPTION_SACK_PERMITTED: "SACK permitted",
+	TCP_OPTION_SACK:           "SACK",
+	TCP_OPTION_TIMESTAMPS:     "Timestamps",
+	TCP_OPTION_MD5:            "MD5 signature",
+	TCP_OPTION_USER_TIMEOUT:   "User timeout",
+	TCP_OPTION_AO:             "TCP-AO",
+	TCP_OPTION_MPTCP:          "Multipath TCP",
+	TCP_OPTION_FAST_OPEN:      "TCP Fast Open",
+	TCP_OPTION_EXPERIMENT_1:   "Experimental",
+	TCP_OPTION_EXPERIMENT_2:   "Experimental",
+}
+
+// 实验选项中 TCP Fast Open 的标识（RFC 7413 之前的实现使用）
+const TCP_EXPERIMENT_FAST_OPEN uint16 = 0xF989
+
+// 窗口扩大因子的最大值（RFC 7323）
+const TCP_MAX_WINDOW_SCALE uint8 = 14
+
+// MPTCP 选项子类型
+const (
+	MPTCP_MP_CAPABLE   uint8 = 0
+	MPTCP_MP_JOIN      uint8 = 1
+	MPTCP_DSS          uint8 = 2
+	MPTCP_ADD_ADDR     uint8 = 3
+	MPTCP_REMOVE_ADDR  uint8 = 4
+	MPTCP_MP_PRIO      uint8 = 5
+	MPTCP_MP_FAIL      uint8 = 6
+	MPTCP_MP_FASTCLOSE uint8 = 7
+	MPTCP_MP_TCPRST    uint8 = 8
+)
+
+var MPTCP_SUBTYPE_NAME = map[uint8]string{
+	MPTCP_MP_CAPABLE:   "MP_CAPABLE",
+	MPTCP_MP_JOIN:      "MP_JOIN",
+	MPTCP_DSS:          "DSS",
+	MPTCP_ADD_ADDR:     "ADD_ADDR",
+	MPTCP_REMOVE_ADDR:  "REMOVE_ADDR",
+	MPTCP_MP_PRIO:      "MP_PRIO",
+	MPTCP_MP_FAIL:      "MP_FAIL",
+	MPTCP_MP_FASTCLOSE: "MP_FASTCLOSE",
+	MPTCP_MP_TCPRST:    "MP_TCPRST",
+}
+
+// 一个 TCP 选项
+type TCPOption struct {
+	Kind      uint8  // 类型
+	Length    uint8  // 长度，包含类型与长度字段；EOL 与 NOP 为 1
+	Data      []byte // 数据，不含类型与长度字段
+	Malformed bool   // 长度字段非法或超出选项区域
+}
+
+// SACK 块，左边界含、右边界不含
+type SACKBlock struct {
+	Left  uint32
+	Right uint32
+}
+
+// MPTCP 子类型，非 MPTCP 选项返回 false
+func (option *TCPOption) MPTCPSubtype() (uint8, bool) {
+	if option.Kind != TCP_OPTION_MPTCP || len(option.Data) < 1 {
+		return 0, false
+	}
+	return option.Data[0] >> 4, true
+}
+
+// 是否为 TCP Fast Open 选项（含实验选项的形式）
+func (option *TCPOption) IsFastOpen() bool {
+	if option.Kind == TCP_OPTION_FAST_OPEN {
+		return true
+	}
+	return option.Kind == TCP_OPTION_EXPERIMENT_2 && len(option.Data) >= 2 && utils.ExtractUint16BE(option.Data, 0) == TCP_EXPERIMENT_FAST_OPEN
+}
+
+// Fast Open Cookie，为空表示请求 Cookie
+func (option *TCPOption) FastOpenCookie() []byte {
+	if option.Kind == TCP_OPTION_EXPERIMENT_2 {
+		return option.Data[2:]
+	}
+	return option.Data
+}
+
+// SACK 块
+func (option *TCPOption) SACKBlocks() []SACKBlock {
+	blocks := []SACKBlock{}
+	if option.Kind != TCP_OPTION_SACK {
+		return blocks
+	}
+	for offset := 0; offset+8 <= len(option.Data); offset += 8 {
+		blocks = append(blocks, SACKBlock{
+			Left:  utils.ExtractUint32BE(option.Data, offset),
+			Right: utils.ExtractUint32BE(option.Data, offset+4),
+		})
+	}
+	return blocks
+}
+
+// 可读形式，不含选项名称
+func (option *TCPOption) ToString() string {
+	if option.Malformed {
+		return fmt.Sprintf("length %d (malformed) %s", option.Length, strings.ToUpper(hex.EncodeToString(option.Data)))
+	}
+	data := option.Data
+	switch option.Kind {
+	case TCP_OPTION_MSS:
+		if len(data) == 2 {
+			return strconv.Itoa(int(utils.ExtractUint16BE(data, 0)))
+		}
+	case TCP_OPTION_WINDOW_SCALE:
+		if len(data) == 1 {
+			text := strconv.Itoa(int(data[0])) + " (multiply by " + strconv.Itoa(1<<min(data[0], TCP_MAX_WINDOW_SCALE)) + ")"
+			if data[0] > TCP_MAX_WINDOW_SCALE {
+				text += " [exceeds " + strconv.Itoa(int(TCP_MAX_WINDOW_SCALE)) + "]"
+			}
+			return text
+		}
+	case TCP_OPTION_SACK_PERMITTED:
+		if len(data) == 0 {
+			return "yes"
+		}
+	case TCP_OPTION_SACK:
+		if len(data)%8 == 0 && len(data) != 0 {
+			blocks := []string{}
+			for _, block := range option.SACKBlocks() {
+				blocks = append(blocks, fmt.Sprintf("%d-%d (%d bytes)", block.Left, block.Right, block.Right-block.Left))
+			}
+			return strings.Join(blocks, ", ")
+		}
+	case TCP_OPTION_TIMESTAMPS:
+		if len(data) == 8 {
+			return fmt.Sprintf("TSval %d, TSecr %d", utils.ExtractUint32BE(data, 0), utils.ExtractUint32BE(data, 4))
+		}
+	case TCP_OPTION_USER_TIMEOUT:
+		if len(data) == 2 {
+			timeout := utils.ExtractUint16BE(data, 0)
+			unit := " s"
+			if timeout&0x8000 != 0 {
+				unit = " min"
+			}
+			return strconv.Itoa(int(timeout&0x7FFF)) + unit
+		}
+	case TCP_OPTION_MPTCP:
+		if len(data) >= 1 {
+			return mptcpString(data)
+		}
+	}
+	if option.IsFastOpen() {
+		cookie := option.FastOpenCookie()
+		if len(cookie) == 0 {
+			return "cookie request"
+		}
+		return "cookie " + strings.ToUpper(hex.EncodeToString(cookie))
+	}
+	if len(data) == 0 {
+		return "length " + strconv.Itoa(int(option.Length))
+	}
+	return "length " + strconv.Itoa(int(option.Length)) + ", " + strings.ToUpper(hex.EncodeToString(data))
+}
+
+// MPTCP 选项的可读形式，data 从子类型开始
+func mptcpString(data []byte) string {
+	subtype := data[0] >> 4
+	builder := new(strings.Builder)
+	if name := MPTCP_SUBTYPE_NAME[subtype]; name != "" {
+		builder.WriteString(name)
+	} else {
+		builder.WriteString("Subtype " + strconv.Itoa(int(subtype)))
+	}
+	switch subtype {
+	case MPTCP_MP_CAPABLE:
+		builder.WriteString(fmt.Sprintf(", version %d", data[0]&0x0F))
+		if len(data) >= 2 {
+			builder.WriteString(fmt.Sprintf(", flags 0x%02X", data[1]))
+		}
+		if len(data) >= 10 {
+			builder.WriteString(fmt.Sprintf(", sender key 0x%016X", utils.ExtractUint64BE(data, 2)))
+		}
+		if len(data) >= 18 {
+			builder.WriteString(fmt.Sprintf(", receiver key 0x%016X", utils.ExtractUint64BE(data, 10)))
+		}
+	case MPTCP_MP_JOIN:
+		backup := data[0]&0x01 != 0
+		switch {
+		// SYN：接收方令牌与发送方随机数
+		case len(data) == 10:
+			builder.WriteString(fmt.Sprintf(", backup %t, address ID %d, token 0x%08X, nonce 0x%08X",
+				backup, data[1], utils.ExtractUint32BE(data, 2), utils.ExtractUint32BE(data, 6)))
+		// SYN/ACK：截断的 HMAC 与随机数
+		case len(data) == 14:
+			builder.WriteString(fmt.Sprintf(", backup %t, address ID %d, HMAC 0x%016X, nonce 0x%08X",
+				backup, data[1], utils.ExtractUint64BE(data, 2), utils.ExtractUint32BE(data, 10)))
+		// ACK：完整的 HMAC
+		case len(data) == 22:
+			builder.WriteString(", HMAC " + strings.ToUpper(hex.EncodeToString(data[2:])))
+		}
+	case MPTCP_DSS:
+		if len(data) >= 2 {
+			flags := data[1]
+			names := []string{}
+			for bit, name := range []string{"A", "a", "M", "m", "F"} {
+				if flags&(1<<bit) != 0 {
+					names = append(names, name)
+				}
+			}
+			builder.WriteString(fmt.Sprintf(", flags 0x%02X (%s)", flags, strings.Join(names, " ")))
+		}
+	case MPTCP_ADD_ADDR:
+		if len(data) >= 2 {
+			builder.WriteString(fmt.Sprintf(", address ID %d", data[1]))
+			// 地址之后可能带有端口与截断的 HMAC，按剩余长度判断地址族
+			rest := data[2:]
+			switch {
+			case len(rest) == 4 || len(rest) == 6 || len(rest) == 12 || len(rest) == 14:
+				ip := types.IPv4{}
+				ip.Parse([4]byte(rest[:4]))
+				builder.WriteString(", " + ip.ToString())
+				if len(rest) == 6 || len(rest) == 14 {
+					builder.WriteString(", port " + strconv.Itoa(int(utils.ExtractUint16BE(rest, 4))))
+				}
+			case len(rest) == 16 || len(rest) == 18 || len(rest) == 24 || len(rest) == 26:
+				ip := types.IPv6{}
+				ip.Parse([16]byte(rest[:16]))
+				builder.WriteString(", " + ip.ToString())
+				if len(rest) == 18 || len(rest) == 26 {
+					builder.WriteString(", port " + strconv.Itoa(int(utils.ExtractUint16BE(rest, 16))))
+				}
+			}
+		}
+	case MPTCP_REMOVE_ADDR:
+		ids := []string{}
+		for _, id := range data[1:] {
+			ids = append(ids, strconv.Itoa(int(id)))
+		}
+		builder.WriteString(", address IDs " + strings.Join(ids, ", "))
+	case MPTCP_MP_PRIO:
+		builder.WriteString(fmt.Sprintf(", backup %t", data[0]&0x01 != 0))
+	case MPTCP_MP_FAIL:
+		if len(data) >= 10 {
+			builder.WriteString(fmt.Sprintf(", data sequence number %d", utils.ExtractUint64BE(data, 2)))
+		}
+	case MPTCP_MP_FASTCLOSE:
+		if len(data) >= 10 {
+			builder.WriteString(fmt.Sprintf(", receiver key 0x%016X", utils.ExtractUint64BE(data, 2)))
+		}
+	}
+	return builder.String()
+}
+
+// 解析选项区域，EOL 之后的数据被忽略
+func ParseTCPOptions(options []byte) []TCPOption {
+	result := []TCPOption{}
+	length := len(options)
+	for offset := 0; offset < length; {
+		kind := options[offset]
+		if kind == TCP_OPTION_EOL || kind == TCP_OPTION_NOP {
+			result = append(result, TCPOption{Kind: kind, Length: 1})
+			offset++
+			if kind == TCP_OPTION_EOL {
+				break
+			}
+			continue
+		}
+		option := TCPOption{Kind: kind}
+		if offset+1 >= length || options[offset+1] < 2 || offset+int(options[offset+1]) > length {
+			// 长度非法时剩余数据都视为该选项的内容
+			option.Malformed = true
+			if offset+1 < length {
+				option.Length = options[offset+1]
+			}
+			option.Data = make([]byte, max(0, length-offset-2))
+			copy(option.Data, options[min(offset+2, length):])
+			result = append(result, option)
+			break
+		}
+		option.Length = options[offset+1]
+		option.Data = make([]byte, option.Length-2)
+		copy(option.Data, options[offset+2:offset+int(option.Length)])
+		result = append(result, option)
+		offset += int(option.Length)
+	}
+	return result
+}
@@ -8,16 +8,30 @@ import (
 	"packet-inspector/utils"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// UDP 报文所属流的状态，由按五元组的流跟踪得出
+type UDPFlow struct {
+	Index           int           // 流序号，从 0 开始
+	Forward         bool          // 报文是否由流的发起方发出
+	ForwardPackets  int           // 到本报文为止发起方发出的报文数
+	BackwardPackets int           // 到本报文为止响应方发出的报文数
+	ForwardBytes    int           // 到本报文为止发起方发出的载荷字节数
+	BackwardBytes   int           // 到本报文为止响应方发出的载荷字节数
+	Delta           time.Duration // 距流中上一个报文的时间，流的第一个报文为 0
+}
+
 type UDP struct {
 	resolver.IPacket
+	resolver.ExpertInfo
 	raw         []byte           // 原始报文
 	source      uint16           // 源端口
 	destination uint16           // 目的端口
 	length      uint16           // 报文总长度
 	checksum    uint16           // 校验和
 	data        resolver.IPacket // 上层协议数据
+	flow        *UDPFlow         // 所属的流，未经流跟踪时为 nil
 }
 
 func (udp *UDP) Raw() []byte {
@@ -28,6 +42,34 @@ func (udp *UDP) Hex() string {
 	return strings.ToUpper(hex.EncodeToString(udp.raw))
 }
 
+func (udp *UDP) Inner() resolver.IPacket {
+	return udp.data
+}
+
+// 设置报文所属的流
+func (udp *UDP) SetFlow(flow UDPFlow) {
+	udp.flow = &flow
+}
+
+func (udp *UDP) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "udp.srcport", Value: strconv.Itoa(int(udp.source))},
+		{Name: "udp.dstport", Value: strconv.Itoa(int(udp.destination))},
+		{Name: "udp.length", Value: strconv.Itoa(int(udp.length))},
+	}
+	if flow := udp.flow; flow != nil {
+		fields = append(fields,
+			resolver.Field{Name: "udp.stream", Value: strconv.Itoa(flow.Index)},
+			resolver.Field{Name: "udp.stream.packets", Value: strconv.Itoa(flow.ForwardPackets + flow.BackwardPackets)},
+			resolver.Field{Name: "udp.stream.bytes", Value: strconv.Itoa(flow.ForwardBytes + flow.BackwardBytes)},
+		)
+		if flow.ForwardPackets+flow.BackwardPackets > 1 {
+			fields = append(fields, resolver.Field{Name: "udp.time_delta_us", Value: strconv.FormatInt(flow.Delta.Microseconds(), 10)})
+		}
+	}
+	return fields
+}
+
 func (udp *UDP) ToReadableString(indent int) string {
 	builder := new(strings.Builder)
 	tabs := make([]byte, indent)
@@ -58,6 +100,35 @@ func (udp *UDP) ToReadableString(indent int) string {
 	builder.WriteString(fmt.Sprintf("0x%04X", udp.checksum))
 	builder.WriteByte('\n')
 
+	if flow := udp.flow; flow != nil {
+		builder.Write(tabs)
+		builder.WriteString("Flow: {\n")
+		builder.Write(tabs)
+		builder.WriteString("\tIndex: ")
+		builder.WriteString(strconv.Itoa(flow.Index))
+		builder.WriteByte('\n')
+		builder.Write(tabs)
+		if flow.Forward {
+			builder.WriteString("\tDirection: from initiator\n")
+		} else {
+			builder.WriteString("\tDirection: from responder\n")
+		}
+		builder.Write(tabs)
+		builder.WriteString(fmt.Sprintf("\tPackets: %d (%d from initiator, %d from responder)\n",
+			flow.ForwardPackets+flow.BackwardPackets, flow.ForwardPackets, flow.BackwardPackets))
+		builder.Write(tabs)
+		builder.WriteString(fmt.Sprintf("\tBytes: %d (%d from initiator, %d from responder)\n",
+			flow.ForwardBytes+flow.BackwardBytes, flow.ForwardBytes, flow.BackwardBytes))
+		if flow.ForwardPackets+flow.BackwardPackets > 1 {
+			builder.Write(tabs)
+			builder.WriteString("\tTime since previous packet: ")
+			builder.WriteString(flow.Delta.String())
+			builder.WriteByte('\n')
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	}
+
 	builder.Write(tabs)
 	builder.WriteString("Data: {\n")
 	if udp.data != nil {
@@ -82,18 +153,23 @@ func UDPResolve(packet []byte) resolver.IPacket {
 	udp.source = utils.ExtractUint16BE(packet, 0)
 	udp.destination = utils.ExtractUint16BE(packet, 2)
 	udp.length = utils.ExtractUint16BE(packet, 4)
-	println("length = ", length, "udp length = ", udp.length)
-	if length != int(udp.length) {
-		return nil
-	}
 	udp.checksum = utils.ExtractUint16BE(packet, 6)
+	// 长度字段有误时按实际数据解析
+	switch {
+	case udp.length < 8:
+		udp.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "UDP", "Length less than header length")
+	case int(udp.length) > length:
+		udp.AddExpert(resolver.SEVERITY_ERROR, resolver.GROUP_MALFORMED, "UDP", "Length exceeds captured data")
+	case int(udp.length) < length:
+		udp.AddExpert(resolver.SEVERITY_WARN, resolver.GROUP_MALFORMED, "UDP", "Data after the end of the datagram")
+		length = int(udp.length)
+	}
 	if length > 8 {
-		for _, resolver := range applicationlayer.Resolvers {
-			udp.data = resolver(packet[8:length])
-			if udp.data != nil {
-				break
-			}
-		}
+		udp.data = PortResolve(udp.source, udp.destination, packet[8:length])
+	}
+	if length > 8 && udp.data == nil {
+		// 按固定顺序尝试，使流跟踪与帧输出得到相同的协议
+		udp.data, _ = applicationlayer.Resolve(packet[8:length])
 	}
 	udp.raw = make([]byte, length)
 	copy(udp.raw, packet)
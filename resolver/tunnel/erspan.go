@@ -0,0 +1,278 @@
+package tunnel
+
+import (
+	"encoding/hex"
+	"packet-inspector/resolver"
+	datalinklayer "packet-inspector/resolver/datalink-layer"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// ERSPAN Type II 的原始帧封装方式
+var ERSPAN_ENCAPSULATION_NAME = map[uint8]string{
+	0: "Untagged",
+	1: "ISL",
+	2: "802.1Q",
+	3: "VLAN preserved",
+}
+
+// ERSPAN Type III 的坏帧/短帧标识
+var ERSPAN_BSO_NAME = map[uint8]string{
+	0: "Good frame",
+	1: "Short frame",
+	2: "Oversized frame",
+	3: "Bad frame",
+}
+
+// ERSPAN Type III 时间戳的精度
+var ERSPAN_GRANULARITY_NAME = map[uint8]string{
+	0: "100 microseconds",
+	1: "100 nanoseconds",
+	2: "IEEE 1588",
+	3: "User configurable",
+}
+
+// Cisco ERSPAN 镜像会话，Type I 没有报文头，Type II 报文头 8 字节，Type III 报文头 12 字节（可选 8 字节平台子报文头）
+type ERSPAN struct {
+	resolver.IPacket
+	raw           []byte           // 原始数据，从 ERSPAN 报文头开始
+	erspanType    uint8            // 1、2 或 3
+	vlan          uint16           // 原始帧的 VLAN（12 bit）
+	cos           uint8            // 原始帧的优先级（3 bit）
+	encapsulation uint8            // Type II：原始帧封装方式；Type III：坏帧/短帧标识（2 bit）
+	truncated     bool             // 原始帧是否被截断（T）
+	session       uint16           // 会话 ID（10 bit）
+	index         uint32           // Type II：端口索引（20 bit）
+	timestamp     uint32           // Type III：时间戳
+	sgt           uint16           // Type III：安全组标签
+	frameType     uint8            // Type III：原始帧类型（5 bit），0 为以太网帧，2 为 IP 报文
+	hardware      uint8            // Type III：硬件 ID（6 bit）
+	egress        bool             // Type III：是否为出方向（D）
+	granularity   uint8            // Type III：时间戳精度（2 bit）
+	platform      []byte           // Type III：平台子报文头
+	data          resolver.IPacket // 被镜像的原始帧
+}
+
+func (erspan *ERSPAN) Raw() []byte {
+	return erspan.raw
+}
+
+func (erspan *ERSPAN) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(erspan.raw))
+}
+
+func (erspan *ERSPAN) Inner() resolver.IPacket {
+	return erspan.data
+}
+
+func (erspan *ERSPAN) Tunnel() string {
+	if erspan.erspanType == ERSPAN_TYPE_I {
+		return "ERSPAN I"
+	}
+	return "ERSPAN " + strings.Repeat("I", int(erspan.erspanType)) + " session " + strconv.Itoa(int(erspan.session))
+}
+
+func (erspan *ERSPAN) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "erspan.type", Value: strconv.Itoa(int(erspan.erspanType))},
+	}
+	if erspan.erspanType == ERSPAN_TYPE_I {
+		return fields
+	}
+	fields = append(fields,
+		resolver.Field{Name: "erspan.session", Value: strconv.Itoa(int(erspan.session))},
+		resolver.Field{Name: "erspan.vlan", Value: strconv.Itoa(int(erspan.vlan))},
+	)
+	if erspan.erspanType == ERSPAN_TYPE_III {
+		direction := "0"
+		if erspan.egress {
+			direction = "1"
+		}
+		fields = append(fields,
+			resolver.Field{Name: "erspan.sgt", Value: strconv.Itoa(int(erspan.sgt))},
+			resolver.Field{Name: "erspan.direction", Value: direction},
+		)
+	}
+	return fields
+}
+
+func (erspan *ERSPAN) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: ERSPAN Type ")
+	builder.WriteString(strings.Repeat("I", int(erspan.erspanType)))
+	builder.WriteString(" (Tunnel)\n")
+
+	if erspan.erspanType != ERSPAN_TYPE_I {
+		builder.Write(tabs)
+		builder.WriteString("Session ID: ")
+		builder.WriteString(strconv.Itoa(int(erspan.session)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("VLAN: ")
+		builder.WriteString(strconv.Itoa(int(erspan.vlan)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("COS: ")
+		builder.WriteString(strconv.Itoa(int(erspan.cos)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Truncated: ")
+		if erspan.truncated {
+			builder.WriteString("1\n")
+		} else {
+			builder.WriteString("0\n")
+		}
+	}
+
+	if erspan.erspanType == ERSPAN_TYPE_II {
+		builder.Write(tabs)
+		builder.WriteString("Encapsulation: ")
+		builder.WriteString(strconv.Itoa(int(erspan.encapsulation)))
+		builder.WriteString(" (")
+		builder.WriteString(ERSPAN_ENCAPSULATION_NAME[erspan.encapsulation])
+		builder.WriteString(")\n")
+
+		builder.Write(tabs)
+		builder.WriteString("Index: ")
+		builder.WriteString(strconv.FormatUint(uint64(erspan.index), 10))
+		builder.WriteByte('\n')
+	}
+
+	if erspan.erspanType == ERSPAN_TYPE_III {
+		builder.Write(tabs)
+		builder.WriteString("Bad/short/oversized: ")
+		builder.WriteString(strconv.Itoa(int(erspan.encapsulation)))
+		builder.WriteString(" (")
+		builder.WriteString(ERSPAN_BSO_NAME[erspan.encapsulation])
+		builder.WriteString(")\n")
+
+		builder.Write(tabs)
+		builder.WriteString("Timestamp: ")
+		builder.WriteString(strconv.FormatUint(uint64(erspan.timestamp), 10))
+		builder.WriteString(" (")
+		builder.WriteString(ERSPAN_GRANULARITY_NAME[erspan.granularity])
+		builder.WriteString(")\n")
+
+		builder.Write(tabs)
+		builder.WriteString("Security group tag: ")
+		builder.WriteString(strconv.Itoa(int(erspan.sgt)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Frame type: ")
+		builder.WriteString(strconv.Itoa(int(erspan.frameType)))
+		switch erspan.frameType {
+		case ERSPAN_FRAME_ETHERNET:
+			builder.WriteString(" (Ethernet)")
+		case ERSPAN_FRAME_IP:
+			builder.WriteString(" (IP)")
+		}
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Hardware ID: ")
+		builder.WriteString(strconv.Itoa(int(erspan.hardware)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Direction: ")
+		if erspan.egress {
+			builder.WriteString("Egress\n")
+		} else {
+			builder.WriteString("Ingress\n")
+		}
+
+		if erspan.platform != nil {
+			builder.Write(tabs)
+			builder.WriteString("Platform specific(HEX): ")
+			builder.WriteString(strings.ToUpper(hex.EncodeToString(erspan.platform)))
+			builder.WriteByte('\n')
+		}
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if erspan.data != nil {
+		builder.WriteString(erspan.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(erspan.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+// 解析 ERSPAN，packet 从 GRE 载荷开始；报文头版本与 erspanType 不符时返回 nil
+func ERSPANResolve(erspanType uint8, packet []byte) resolver.IPacket {
+	length := len(packet)
+
+	erspan := new(ERSPAN)
+	erspan.erspanType = erspanType
+	offset := 0
+	switch erspanType {
+	case ERSPAN_TYPE_II:
+		if length < 8 || packet[0]>>4 != 1 {
+			return nil
+		}
+		erspan.readCommon(packet)
+		erspan.encapsulation = (packet[2] >> 3) & 0x03
+		erspan.index = utils.ExtractUint32BE(packet, 4) & 0xFFFFF
+		offset = 8
+	case ERSPAN_TYPE_III:
+		if length < 12 || packet[0]>>4 != 2 {
+			return nil
+		}
+		erspan.readCommon(packet)
+		erspan.encapsulation = (packet[2] >> 3) & 0x03
+		erspan.timestamp = utils.ExtractUint32BE(packet, 4)
+		erspan.sgt = utils.ExtractUint16BE(packet, 8)
+		erspan.frameType = (packet[10] >> 2) & 0x1F
+		erspan.hardware = uint8(utils.ExtractUint16BE(packet, 10)>>4) & 0x3F
+		erspan.egress = (packet[11] & 0x08) == 0x08
+		erspan.granularity = (packet[11] >> 1) & 0x03
+		offset = 12
+		if packet[11]&0x01 == 0x01 {
+			if length < 20 {
+				return nil
+			}
+			erspan.platform = make([]byte, 8)
+			copy(erspan.platform, packet[12:20])
+			offset = 20
+		}
+	}
+
+	if erspan.frameType == ERSPAN_FRAME_IP {
+		erspan.data = datalinklayer.RawIPResolve(packet[offset:length])
+	} else {
+		erspan.data = datalinklayer.EthernetResolve(packet[offset:length])
+	}
+	erspan.raw = make([]byte, length)
+	copy(erspan.raw, packet)
+
+	return erspan
+}
+
+// Type II 与 Type III 共有的前 4 字节
+func (erspan *ERSPAN) readCommon(packet []byte) {
+	erspan.vlan = utils.ExtractUint16BE(packet, 0) & 0x0FFF
+	erspan.cos = packet[2] >> 5
+	erspan.truncated = (packet[2] & 0x04) == 0x04
+	erspan.session = utils.ExtractUint16BE(packet, 2) & 0x03FF
+}
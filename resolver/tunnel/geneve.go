@@ -0,0 +1,205 @@
+package tunnel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// Geneve 选项类别，见 IANA "Geneve Option Class" 注册表
+var GENEVE_OPTION_CLASS_NAME = map[uint16]string{
+	0x0100: "Linux",
+	0x0101: "Open vSwitch",
+	0x0102: "Open Virtual Networking",
+	0x0103: "In-band Network Telemetry",
+	0x0104: "VMware",
+	0x0105: "Amazon",
+	0x0106: "Cisco",
+	0x0107: "Oracle",
+}
+
+// Geneve 协议（RFC 8926）
+type Geneve struct {
+	resolver.IPacket
+	raw      []byte           // 原始报文
+	version  uint8            // 版本（2 bit）
+	oam      bool             // 是否为控制报文（O）
+	critical bool             // 是否带有关键选项（C）
+	protocol uint16           // 载荷类型
+	vni      uint32           // 虚拟网络标识（24 bit）
+	options  []GeneveOption   // 选项
+	data     resolver.IPacket // 载荷的数据
+}
+
+// Geneve 选项
+type GeneveOption struct {
+	class      uint16 // 选项类别
+	optionType uint8  // 选项类型，最高位为关键标志
+	data       []byte // 选项数据
+}
+
+func (geneve *Geneve) Raw() []byte {
+	return geneve.raw
+}
+
+func (geneve *Geneve) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(geneve.raw))
+}
+
+func (geneve *Geneve) Inner() resolver.IPacket {
+	return geneve.data
+}
+
+func (geneve *Geneve) Tunnel() string {
+	return "Geneve " + strconv.FormatUint(uint64(geneve.vni), 10)
+}
+
+func (geneve *Geneve) Fields() []resolver.Field {
+	oam := "0"
+	if geneve.oam {
+		oam = "1"
+	}
+	fields := []resolver.Field{
+		{Name: "geneve.vni", Value: strconv.FormatUint(uint64(geneve.vni), 10)},
+		{Name: "geneve.proto", Value: fmt.Sprintf("0x%04X", geneve.protocol)},
+		{Name: "geneve.oam", Value: oam},
+	}
+	for _, option := range geneve.options {
+		fields = append(fields, resolver.Field{Name: "geneve.option.class", Value: fmt.Sprintf("0x%04X", option.class)})
+	}
+	return fields
+}
+
+func (geneve *Geneve) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: Geneve (Tunnel)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Version: ")
+	builder.WriteString(strconv.Itoa(int(geneve.version)))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("OAM: ")
+	if geneve.oam {
+		builder.WriteString("1\n")
+	} else {
+		builder.WriteString("0\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Critical options present: ")
+	if geneve.critical {
+		builder.WriteString("1\n")
+	} else {
+		builder.WriteString("0\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol type: ")
+	builder.WriteString(fmt.Sprintf("0x%04X (", geneve.protocol))
+	builder.WriteString(protocolName(geneve.protocol))
+	builder.WriteString(")\n")
+
+	builder.Write(tabs)
+	builder.WriteString("VNI: ")
+	builder.WriteString(strconv.FormatUint(uint64(geneve.vni), 10))
+	builder.WriteByte('\n')
+
+	if len(geneve.options) != 0 {
+		builder.Write(tabs)
+		builder.WriteString("Options: {\n")
+		for _, option := range geneve.options {
+			builder.Write(tabs)
+			builder.WriteString(fmt.Sprintf("\tClass 0x%04X (", option.class))
+			if name := GENEVE_OPTION_CLASS_NAME[option.class]; name != "" {
+				builder.WriteString(name)
+			} else {
+				builder.WriteString("Unknown")
+			}
+			builder.WriteString(fmt.Sprintf("), type 0x%02X", option.optionType))
+			if option.optionType&0x80 == 0x80 {
+				builder.WriteString(" [critical]")
+			}
+			builder.WriteString(": ")
+			builder.WriteString(strings.ToUpper(hex.EncodeToString(option.data)))
+			builder.WriteByte('\n')
+		}
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if geneve.data != nil {
+		builder.WriteString(geneve.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(geneve.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func GeneveResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 8 {
+		return nil
+	}
+
+	geneve := new(Geneve)
+	geneve.version = packet[0] >> 6
+	if geneve.version != 0 {
+		return nil
+	}
+	optionsLength := int(packet[0]&0x3F) * 4
+	geneve.oam = (packet[1] & 0x80) == 0x80
+	geneve.critical = (packet[1] & 0x40) == 0x40
+	geneve.protocol = utils.ExtractUint16BE(packet, 2)
+	geneve.vni = utils.ExtractUint32BE(packet, 4) >> 8
+	end := 8 + optionsLength
+	if end > length {
+		return nil
+	}
+
+	// 选项长度单位 4 字节，不含 4 字节的选项头
+	for offset := 8; offset < end; {
+		if offset+4 > end {
+			return nil
+		}
+		option := GeneveOption{
+			class:      utils.ExtractUint16BE(packet, offset),
+			optionType: packet[offset+2],
+		}
+		size := int(packet[offset+3]&0x1F) * 4
+		if offset+4+size > end {
+			return nil
+		}
+		option.data = make([]byte, size)
+		copy(option.data, packet[offset+4:offset+4+size])
+		geneve.options = append(geneve.options, option)
+		offset += 4 + size
+	}
+
+	geneve.data = protocolResolve(geneve.protocol, packet[end:length])
+	geneve.raw = make([]byte, length)
+	copy(geneve.raw, packet)
+
+	return geneve
+}
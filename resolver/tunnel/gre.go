@@ -0,0 +1,235 @@
+package tunnel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// GRE 协议（RFC 2784、RFC 2890），版本 1 为 PPTP 使用的增强 GRE（RFC 2637）
+type GRE struct {
+	resolver.IPacket
+	raw             []byte           // 原始报文
+	checksumPresent bool             // 是否带有校验和（C）
+	routingPresent  bool             // 是否带有路由信息（R，已废弃）
+	keyPresent      bool             // 是否带有密钥（K）
+	sequencePresent bool             // 是否带有序号（S）
+	ackPresent      bool             // 版本 1：是否带有确认号（A）
+	version         uint8            // 版本（3 bit）
+	protocol        uint16           // 载荷类型
+	checksum        uint16           // 校验和，覆盖 GRE 报文头与载荷
+	checksumValid   bool             // 校验和是否正确
+	key             uint32           // 密钥；版本 1 时高 16 位为载荷长度，低 16 位为呼叫 ID
+	sequence        uint32           // 序号
+	acknowledgment  uint32           // 版本 1：确认号
+	data            resolver.IPacket // 载荷的数据
+}
+
+func (gre *GRE) Raw() []byte {
+	return gre.raw
+}
+
+func (gre *GRE) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(gre.raw))
+}
+
+func (gre *GRE) Inner() resolver.IPacket {
+	return gre.data
+}
+
+// 载荷为 ERSPAN 时由 ERSPAN 报告隧道
+func (gre *GRE) Tunnel() string {
+	if _, ok := gre.data.(*ERSPAN); ok || gre.data == nil {
+		return ""
+	}
+	if gre.keyPresent && gre.version == 0 {
+		return "GRE key " + strconv.FormatUint(uint64(gre.key), 10)
+	}
+	return "GRE"
+}
+
+func (gre *GRE) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "gre.version", Value: strconv.Itoa(int(gre.version))},
+		{Name: "gre.proto", Value: fmt.Sprintf("0x%04X", gre.protocol)},
+	}
+	if gre.keyPresent {
+		fields = append(fields, resolver.Field{Name: "gre.key", Value: strconv.FormatUint(uint64(gre.key), 10)})
+	}
+	if gre.sequencePresent {
+		fields = append(fields, resolver.Field{Name: "gre.seq", Value: strconv.FormatUint(uint64(gre.sequence), 10)})
+	}
+	return fields
+}
+
+func (gre *GRE) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: GRE (Tunnel)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Version: ")
+	builder.WriteString(strconv.Itoa(int(gre.version)))
+	if gre.version == 1 {
+		builder.WriteString(" (Enhanced GRE)")
+	}
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol type: ")
+	builder.WriteString(fmt.Sprintf("0x%04X (", gre.protocol))
+	builder.WriteString(protocolName(gre.protocol))
+	builder.WriteString(")\n")
+
+	if gre.checksumPresent {
+		builder.Write(tabs)
+		builder.WriteString("Checksum: ")
+		builder.WriteString(fmt.Sprintf("0x%04X", gre.checksum))
+		if gre.checksumValid {
+			builder.WriteString(" (correct)\n")
+		} else {
+			builder.WriteString(" (incorrect)\n")
+		}
+	}
+
+	if gre.keyPresent && gre.version == 1 {
+		builder.Write(tabs)
+		builder.WriteString("Payload length: ")
+		builder.WriteString(strconv.Itoa(int(gre.key >> 16)))
+		builder.WriteByte('\n')
+
+		builder.Write(tabs)
+		builder.WriteString("Call ID: ")
+		builder.WriteString(strconv.Itoa(int(gre.key & 0xFFFF)))
+		builder.WriteByte('\n')
+	} else if gre.keyPresent {
+		builder.Write(tabs)
+		builder.WriteString("Key: ")
+		builder.WriteString(fmt.Sprintf("0x%08X (%d)", gre.key, gre.key))
+		builder.WriteByte('\n')
+	}
+
+	if gre.sequencePresent {
+		builder.Write(tabs)
+		builder.WriteString("Sequence number: ")
+		builder.WriteString(strconv.FormatUint(uint64(gre.sequence), 10))
+		builder.WriteByte('\n')
+	}
+
+	if gre.ackPresent {
+		builder.Write(tabs)
+		builder.WriteString("Acknowledgment number: ")
+		builder.WriteString(strconv.FormatUint(uint64(gre.acknowledgment), 10))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if gre.data != nil {
+		builder.WriteString(gre.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(gre.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func GREResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 4 {
+		return nil
+	}
+
+	gre := new(GRE)
+	gre.checksumPresent = (packet[0] & 0x80) == 0x80
+	gre.routingPresent = (packet[0] & 0x40) == 0x40
+	gre.keyPresent = (packet[0] & 0x20) == 0x20
+	gre.sequencePresent = (packet[0] & 0x10) == 0x10
+	gre.ackPresent = (packet[1] & 0x80) == 0x80
+	gre.version = packet[1] & 0x07
+	gre.protocol = utils.ExtractUint16BE(packet, 2)
+	if gre.version > 1 {
+		return nil
+	}
+
+	offset := 4
+	// 校验和与路由信息任一存在时，都带有校验和与偏移字段
+	if gre.checksumPresent || gre.routingPresent {
+		if offset+4 > length {
+			return nil
+		}
+		gre.checksum = utils.ExtractUint16BE(packet, offset)
+		gre.checksumValid = utils.InternetChecksum(packet) == 0
+		offset += 4
+	}
+	if gre.keyPresent {
+		if offset+4 > length {
+			return nil
+		}
+		gre.key = utils.ExtractUint32BE(packet, offset)
+		offset += 4
+	}
+	if gre.sequencePresent {
+		if offset+4 > length {
+			return nil
+		}
+		gre.sequence = utils.ExtractUint32BE(packet, offset)
+		offset += 4
+	}
+	if gre.ackPresent && gre.version == 1 {
+		if offset+4 > length {
+			return nil
+		}
+		gre.acknowledgment = utils.ExtractUint32BE(packet, offset)
+		offset += 4
+	}
+	// 路由信息由若干 SRE 组成，以地址族与长度均为 0 的 SRE 结束
+	for gre.routingPresent {
+		if offset+4 > length {
+			return nil
+		}
+		sre := int(packet[offset+3])
+		offset += 4 + sre
+		if sre == 0 {
+			break
+		}
+	}
+	if offset > length {
+		return nil
+	}
+
+	payload := packet[offset:length]
+	switch gre.protocol {
+	case PROTOCOL_ERSPAN_I_II:
+		// 不带序号的为 Type I，没有 ERSPAN 报文头
+		if gre.sequencePresent {
+			gre.data = ERSPANResolve(ERSPAN_TYPE_II, payload)
+		} else {
+			gre.data = ERSPANResolve(ERSPAN_TYPE_I, payload)
+		}
+	case PROTOCOL_ERSPAN_III:
+		gre.data = ERSPANResolve(ERSPAN_TYPE_III, payload)
+	default:
+		gre.data = protocolResolve(gre.protocol, payload)
+	}
+	gre.raw = make([]byte, length)
+	copy(gre.raw, packet)
+
+	return gre
+}
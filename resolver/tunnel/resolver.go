@@ -0,0 +1,56 @@
+package tunnel
+
+import (
+	"packet-inspector/resolver"
+	datalinklayer "packet-inspector/resolver/datalink-layer"
+	networklayer "packet-inspector/resolver/network-layer"
+	transportlayer "packet-inspector/resolver/transport-layer"
+)
+
+// 隧道协议的载荷类型，GRE 与 Geneve 使用以太网帧类型编号
+const (
+	PROTOCOL_ERSPAN_III   uint16 = 0x22EB
+	PROTOCOL_TEB          uint16 = 0x6558 // 透明以太网桥接，载荷为完整的以太网帧
+	PROTOCOL_PPP          uint16 = 0x880B
+	PROTOCOL_ERSPAN_I_II  uint16 = 0x88BE
+	ERSPAN_TYPE_I         uint8  = 1
+	ERSPAN_TYPE_II        uint8  = 2
+	ERSPAN_TYPE_III       uint8  = 3
+	ERSPAN_FRAME_ETHERNET uint8  = 0
+	ERSPAN_FRAME_IP       uint8  = 2
+)
+
+var PROTOCOL_NAME = map[uint16]string{
+	PROTOCOL_ERSPAN_III:  "ERSPAN Type III",
+	PROTOCOL_TEB:         "Transparent Ethernet Bridging",
+	PROTOCOL_PPP:         "PPP",
+	PROTOCOL_ERSPAN_I_II: "ERSPAN",
+}
+
+// 隧道载荷类型的名称，先查隧道协议自己的类型，再查以太网帧类型
+func protocolName(protocol uint16) string {
+	if name := PROTOCOL_NAME[protocol]; name != "" {
+		return name
+	}
+	if name := datalinklayer.ETHERNET_PROTOCOL_NAME[protocol]; name != "" {
+		return name
+	}
+	return "Unknown"
+}
+
+// 按载荷类型解析隧道内层报文，重新进入链路层或网络层
+func protocolResolve(protocol uint16, payload []byte) resolver.IPacket {
+	switch protocol {
+	case PROTOCOL_TEB:
+		return datalinklayer.EthernetResolve(payload)
+	case PROTOCOL_PPP:
+		return datalinklayer.PPPResolve(payload)
+	}
+	return datalinklayer.EtherTypeResolve(protocol, payload)
+}
+
+func init() {
+	networklayer.Resolvers["GRE"] = GREResolve
+	transportlayer.PortResolvers["VXLAN"] = VXLANResolve
+	transportlayer.PortResolvers["Geneve"] = GeneveResolve
+}
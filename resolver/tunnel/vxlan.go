@@ -0,0 +1,118 @@
+package tunnel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"packet-inspector/resolver"
+	datalinklayer "packet-inspector/resolver/datalink-layer"
+	"packet-inspector/utils"
+	"strconv"
+	"strings"
+)
+
+// VXLAN 协议（RFC 7348），载荷为完整的以太网帧；G 标志置位时带有组策略 ID（VXLAN-GBP）
+type VXLAN struct {
+	resolver.IPacket
+	raw         []byte           // 原始报文
+	flags       uint8            // 标志，I（0x08）置位时 VNI 有效，G（0x80）置位时带有组策略 ID
+	groupPolicy uint16           // 组策略 ID
+	vni         uint32           // VXLAN 网络标识（24 bit）
+	data        resolver.IPacket // 内层以太网帧
+}
+
+func (vxlan *VXLAN) Raw() []byte {
+	return vxlan.raw
+}
+
+func (vxlan *VXLAN) Hex() string {
+	return strings.ToUpper(hex.EncodeToString(vxlan.raw))
+}
+
+func (vxlan *VXLAN) Inner() resolver.IPacket {
+	return vxlan.data
+}
+
+// VXLAN 网络标识
+func (vxlan *VXLAN) VNI() uint32 {
+	return vxlan.vni
+}
+
+func (vxlan *VXLAN) Tunnel() string {
+	return "VXLAN " + strconv.FormatUint(uint64(vxlan.vni), 10)
+}
+
+func (vxlan *VXLAN) Fields() []resolver.Field {
+	fields := []resolver.Field{
+		{Name: "vxlan.vni", Value: strconv.FormatUint(uint64(vxlan.vni), 10)},
+	}
+	if vxlan.flags&0x80 == 0x80 {
+		fields = append(fields, resolver.Field{Name: "vxlan.gbp", Value: strconv.Itoa(int(vxlan.groupPolicy))})
+	}
+	return fields
+}
+
+func (vxlan *VXLAN) ToReadableString(indent int) string {
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Protocol: VXLAN (Tunnel)\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Flags: ")
+	builder.WriteString(fmt.Sprintf("0x%02X", vxlan.flags))
+	builder.WriteByte('\n')
+
+	builder.Write(tabs)
+	builder.WriteString("VNI: ")
+	builder.WriteString(strconv.FormatUint(uint64(vxlan.vni), 10))
+	if vxlan.flags&0x08 == 0 {
+		builder.WriteString(" (invalid)")
+	}
+	builder.WriteByte('\n')
+
+	if vxlan.flags&0x80 == 0x80 {
+		builder.Write(tabs)
+		builder.WriteString("Group policy ID: ")
+		builder.WriteString(strconv.Itoa(int(vxlan.groupPolicy)))
+		builder.WriteByte('\n')
+	}
+
+	builder.Write(tabs)
+	builder.WriteString("Data: {\n")
+	if vxlan.data != nil {
+		builder.WriteString(vxlan.data.ToReadableString(indent + 1))
+	} else {
+		builder.Write(tabs)
+		builder.WriteString("\t(NOT RESOLVED)\n")
+	}
+	builder.Write(tabs)
+	builder.WriteString("}\n")
+
+	builder.Write(tabs)
+	builder.WriteString("Raw: ")
+	builder.WriteString(vxlan.Hex())
+	builder.WriteByte('\n')
+
+	return builder.String()
+}
+
+func VXLANResolve(packet []byte) resolver.IPacket {
+	length := len(packet)
+	if length < 8 {
+		return nil
+	}
+
+	vxlan := new(VXLAN)
+	vxlan.flags = packet[0]
+	vxlan.groupPolicy = utils.ExtractUint16BE(packet, 2)
+	vxlan.vni = utils.ExtractUint32BE(packet, 4) >> 8
+	vxlan.data = datalinklayer.EthernetResolve(packet[8:length])
+	vxlan.raw = make([]byte, length)
+	copy(vxlan.raw, packet)
+
+	return vxlan
+}
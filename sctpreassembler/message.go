@@ -0,0 +1,115 @@
+package sctpreassembler
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+type DropReason uint8
+
+const (
+	DROPPED_NONE           DropReason = 0
+	DROPPED_TIMEOUT        DropReason = 1
+	DROPPED_FRAGMENT_LIMIT DropReason = 2
+	DROPPED_MEMORY_LIMIT   DropReason = 3
+	DROPPED_CAPTURE_END    DropReason = 4
+)
+
+var DROP_REASON_NAME = map[DropReason]string{
+	DROPPED_NONE:           "none",
+	DROPPED_TIMEOUT:        "timeout",
+	DROPPED_FRAGMENT_LIMIT: "fragment limit",
+	DROPPED_MEMORY_LIMIT:   "memory limit",
+	DROPPED_CAPTURE_END:    "end of capture",
+}
+
+// 分片所属的流：关联的一个方向上的一个流
+type Key struct {
+	Source          netip.Addr // 源地址
+	Destination     netip.Addr // 目的地址
+	SourcePort      uint16
+	DestinationPort uint16
+	VerificationTag uint32 // 验证标签，关联重启后不同
+	Stream          uint16 // 流标识
+}
+
+// 组成用户消息的一个 DATA 块
+type Fragment struct {
+	Frame  int    // 分片所在的帧序号
+	TSN    uint32 // 传输序号
+	Length int    // 用户数据的长度
+}
+
+// 重组完成（或被丢弃）的用户消息
+type Message struct {
+	key       Key
+	unordered bool
+	ssn       uint16
+	ppid      uint32
+	fragments []Fragment // 按 TSN 排列的分片
+	start     time.Time  // 最早到达的分片的抓包时间
+	end       time.Time  // 最晚到达的分片的抓包时间
+	dropped   DropReason
+	data      []byte // 按 TSN 拼接的用户数据，被丢弃时为已收到的部分
+}
+
+func (message *Message) Key() Key {
+	return message.key
+}
+
+func (message *Message) Unordered() bool {
+	return message.unordered
+}
+
+func (message *Message) SSN() uint16 {
+	return message.ssn
+}
+
+// 载荷协议标识，取自第一个分片
+func (message *Message) PPID() uint32 {
+	return message.ppid
+}
+
+// 按 TSN 排列的分片
+func (message *Message) Fragments() []Fragment {
+	return message.fragments
+}
+
+// 贡献了分片的帧序号，按 TSN 排列
+func (message *Message) Frames() []int {
+	frames := make([]int, 0, len(message.fragments))
+	for _, fragment := range message.fragments {
+		frames = append(frames, fragment.Frame)
+	}
+	return frames
+}
+
+// 第一个与最后一个分片的 TSN
+func (message *Message) TSNs() (uint32, uint32) {
+	return message.fragments[0].TSN, message.fragments[len(message.fragments)-1].TSN
+}
+
+func (message *Message) Start() time.Time {
+	return message.start
+}
+
+func (message *Message) End() time.Time {
+	return message.end
+}
+
+func (message *Message) Dropped() DropReason {
+	return message.dropped
+}
+
+// 重组后的用户数据，被丢弃的消息为已收到的连续分片
+func (message *Message) Data() []byte {
+	return message.data
+}
+
+// 可读的流标识，如 "192.0.2.1:2905 -> 192.0.2.2:2905 stream 1"
+func (message *Message) Tuple() string {
+	return fmt.Sprintf("%s -> %s stream %d",
+		netip.AddrPortFrom(message.key.Source, message.key.SourcePort),
+		netip.AddrPortFrom(message.key.Destination, message.key.DestinationPort), message.key.Stream)
+}
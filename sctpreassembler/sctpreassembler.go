@@ -0,0 +1,279 @@
+package sctpreassembler
+
+import (
+	"container/list"
+	"net/netip"
+	"packet-inspector/statistics"
+	"slices"
+	"time"
+
+	transportlayer "packet-inspector/resolver/transport-layer"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+const STATISTICS_GROUP = "SCTP reassembly"
+
+// 重组缓存的限制，0 表示不限制
+type Limits struct {
+	MaxFragments int // 最多同时缓存的分片数量，超出时丢弃最早的分片所在的消息
+	MaxBytes     int // 所有分片合计最多缓存的字节数，超出时丢弃最早的分片所在的消息
+}
+
+// 重组器选项
+type Options struct {
+	Limits
+	Timeout time.Duration // 到达后超过该时长仍未重组完成的分片所在的消息被丢弃
+}
+
+// 每个流记住的最近重组完成的消息数，用于识别其分片的重传
+const DELIVERED_HISTORY = 16
+
+// 已重组完成的消息的 TSN 范围
+type tsnRange struct {
+	first uint32
+	last  uint32
+}
+
+func (r tsnRange) contains(tsn uint32) bool {
+	return tsn-r.first <= r.last-r.first
+}
+
+// 一个流最近重组完成的消息
+type history struct {
+	ranges []tsnRange
+	last   time.Time // 该流最近一个分片的抓包时间
+}
+
+// 等待重组的 DATA 块
+type fragment struct {
+	element   *list.Element
+	key       Key
+	frame     int
+	timestamp time.Time
+	data      transportlayer.SCTPData
+}
+
+// 两个分片是否可能属于同一条用户消息：有序消息的分片带有相同的流序号
+func (f *fragment) sameMessage(other *fragment) bool {
+	return f.data.Unordered == other.data.Unordered && (f.data.Unordered || f.data.SSN == other.data.SSN)
+}
+
+// 以抓包时间戳驱动的 SCTP 用户消息重组器，按流重组分片的 DATA 块；
+// 同一条消息的分片使用连续的 TSN，由第一个（B 标志）到最后一个（E 标志）分片组成
+type Reassembler struct {
+	limits    Limits
+	timeout   time.Duration
+	streams   map[Key]map[uint32]*fragment // 按流与 TSN 索引的分片
+	recent    map[Key]*history             // 按流记录的最近重组完成的消息
+	order     *list.List                   // 按到达时间排序的分片，表头最早
+	total     int                          // 所有分片合计缓存的字节数
+	now       time.Time                    // 最近一个报文的抓包时间
+	nextSweep time.Time                    // 下一次清除空闲流的记录的时间
+	complete  func(*Message)               // 消息重组完成或被丢弃时的回调
+}
+
+// 创建重组器，complete 在每条消息重组完成或被丢弃时被调用
+func New(options Options, complete func(*Message)) *Reassembler {
+	return &Reassembler{
+		limits:   options.Limits,
+		timeout:  options.Timeout,
+		streams:  map[Key]map[uint32]*fragment{},
+		recent:   map[Key]*history{},
+		order:    list.New(),
+		complete: complete,
+	}
+}
+
+// 缓存的分片数量
+func (reassembler *Reassembler) Len() int {
+	return reassembler.order.Len()
+}
+
+// 处理一个报文，frame 为其帧序号，不含分片的报文仅用于推进抓包时间
+func (reassembler *Reassembler) Reassemble(frame int, packet gopacket.Packet) {
+	timestamp := packet.Metadata().Timestamp
+	if timestamp.After(reassembler.now) {
+		reassembler.now = timestamp
+	}
+	reassembler.expire()
+
+	layer := packet.Layer(layers.LayerTypeSCTP)
+	network := packet.NetworkLayer()
+	if layer == nil || network == nil {
+		return
+	}
+	source, _ := netip.AddrFromSlice(network.NetworkFlow().Src().Raw())
+	destination, _ := netip.AddrFromSlice(network.NetworkFlow().Dst().Raw())
+	contents := append(slices.Clone(layer.LayerContents()), layer.LayerPayload()...)
+	sctp, ok := transportlayer.SCTPResolve(contents).(*transportlayer.SCTP)
+	if !ok {
+		return
+	}
+	for _, chunk := range sctp.Chunks() {
+		data, ok := chunk.Data()
+		if !ok || data.Complete() {
+			continue
+		}
+		key := Key{
+			Source:          source,
+			Destination:     destination,
+			SourcePort:      sctp.SourcePort(),
+			DestinationPort: sctp.DestinationPort(),
+			VerificationTag: sctp.VerificationTag(),
+			Stream:          data.Stream,
+		}
+		// 用户数据与整个报文共享底层数组，复制后缓存以免保留整个报文
+		data.UserData = slices.Clone(data.UserData)
+		reassembler.add(&fragment{key: key, frame: frame, timestamp: timestamp, data: data})
+	}
+}
+
+// 加入一个分片，收齐一条消息的全部分片时交付
+func (reassembler *Reassembler) add(f *fragment) {
+	statistics.Add(STATISTICS_GROUP, "Fragments", 1)
+	fragments := reassembler.streams[f.key]
+	if fragments == nil {
+		fragments = map[uint32]*fragment{}
+		reassembler.streams[f.key] = fragments
+	}
+	// 重传的分片：已缓存，或属于刚重组完成的消息
+	duplicate := fragments[f.data.TSN] != nil
+	if h := reassembler.recent[f.key]; h != nil {
+		h.last = f.timestamp
+		for _, r := range h.ranges {
+			duplicate = duplicate || r.contains(f.data.TSN)
+		}
+	}
+	if duplicate {
+		statistics.Add(STATISTICS_GROUP, "Duplicate fragments", 1)
+		return
+	}
+	fragments[f.data.TSN] = f
+	f.element = reassembler.order.PushBack(f)
+	reassembler.total += len(f.data.UserData)
+
+	run := reassembler.run(fragments, f)
+	if run[0].data.Begin && run[len(run)-1].data.End {
+		statistics.Add(STATISTICS_GROUP, "Reassembled messages", 1)
+		h := reassembler.recent[f.key]
+		if h == nil {
+			h = &history{}
+			reassembler.recent[f.key] = h
+		}
+		h.ranges = append(h.ranges, tsnRange{first: run[0].data.TSN, last: run[len(run)-1].data.TSN})
+		if len(h.ranges) > DELIVERED_HISTORY {
+			h.ranges = h.ranges[1:]
+		}
+		h.last = f.timestamp
+		reassembler.finish(run, DROPPED_NONE)
+		return
+	}
+
+	if reassembler.limits.MaxFragments > 0 {
+		for reassembler.order.Len() > reassembler.limits.MaxFragments {
+			reassembler.drop(reassembler.order.Front().Value.(*fragment), DROPPED_FRAGMENT_LIMIT)
+		}
+	}
+	if reassembler.limits.MaxBytes > 0 {
+		for reassembler.total > reassembler.limits.MaxBytes && reassembler.order.Len() > 0 {
+			reassembler.drop(reassembler.order.Front().Value.(*fragment), DROPPED_MEMORY_LIMIT)
+		}
+	}
+}
+
+// 分片所在的 TSN 连续的一组分片：向前找到第一个分片（或缺失处），再向后找到最后一个分片（或缺失处）
+func (reassembler *Reassembler) run(fragments map[uint32]*fragment, f *fragment) []*fragment {
+	first := f
+	for !first.data.Begin {
+		previous := fragments[first.data.TSN-1]
+		if previous == nil || previous.data.End || !previous.sameMessage(first) {
+			break
+		}
+		first = previous
+	}
+	run := []*fragment{first}
+	for last := first; !last.data.End; {
+		next := fragments[last.data.TSN+1]
+		if next == nil || next.data.Begin || !next.sameMessage(last) {
+			break
+		}
+		run = append(run, next)
+		last = next
+	}
+	return run
+}
+
+// 丢弃所有未重组完成的消息，在抓包结束时调用
+func (reassembler *Reassembler) FlushAll() {
+	for reassembler.order.Len() > 0 {
+		reassembler.drop(reassembler.order.Front().Value.(*fragment), DROPPED_CAPTURE_END)
+	}
+}
+
+// 丢弃超时的分片所在的消息，并定期清除没有待重组分片且空闲超时的流的记录
+func (reassembler *Reassembler) expire() {
+	if reassembler.timeout <= 0 {
+		return
+	}
+	deadline := reassembler.now.Add(-reassembler.timeout)
+	for reassembler.order.Len() > 0 {
+		f := reassembler.order.Front().Value.(*fragment)
+		if !f.timestamp.Before(deadline) {
+			break
+		}
+		reassembler.drop(f, DROPPED_TIMEOUT)
+	}
+
+	if reassembler.nextSweep.IsZero() {
+		reassembler.nextSweep = reassembler.now.Add(reassembler.timeout)
+	} else if reassembler.now.After(reassembler.nextSweep) {
+		for key, h := range reassembler.recent {
+			if reassembler.streams[key] == nil && h.last.Before(deadline) {
+				delete(reassembler.recent, key)
+			}
+		}
+		reassembler.nextSweep = reassembler.now.Add(reassembler.timeout)
+	}
+}
+
+// 丢弃分片所在的消息（TSN 连续的一组分片）
+func (reassembler *Reassembler) drop(f *fragment, reason DropReason) {
+	statistics.Add(STATISTICS_GROUP, "Dropped ("+DROP_REASON_NAME[reason]+")", 1)
+	reassembler.finish(reassembler.run(reassembler.streams[f.key], f), reason)
+}
+
+// 移除一组分片并作为一条消息交付给回调
+func (reassembler *Reassembler) finish(run []*fragment, reason DropReason) {
+	first := run[0]
+	message := &Message{
+		key:       first.key,
+		unordered: first.data.Unordered,
+		ssn:       first.data.SSN,
+		ppid:      first.data.PPID,
+		start:     first.timestamp,
+		end:       first.timestamp,
+		dropped:   reason,
+	}
+	fragments := reassembler.streams[first.key]
+	for _, f := range run {
+		message.fragments = append(message.fragments, Fragment{Frame: f.frame, TSN: f.data.TSN, Length: len(f.data.UserData)})
+		message.data = append(message.data, f.data.UserData...)
+		if f.timestamp.Before(message.start) {
+			message.start = f.timestamp
+		}
+		if f.timestamp.After(message.end) {
+			message.end = f.timestamp
+		}
+		reassembler.order.Remove(f.element)
+		reassembler.total -= len(f.data.UserData)
+		delete(fragments, f.data.TSN)
+	}
+	if len(fragments) == 0 {
+		delete(reassembler.streams, first.key)
+	}
+	if reassembler.complete != nil {
+		reassembler.complete(message)
+	}
+}
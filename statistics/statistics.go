@@ -0,0 +1,75 @@
+package statistics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var mutex sync.Mutex
+
+// 分组计数器，group -> key -> count
+var counters = map[string]map[string]uint64{}
+
+// 累加计数
+func Add(group string, key string, delta uint64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if counters[group] == nil {
+		counters[group] = map[string]uint64{}
+	}
+	counters[group][key] += delta
+}
+
+// 读取计数
+func Get(group string, key string) uint64 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return counters[group][key]
+}
+
+// 转换为可读字符串
+func ToReadableString(indent int) string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	builder := new(strings.Builder)
+	tabs := make([]byte, indent)
+	for i := range indent {
+		tabs[i] = '\t'
+	}
+
+	groups := make([]string, 0, len(counters))
+	for group := range counters {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		builder.Write(tabs)
+		builder.WriteString(group)
+		builder.WriteString(": {\n")
+
+		keys := make([]string, 0, len(counters[group]))
+		for key := range counters[group] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			builder.Write(tabs)
+			builder.WriteByte('\t')
+			builder.WriteString(key)
+			builder.WriteString(": ")
+			builder.WriteString(strconv.FormatUint(counters[group][key], 10))
+			builder.WriteByte('\n')
+		}
+
+		builder.Write(tabs)
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,501 @@
+package tcpanalysis
+
+import (
+	"container/heap"
+	"container/list"
+	"packet-inspector/resolver"
+	"packet-inspector/statistics"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	transportlayer "packet-inspector/resolver/transport-layer"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+const STATISTICS_GROUP = "TCP analysis"
+
+// 最多同时跟踪的连接数量，达到该值时清除空闲的连接，仍不足时淘汰最久未活动的连接
+const MAX_CONNECTIONS = 65536
+
+// 已清除的连接中最多保留摘要的数量，超出时只保留问题最多（其次报文最多）的连接，其余并入合计
+const MAX_RETAINED = 1024
+
+// 超过该时长没有报文的连接视为空闲
+const IDLE_TIMEOUT = 5 * time.Minute
+
+// 未得到握手往返时间时，区分乱序与重传的时间阈值
+const OUT_OF_ORDER_THRESHOLD = 3 * time.Millisecond
+
+// 连接的一个方向，由发送方指向接收方
+type direction struct {
+	net       gopacket.Flow
+	transport gopacket.Flow
+}
+
+func (key direction) reverse() direction {
+	return direction{net: key.net.Reverse(), transport: key.transport.Reverse()}
+}
+
+// 以 "地址:端口" 表示的端点
+func endpoint(address gopacket.Endpoint, port gopacket.Endpoint) string {
+	if address.EndpointType() == layers.EndpointIPv6 {
+		return "[" + address.String() + "]:" + port.String()
+	}
+	return address.String() + ":" + port.String()
+}
+
+// 序号 a 是否在 b 之后（考虑回绕）
+func after(a uint32, b uint32) bool {
+	return int32(a-b) > 0
+}
+
+// 连接的一个方向的状态
+type half struct {
+	offered  int       // 该方向 SYN 中的窗口扩大选项，-1 表示没有该选项
+	scale    int       // 协商后的窗口扩大因子，-1 表示未知
+	seen     bool      // 是否已看到该方向的报文
+	nextSeq  uint32    // 该方向已发送的最大序号之后的序号
+	acked    bool      // 是否已看到该方向的 ACK
+	lastAck  uint32    // 最近一次的确认序号
+	window   uint16    // 最近一次的窗口字段
+	dupAcks  int       // 连续的重复 ACK 数
+	probed   bool      // 最近一个报文是否为保活探测，对端随后的 ACK 不算重复 ACK
+	lastData time.Time // 最近一次发送数据的抓包时间
+	bytes    int       // 发送的载荷字节数
+	segments int       // 发送的报文数
+}
+
+// 按连接汇总的分析结果
+type Connection struct {
+	element    *list.Element
+	net        gopacket.Flow // 客户端到服务端的网络层地址
+	transport  gopacket.Flow // 客户端到服务端的传输层端口
+	client     half          // 客户端发往服务端方向的状态
+	server     half          // 服务端发往客户端方向的状态
+	isn        uint32        // 客户端 SYN 的序号
+	syn        time.Time     // 客户端 SYN 的抓包时间，未看到 SYN 时为零值
+	synAck     time.Time     // 服务端 SYN+ACK 的抓包时间
+	handshaken bool          // 是否已看到完成握手的 ACK
+	rtt        time.Duration // 握手往返时间（SYN 到完成握手的 ACK）
+	data       bool          // 是否已传输过数据
+	counts     map[uint8]int // 各类问题出现的次数
+	first      time.Time     // 第一个报文的抓包时间
+	last       time.Time     // 最近一个报文的抓包时间
+	firstFrame int
+	lastFrame  int
+}
+
+// 以 "客户端地址:端口 -> 服务端地址:端口" 表示的连接
+func (connection *Connection) Tuple() string {
+	return endpoint(connection.net.Src(), connection.transport.Src()) + " -> " + endpoint(connection.net.Dst(), connection.transport.Dst())
+}
+
+// 握手往返时间，未看到完整握手时返回 false
+func (connection *Connection) HandshakeRTT() (time.Duration, bool) {
+	return connection.rtt, connection.handshaken
+}
+
+// 指定问题出现的次数
+func (connection *Connection) Count(analysis uint8) int {
+	return connection.counts[analysis]
+}
+
+// 各类问题出现的总次数
+func (connection *Connection) Problems() int {
+	problems := 0
+	for _, count := range connection.counts {
+		problems += count
+	}
+	return problems
+}
+
+func (connection *Connection) Packets() int {
+	return connection.client.segments + connection.server.segments
+}
+
+func (connection *Connection) Bytes() int {
+	return connection.client.bytes + connection.server.bytes
+}
+
+func (connection *Connection) First() time.Time {
+	return connection.first
+}
+
+func (connection *Connection) Last() time.Time {
+	return connection.last
+}
+
+func (connection *Connection) FirstFrame() int {
+	return connection.firstFrame
+}
+
+func (connection *Connection) LastFrame() int {
+	return connection.lastFrame
+}
+
+// 格式化为一行摘要，如 "10.0.0.1:40000 -> 10.0.0.2:80, 12 packets, 3000 bytes, handshake RTT 1ms, Retransmission: 2"
+func (connection *Connection) ToString() string {
+	builder := new(strings.Builder)
+	builder.WriteString(connection.Tuple())
+	builder.WriteString(", ")
+	builder.WriteString(strconv.Itoa(connection.Packets()))
+	builder.WriteString(" packets, ")
+	builder.WriteString(strconv.Itoa(connection.Bytes()))
+	builder.WriteString(" bytes, ")
+	if connection.handshaken {
+		builder.WriteString("handshake RTT ")
+		builder.WriteString(connection.rtt.String())
+	} else {
+		builder.WriteString("handshake not seen")
+	}
+	for analysis := range uint8(len(transportlayer.TCP_ANALYSIS_NAME)) {
+		if count := connection.counts[analysis]; count != 0 {
+			builder.WriteString(", ")
+			builder.WriteString(transportlayer.TCP_ANALYSIS_NAME[analysis])
+			builder.WriteString(": ")
+			builder.WriteString(strconv.Itoa(count))
+		}
+	}
+	return builder.String()
+}
+
+// 报文所在方向的状态，以及相反方向的状态
+func (connection *Connection) halves(key direction) (*half, *half) {
+	if key.net == connection.net && key.transport == connection.transport {
+		return &connection.client, &connection.server
+	}
+	return &connection.server, &connection.client
+}
+
+// 分析一个报文段，更新发送方向的状态并返回发现的问题
+func (connection *Connection) analyze(sender *half, receiver *half, tcp *transportlayer.TCP, timestamp time.Time) []uint8 {
+	var findings []uint8
+	length := uint32(len(tcp.Payload()))
+	sequence := tcp.Sequence()
+	end := sequence + length
+	// SYN 与 FIN 各占用一个序号
+	if tcp.SYN() {
+		end++
+	}
+	if tcp.FIN() {
+		end++
+	}
+
+	sender.segments++
+	if tcp.RST() {
+		if connection.data {
+			findings = append(findings, transportlayer.TCP_ANALYSIS_RST_AFTER_DATA)
+		}
+		return findings
+	}
+
+	if tcp.Window() == 0 && !tcp.SYN() && !tcp.FIN() {
+		findings = append(findings, transportlayer.TCP_ANALYSIS_ZERO_WINDOW)
+	}
+
+	sender.probed = false
+	if sender.seen && length <= 1 && !tcp.SYN() && !tcp.FIN() && sequence == sender.nextSeq-1 {
+		// 保活探测重发已确认的最后一个字节（或不带数据）
+		findings = append(findings, transportlayer.TCP_ANALYSIS_KEEP_ALIVE)
+		sender.probed = true
+	} else if sender.seen && end != sequence && after(sender.nextSeq, sequence) {
+		threshold := OUT_OF_ORDER_THRESHOLD
+		if connection.handshaken {
+			threshold = connection.rtt
+		}
+		switch {
+		case receiver.dupAcks >= 2 && receiver.lastAck == sequence:
+			findings = append(findings, transportlayer.TCP_ANALYSIS_FAST_RETRANSMISSION)
+		case !sender.lastData.IsZero() && timestamp.Sub(sender.lastData) < threshold:
+			findings = append(findings, transportlayer.TCP_ANALYSIS_OUT_OF_ORDER)
+		default:
+			findings = append(findings, transportlayer.TCP_ANALYSIS_RETRANSMISSION)
+		}
+	} else if length != 0 && receiver.acked && receiver.scale >= 0 && receiver.window != 0 &&
+		end == receiver.lastAck+uint32(receiver.window)<<receiver.scale {
+		// 数据恰好填满对端通告的窗口
+		findings = append(findings, transportlayer.TCP_ANALYSIS_WINDOW_FULL)
+	}
+
+	if tcp.ACK() && length == 0 && !tcp.SYN() && !tcp.FIN() && !sender.probed {
+		if sender.acked && tcp.Acknowledgment() == sender.lastAck && tcp.Window() == sender.window &&
+			tcp.Window() != 0 && !receiver.probed {
+			sender.dupAcks++
+			findings = append(findings, transportlayer.TCP_ANALYSIS_DUPLICATE_ACK)
+		} else {
+			sender.dupAcks = 0
+		}
+	} else if tcp.ACK() && tcp.Acknowledgment() != sender.lastAck {
+		sender.dupAcks = 0
+	}
+	receiver.probed = false
+
+	if tcp.ACK() {
+		sender.lastAck, sender.acked = tcp.Acknowledgment(), true
+	}
+	sender.window = tcp.Window()
+	if !sender.seen || after(end, sender.nextSeq) {
+		sender.nextSeq = end
+	}
+	sender.seen = true
+	if length != 0 {
+		connection.data = true
+		sender.lastData = timestamp
+		sender.bytes += int(length)
+	}
+	return findings
+}
+
+// 一个 TCP 报文的分析结果
+type Result struct {
+	WindowScale  int           // 报文所在方向的窗口扩大因子，-1 表示未知
+	Findings     []uint8       // 发现的问题，取值为 transportlayer.TCP_ANALYSIS_*
+	HandshakeRTT time.Duration // 报文完成握手时为握手往返时间，否则为 0
+}
+
+// 把分析结果写入已解析的报文中的第一个 TCP 报文
+func (result Result) Apply(packet resolver.IPacket) {
+	for packet != nil {
+		if tcp, ok := packet.(*transportlayer.TCP); ok {
+			tcp.SetWindowScale(result.WindowScale)
+			tcp.SetAnalysis(result.Findings, result.HandshakeRTT)
+			return
+		}
+		container, ok := packet.(resolver.IContainer)
+		if !ok {
+			return
+		}
+		packet = container.Inner()
+	}
+}
+
+// 未保留摘要的已清除连接的合计
+type Omitted struct {
+	connections int
+	packets     int
+	bytes       int
+	counts      map[uint8]int // 各类问题出现的次数
+}
+
+func (omitted *Omitted) Connections() int {
+	return omitted.connections
+}
+
+// 并入一个连接
+func (omitted *Omitted) add(connection *Connection) {
+	omitted.connections++
+	omitted.packets += connection.Packets()
+	omitted.bytes += connection.Bytes()
+	for analysis, count := range connection.counts {
+		omitted.counts[analysis] += count
+	}
+}
+
+// 格式化为一行摘要，如 "3 more connections, 30 packets, 6000 bytes, Retransmission: 1"
+func (omitted *Omitted) ToString() string {
+	builder := new(strings.Builder)
+	builder.WriteString(strconv.Itoa(omitted.connections))
+	builder.WriteString(" more connections, ")
+	builder.WriteString(strconv.Itoa(omitted.packets))
+	builder.WriteString(" packets, ")
+	builder.WriteString(strconv.Itoa(omitted.bytes))
+	builder.WriteString(" bytes")
+	for analysis := range uint8(len(transportlayer.TCP_ANALYSIS_NAME)) {
+		if count := omitted.counts[analysis]; count != 0 {
+			builder.WriteString(", ")
+			builder.WriteString(transportlayer.TCP_ANALYSIS_NAME[analysis])
+			builder.WriteString(": ")
+			builder.WriteString(strconv.Itoa(count))
+		}
+	}
+	return builder.String()
+}
+
+// 已清除连接的堆，堆顶为问题最少（其次报文最少）的连接
+type retainedHeap []*Connection
+
+func (h retainedHeap) Len() int {
+	return len(h)
+}
+
+func (h retainedHeap) Less(i, j int) bool {
+	a, b := h[i].Problems(), h[j].Problems()
+	if a != b {
+		return a < b
+	}
+	return h[i].Packets() < h[j].Packets()
+}
+
+func (h retainedHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *retainedHeap) Push(x any) {
+	*h = append(*h, x.(*Connection))
+}
+
+func (h *retainedHeap) Pop() any {
+	old := *h
+	connection := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return connection
+}
+
+// 按连接跟踪 TCP 握手、序号与确认号的分析器，报文必须按抓包顺序送入
+type Analyzer struct {
+	connections map[direction]*Connection // 以两个方向分别索引的正在跟踪的连接
+	order       *list.List                // 按活动时间排序的正在跟踪的连接，表头为最近活动的连接
+	retained    retainedHeap              // 保留摘要的已清除连接
+	omitted     Omitted                   // 未保留摘要的已清除连接的合计
+}
+
+func New() *Analyzer {
+	return &Analyzer{connections: map[direction]*Connection{}, order: list.New(), omitted: Omitted{counts: map[uint8]int{}}}
+}
+
+// 按出现顺序排列的正在跟踪的连接与保留摘要的已清除连接
+func (analyzer *Analyzer) Connections() []*Connection {
+	connections := slices.Clone([]*Connection(analyzer.retained))
+	for element := analyzer.order.Front(); element != nil; element = element.Next() {
+		connections = append(connections, element.Value.(*Connection))
+	}
+	slices.SortFunc(connections, func(a, b *Connection) int {
+		return a.firstFrame - b.firstFrame
+	})
+	return connections
+}
+
+// 未保留摘要的已清除连接的合计
+func (analyzer *Analyzer) Omitted() *Omitted {
+	return &analyzer.omitted
+}
+
+// 分析一个报文，frame 为其帧序号，非 TCP 报文返回窗口扩大因子未知的结果
+func (analyzer *Analyzer) Inspect(frame int, packet gopacket.Packet) Result {
+	result := Result{WindowScale: -1}
+	layer := packet.Layer(layers.LayerTypeTCP)
+	if layer == nil || packet.NetworkLayer() == nil {
+		return result
+	}
+	// 载荷长度决定序号的推进，需与首部一起解析
+	contents := append(slices.Clone(layer.LayerContents()), layer.LayerPayload()...)
+	tcp, ok := transportlayer.TCPResolve(contents).(*transportlayer.TCP)
+	if !ok {
+		return result
+	}
+	timestamp := packet.Metadata().Timestamp
+	key := direction{net: packet.NetworkLayer().NetworkFlow(), transport: layer.(*layers.TCP).TransportFlow()}
+	connection := analyzer.connection(frame, key, tcp, timestamp)
+	connection.last, connection.lastFrame = timestamp, frame
+	analyzer.order.MoveToFront(connection.element)
+	current, peer := connection.halves(key)
+
+	if tcp.SYN() {
+		current.offered = -1
+		if shift, ok := tcp.WindowScaleOption(); ok {
+			current.offered = int(shift)
+		}
+		if !tcp.ACK() {
+			// 新的握手重新协商窗口扩大
+			current.scale, peer.scale = -1, -1
+			if connection.syn.IsZero() {
+				connection.syn, connection.isn = timestamp, tcp.Sequence()
+			}
+		} else {
+			if connection.synAck.IsZero() {
+				connection.synAck = timestamp
+			}
+			// SYN+ACK 完成窗口扩大的协商：双方都带有该选项时才启用
+			if peer.seen && peer.scale < 0 {
+				if current.offered >= 0 && peer.offered >= 0 {
+					current.scale, peer.scale = current.offered, peer.offered
+				} else {
+					current.scale, peer.scale = 0, 0
+				}
+			} else if !peer.seen && current.offered < 0 {
+				// 没有看到 SYN，但 SYN+ACK 没有该选项说明没有启用窗口扩大
+				current.scale, peer.scale = 0, 0
+			}
+		}
+	} else if tcp.ACK() && !connection.handshaken && !connection.syn.IsZero() && !connection.synAck.IsZero() &&
+		current == &connection.client {
+		connection.handshaken = true
+		connection.rtt = timestamp.Sub(connection.syn)
+		result.HandshakeRTT = connection.rtt
+	}
+
+	result.WindowScale = current.scale
+	result.Findings = connection.analyze(current, peer, tcp, timestamp)
+	for _, analysis := range result.Findings {
+		connection.counts[analysis]++
+		statistics.Add(STATISTICS_GROUP, transportlayer.TCP_ANALYSIS_NAME[analysis], 1)
+	}
+	return result
+}
+
+// 取得或创建报文所属的连接，客户端发起新的握手（SYN 的序号不同）时创建新的连接
+func (analyzer *Analyzer) connection(frame int, key direction, tcp *transportlayer.TCP, timestamp time.Time) *Connection {
+	connection := analyzer.connections[key]
+	if connection != nil && tcp.SYN() && !tcp.ACK() && !connection.syn.IsZero() && tcp.Sequence() != connection.isn {
+		analyzer.remove(connection)
+		connection = nil
+	}
+	if connection != nil {
+		return connection
+	}
+
+	if analyzer.order.Len() >= MAX_CONNECTIONS {
+		analyzer.prune(timestamp)
+	}
+	// 发送 SYN+ACK 的一方是服务端，其余情况以第一个报文的发送方为客户端
+	client := key
+	if tcp.SYN() && tcp.ACK() {
+		client = key.reverse()
+	}
+	connection = &Connection{
+		net:        client.net,
+		transport:  client.transport,
+		client:     half{offered: -1, scale: -1},
+		server:     half{offered: -1, scale: -1},
+		counts:     map[uint8]int{},
+		first:      timestamp,
+		firstFrame: frame,
+	}
+	statistics.Add(STATISTICS_GROUP, "Connections", 1)
+	connection.element = analyzer.order.PushFront(connection)
+	analyzer.connections[key] = connection
+	analyzer.connections[key.reverse()] = connection
+	return connection
+}
+
+// 清除空闲的连接，仍达到上限时淘汰最久未活动的连接
+func (analyzer *Analyzer) prune(timestamp time.Time) {
+	for analyzer.order.Len() > 0 {
+		connection := analyzer.order.Back().Value.(*Connection)
+		if timestamp.Sub(connection.last) <= IDLE_TIMEOUT {
+			break
+		}
+		analyzer.remove(connection)
+	}
+	for analyzer.order.Len() >= MAX_CONNECTIONS {
+		analyzer.remove(analyzer.order.Back().Value.(*Connection))
+		statistics.Add(STATISTICS_GROUP, "Evicted (connection limit)", 1)
+	}
+}
+
+// 停止跟踪连接，超出保留数量时把最不重要的已清除连接并入合计
+func (analyzer *Analyzer) remove(connection *Connection) {
+	key := direction{net: connection.net, transport: connection.transport}
+	delete(analyzer.connections, key)
+	delete(analyzer.connections, key.reverse())
+	analyzer.order.Remove(connection.element)
+	heap.Push(&analyzer.retained, connection)
+	if analyzer.retained.Len() > MAX_RETAINED {
+		analyzer.omitted.add(heap.Pop(&analyzer.retained).(*Connection))
+	}
+}
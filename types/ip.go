@@ -0,0 +1,287 @@
+package types
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const (
+	IP_CLASS_GLOBAL      = "global"
+	IP_CLASS_PRIVATE     = "private"
+	IP_CLASS_LOOPBACK    = "loopback"
+	IP_CLASS_MULTICAST   = "multicast"
+	IP_CLASS_LINK_LOCAL  = "link-local"
+	IP_CLASS_BROADCAST   = "broadcast"
+	IP_CLASS_UNSPECIFIED = "unspecified"
+)
+
+// IPv4 或 IPv6 地址
+type IP interface {
+	Bytes() []byte
+	ToString() string
+	IsPrivate() bool
+	IsLoopback() bool
+	IsMulticast() bool
+	IsLinkLocal() bool
+	IsUnspecified() bool
+	Classify() string
+}
+
+type IPv4 struct {
+	address [4]byte
+}
+
+// 解析 IPv4 地址
+func (ip *IPv4) Parse(addr [4]byte) {
+	ip.address = addr
+}
+
+func (ip IPv4) Bytes() []byte {
+	return ip.address[:]
+}
+
+// 格式化为点分十进制字符串
+func (ip IPv4) ToString() string {
+	builder := new(strings.Builder)
+	for i, b := range ip.address {
+		if i != 0 {
+			builder.WriteByte('.')
+		}
+		builder.WriteString(strconv.Itoa(int(b)))
+	}
+	return builder.String()
+}
+
+// 10.0.0.0/8、172.16.0.0/12、192.168.0.0/16
+func (ip IPv4) IsPrivate() bool {
+	return ip.address[0] == 10 ||
+		(ip.address[0] == 172 && ip.address[1]&0xF0 == 16) ||
+		(ip.address[0] == 192 && ip.address[1] == 168)
+}
+
+// 127.0.0.0/8
+func (ip IPv4) IsLoopback() bool {
+	return ip.address[0] == 127
+}
+
+// 224.0.0.0/4
+func (ip IPv4) IsMulticast() bool {
+	return ip.address[0]&0xF0 == 0xE0
+}
+
+// 169.254.0.0/16
+func (ip IPv4) IsLinkLocal() bool {
+	return ip.address[0] == 169 && ip.address[1] == 254
+}
+
+// 255.255.255.255
+func (ip IPv4) IsBroadcast() bool {
+	return ip.address == [4]byte{255, 255, 255, 255}
+}
+
+// 0.0.0.0
+func (ip IPv4) IsUnspecified() bool {
+	return ip.address == [4]byte{}
+}
+
+// 地址类别，IP_CLASS_* 之一
+func (ip IPv4) Classify() string {
+	switch {
+	case ip.IsUnspecified():
+		return IP_CLASS_UNSPECIFIED
+	case ip.IsBroadcast():
+		return IP_CLASS_BROADCAST
+	case ip.IsLoopback():
+		return IP_CLASS_LOOPBACK
+	case ip.IsMulticast():
+		return IP_CLASS_MULTICAST
+	case ip.IsLinkLocal():
+		return IP_CLASS_LINK_LOCAL
+	case ip.IsPrivate():
+		return IP_CLASS_PRIVATE
+	}
+	return IP_CLASS_GLOBAL
+}
+
+type IPv6 struct {
+	address [16]byte
+}
+
+// 解析 IPv6 地址
+func (ip *IPv6) Parse(addr [16]byte) {
+	ip.address = addr
+}
+
+func (ip IPv6) Bytes() []byte {
+	return ip.address[:]
+}
+
+// IPv4 映射地址 ::ffff:0:0/96
+func (ip IPv6) IsIPv4Mapped() bool {
+	return ip.address[10] == 0xFF && ip.address[11] == 0xFF && [10]byte(ip.address[0:10]) == [10]byte{}
+}
+
+// 按 RFC 5952 格式化：小写、省略前导零、最长的连续零组（至少两组）压缩为 "::"，
+// IPv4 映射地址的最后 32 bit 以点分十进制表示
+func (ip IPv6) ToString() string {
+	groups := 8
+	if ip.IsIPv4Mapped() {
+		groups = 6
+	}
+
+	// 查找最长的连续零组，长度相同时取第一个
+	start, length := -1, 0
+	for i := 0; i < groups; {
+		if ip.address[i*2] != 0 || ip.address[i*2+1] != 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < groups && ip.address[j*2] == 0 && ip.address[j*2+1] == 0 {
+			j++
+		}
+		if j-i > length {
+			start, length = i, j-i
+		}
+		i = j
+	}
+	if length < 2 {
+		start = -1
+	}
+
+	builder := new(strings.Builder)
+	for i := 0; i < groups; i++ {
+		if i == start {
+			builder.WriteString("::")
+			i += length - 1
+			continue
+		}
+		if i != 0 && i != start+length {
+			builder.WriteByte(':')
+		}
+		builder.WriteString(strconv.FormatUint(uint64(ip.address[i*2])<<8|uint64(ip.address[i*2+1]), 16))
+	}
+	if groups == 6 {
+		if start+length != groups {
+			builder.WriteByte(':')
+		}
+		builder.WriteString(IPv4{address: [4]byte(ip.address[12:16])}.ToString())
+	}
+	return builder.String()
+}
+
+// fc00::/7 唯一本地地址
+func (ip IPv6) IsPrivate() bool {
+	return ip.address[0]&0xFE == 0xFC
+}
+
+// ::1
+func (ip IPv6) IsLoopback() bool {
+	return ip.address == [16]byte{15: 1}
+}
+
+// ff00::/8
+func (ip IPv6) IsMulticast() bool {
+	return ip.address[0] == 0xFF
+}
+
+// fe80::/10
+func (ip IPv6) IsLinkLocal() bool {
+	return ip.address[0] == 0xFE && ip.address[1]&0xC0 == 0x80
+}
+
+// ::
+func (ip IPv6) IsUnspecified() bool {
+	return ip.address == [16]byte{}
+}
+
+// 地址类别，IP_CLASS_* 之一
+func (ip IPv6) Classify() string {
+	switch {
+	case ip.IsUnspecified():
+		return IP_CLASS_UNSPECIFIED
+	case ip.IsLoopback():
+		return IP_CLASS_LOOPBACK
+	case ip.IsMulticast():
+		return IP_CLASS_MULTICAST
+	case ip.IsLinkLocal():
+		return IP_CLASS_LINK_LOCAL
+	case ip.IsPrivate():
+		return IP_CLASS_PRIVATE
+	case ip.IsIPv4Mapped():
+		return IPv4{address: [4]byte(ip.address[12:16])}.Classify()
+	}
+	return IP_CLASS_GLOBAL
+}
+
+// 从字符串解析 IPv4 或 IPv6 地址，不接受带有区域标识的地址
+func ParseIP(s string) (IP, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return nil, err
+	}
+	if addr.Zone() != "" {
+		return nil, errors.New("types: IP address with zone " + strconv.Quote(s))
+	}
+	if addr.Is4() {
+		return IPv4{address: addr.As4()}, nil
+	}
+	return IPv6{address: addr.As16()}, nil
+}
+
+// CIDR 网段
+type Prefix struct {
+	address []byte // 网络地址，主机部分已清零
+	bits    int    // 前缀长度
+}
+
+// 从 CIDR 字符串解析网段，如 "10.0.0.0/8" 或 "2001:db8::/32"，
+// 不带前缀长度的地址视为只包含该地址的网段
+func ParsePrefix(s string) (Prefix, error) {
+	if !strings.Contains(s, "/") {
+		ip, err := ParseIP(s)
+		if err != nil {
+			return Prefix{}, err
+		}
+		return Prefix{address: ip.Bytes(), bits: len(ip.Bytes()) * 8}, nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return Prefix{}, err
+	}
+	if prefix.Addr().Zone() != "" {
+		return Prefix{}, errors.New("types: prefix with zone " + strconv.Quote(s))
+	}
+	return Prefix{address: prefix.Masked().Addr().AsSlice(), bits: prefix.Bits()}, nil
+}
+
+// 网段是否包含该地址，IPv4 网段不包含 IPv6 地址，反之亦然
+func (prefix Prefix) Contains(ip IP) bool {
+	address := ip.Bytes()
+	if len(address) != len(prefix.address) {
+		return false
+	}
+	for i := 0; i < prefix.bits; i += 8 {
+		mask := byte(0xFF)
+		if prefix.bits-i < 8 {
+			mask <<= 8 - (prefix.bits - i)
+		}
+		if address[i/8]&mask != prefix.address[i/8] {
+			return false
+		}
+	}
+	return true
+}
+
+// 格式化为 CIDR 字符串
+func (prefix Prefix) ToString() string {
+	var ip IP
+	if len(prefix.address) == 4 {
+		ip = IPv4{address: [4]byte(prefix.address)}
+	} else {
+		ip = IPv6{address: [16]byte(prefix.address)}
+	}
+	return ip.ToString() + "/" + strconv.Itoa(prefix.bits)
+}
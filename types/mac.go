@@ -13,7 +13,80 @@ func (mac *Mac) Parse(addr [6]byte) {
 	copy(mac.id[:], addr[3:6])
 }
 
+// 从字符串解析 MAC 地址，可用 ':'、'-' 或 '.' 分隔
+func ParseMac(s string) (Mac, error) {
+	mac := Mac{}
+	address, err := parseMacBytes(s)
+	if err != nil {
+		return mac, err
+	}
+	if len(address) != 6 {
+		return mac, fmt.Errorf("invalid MAC address %q", s)
+	}
+	mac.Parse([6]byte(address))
+	return mac, nil
+}
+
 // 格式化为字符串
 func (mac *Mac) ToString() string {
 	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", mac.maker[0], mac.maker[1], mac.maker[2], mac.id[0], mac.id[1], mac.id[2])
 }
+
+// 格式化为带名称的字符串：用户自定义的设备名称，或 "厂商简称_后三字节"，如 "Vector_12:34:56"；
+// 广播地址为 "Broadcast"，其余无法命名的地址与 ToString 相同
+func (mac *Mac) ToNamedString() string {
+	if name := macNames[mac.Bytes()]; name != "" {
+		return name
+	}
+	if mac.IsBroadcast() {
+		return "Broadcast"
+	}
+	if mac.IsUniversal() && mac.IsUnicast() {
+		if vendor := LookupVendorShort(mac.maker); vendor != "" {
+			return fmt.Sprintf("%s_%02X:%02X:%02X", vendor, mac.id[0], mac.id[1], mac.id[2])
+		}
+	}
+	return mac.ToString()
+}
+
+// 厂商的完整名称，本地管理的地址与未知厂商返回空字符串
+func (mac *Mac) Vendor() string {
+	if mac.IsLocal() {
+		return ""
+	}
+	return LookupVendor(mac.maker)
+}
+
+// 组织唯一标识符（前三字节）
+func (mac *Mac) OUI() [3]byte {
+	return mac.maker
+}
+
+func (mac *Mac) Bytes() [6]byte {
+	return [6]byte{mac.maker[0], mac.maker[1], mac.maker[2], mac.id[0], mac.id[1], mac.id[2]}
+}
+
+// FF:FF:FF:FF:FF:FF
+func (mac *Mac) IsBroadcast() bool {
+	return mac.maker == [3]byte{0xFF, 0xFF, 0xFF} && mac.id == [3]byte{0xFF, 0xFF, 0xFF}
+}
+
+// I/G 位为 1 的组播地址（包括广播地址）
+func (mac *Mac) IsMulticast() bool {
+	return mac.maker[0]&0x01 != 0
+}
+
+// I/G 位为 0 的单播地址
+func (mac *Mac) IsUnicast() bool {
+	return !mac.IsMulticast()
+}
+
+// U/L 位为 1 的本地管理地址
+func (mac *Mac) IsLocal() bool {
+	return mac.maker[0]&0x02 != 0
+}
+
+// U/L 位为 0 的全球唯一地址，前三字节为 IEEE 分配的 OUI
+func (mac *Mac) IsUniversal() bool {
+	return !mac.IsLocal()
+}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gopacket/gopacket/macs"
+)
+
+// 用户自定义的 MAC 地址名称，完整地址 -> 名称
+var macNames = map[[6]byte]string{}
+
+// 用户自定义的厂商名称，OUI -> 名称，优先于内置的 IEEE OUI 数据库
+var vendorNames = map[[3]byte]string{}
+
+// 查询厂商的完整名称，未知时返回空字符串
+func LookupVendor(oui [3]byte) string {
+	if name := vendorNames[oui]; name != "" {
+		return name
+	}
+	return macs.ValidMACPrefixMap[oui]
+}
+
+// 查询厂商的简称，用于 "Vector_12:34:56" 格式，未知时返回空字符串
+func LookupVendorShort(oui [3]byte) string {
+	if name := vendorNames[oui]; name != "" {
+		return name
+	}
+	return shortVendorName(macs.ValidMACPrefixMap[oui])
+}
+
+// 取厂商名称的第一个单词作为简称，如 "Vector Informatik GmbH" -> "Vector"
+func shortVendorName(name string) string {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimRight(fields[0], ",.;:")
+}
+
+// 读取用户自定义的名称文件，每行一个 "地址 名称"：
+// 完整的 6 字节地址为单个设备命名，3 字节的 OUI 为厂商命名；# 之后为注释。
+// 地址可用 ':'、'-' 或 '.' 分隔，也可不分隔
+func LoadMacNames(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return errors.New(path + ":" + strconv.Itoa(line) + ": missing name")
+		}
+		address, err := parseMacBytes(fields[0])
+		if err != nil {
+			return errors.New(path + ":" + strconv.Itoa(line) + ": " + err.Error())
+		}
+		name := strings.Join(fields[1:], " ")
+		switch len(address) {
+		case 3:
+			vendorNames[[3]byte(address)] = name
+		case 6:
+			macNames[[6]byte(address)] = name
+		default:
+			return errors.New(path + ":" + strconv.Itoa(line) + ": address must be 3 or 6 bytes")
+		}
+	}
+	return scanner.Err()
+}
+
+// 解析以 ':'、'-' 或 '.' 分隔（或不分隔）的 16 进制地址
+func parseMacBytes(s string) ([]byte, error) {
+	digits := strings.NewReplacer(":", "", "-", "", ".", "").Replace(s)
+	address, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, errors.New("invalid address " + strconv.Quote(s))
+	}
+	return address, nil
+}
@@ -0,0 +1,348 @@
+package udpflow
+
+import (
+	"container/heap"
+	"container/list"
+	"packet-inspector/resolver"
+	"packet-inspector/statistics"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	applicationlayer "packet-inspector/resolver/application-layer"
+	transportlayer "packet-inspector/resolver/transport-layer"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+const STATISTICS_GROUP = "UDP flows"
+
+// 最多同时跟踪的流数量，达到该值时清除空闲的流，仍不足时淘汰最久未活动的流
+const MAX_FLOWS = 65536
+
+// 已结束跟踪的流中最多保留摘要的数量，超出时只保留报文最多的流，其余并入合计
+const MAX_RETAINED = 1024
+
+// 流的一个方向，由发送方指向接收方
+type direction struct {
+	net       gopacket.Flow
+	transport gopacket.Flow
+}
+
+func (key direction) reverse() direction {
+	return direction{net: key.net.Reverse(), transport: key.transport.Reverse()}
+}
+
+// 以 "地址:端口" 表示的端点
+func endpoint(address gopacket.Endpoint, port gopacket.Endpoint) string {
+	if address.EndpointType() == layers.EndpointIPv6 {
+		return "[" + address.String() + "]:" + port.String()
+	}
+	return address.String() + ":" + port.String()
+}
+
+// 按五元组划分的 UDP 流，第一个报文的发送方为发起方
+type Flow struct {
+	element         *list.Element
+	index           int
+	net             gopacket.Flow // 发起方到响应方的网络层地址
+	transport       gopacket.Flow // 发起方到响应方的传输层端口
+	forwardPackets  int
+	backwardPackets int
+	forwardBytes    int
+	backwardBytes   int
+	trackers        map[string]applicationlayer.FlowTracker // 按协议名索引的应用层跟踪器
+	protocols       []string                                // 按出现顺序排列的应用层协议
+	first           time.Time
+	last            time.Time
+	firstFrame      int
+	lastFrame       int
+	summary         string // 结束跟踪（超时或被淘汰）时的摘要，此后不再保留应用层跟踪器
+}
+
+// 流序号，从 0 开始
+func (flow *Flow) Index() int {
+	return flow.index
+}
+
+// 以 "发起方地址:端口 -> 响应方地址:端口" 表示的流
+func (flow *Flow) Tuple() string {
+	return endpoint(flow.net.Src(), flow.transport.Src()) + " -> " + endpoint(flow.net.Dst(), flow.transport.Dst())
+}
+
+func (flow *Flow) Packets() int {
+	return flow.forwardPackets + flow.backwardPackets
+}
+
+func (flow *Flow) Bytes() int {
+	return flow.forwardBytes + flow.backwardBytes
+}
+
+func (flow *Flow) First() time.Time {
+	return flow.first
+}
+
+func (flow *Flow) Last() time.Time {
+	return flow.last
+}
+
+func (flow *Flow) FirstFrame() int {
+	return flow.firstFrame
+}
+
+func (flow *Flow) LastFrame() int {
+	return flow.lastFrame
+}
+
+// 格式化为一行摘要，如 "#0 10.0.0.1:5000 -> 10.0.0.2:6000, 4 packets (2 from initiator, 2 from responder), 60 bytes, duration 2ms, PieP: ..."
+func (flow *Flow) ToString() string {
+	if flow.summary != "" {
+		return flow.summary
+	}
+	builder := new(strings.Builder)
+	builder.WriteString("#")
+	builder.WriteString(strconv.Itoa(flow.index))
+	builder.WriteString(" ")
+	builder.WriteString(flow.Tuple())
+	builder.WriteString(", ")
+	builder.WriteString(strconv.Itoa(flow.Packets()))
+	builder.WriteString(" packets (")
+	builder.WriteString(strconv.Itoa(flow.forwardPackets))
+	builder.WriteString(" from initiator, ")
+	builder.WriteString(strconv.Itoa(flow.backwardPackets))
+	builder.WriteString(" from responder), ")
+	builder.WriteString(strconv.Itoa(flow.Bytes()))
+	builder.WriteString(" bytes, duration ")
+	builder.WriteString(flow.last.Sub(flow.first).String())
+	for _, protocol := range flow.protocols {
+		builder.WriteString(", ")
+		builder.WriteString(flow.trackers[protocol].Summary())
+	}
+	return builder.String()
+}
+
+// 一个 UDP 报文的跟踪结果
+type Result struct {
+	Flow  *transportlayer.UDPFlow   // 报文所属的流，非 UDP 报文为 nil
+	Note  applicationlayer.FlowNote // 应用层跟踪器对载荷的注解
+	Noted bool                      // 载荷是否经过应用层跟踪器
+}
+
+// 把跟踪结果写入已解析的报文中的第一个 UDP 报文及其载荷
+func (result Result) Apply(packet resolver.IPacket) {
+	if result.Flow == nil {
+		return
+	}
+	for packet != nil {
+		if udp, ok := packet.(*transportlayer.UDP); ok {
+			udp.SetFlow(*result.Flow)
+			if message, ok := udp.Inner().(applicationlayer.IFlowNoted); ok && result.Noted {
+				message.SetFlowNote(result.Note)
+			}
+			return
+		}
+		container, ok := packet.(resolver.IContainer)
+		if !ok {
+			return
+		}
+		packet = container.Inner()
+	}
+}
+
+// 未保留摘要的已结束跟踪的流的合计
+type Omitted struct {
+	flows   int
+	packets int
+	bytes   int
+}
+
+func (omitted *Omitted) Flows() int {
+	return omitted.flows
+}
+
+// 并入一个流
+func (omitted *Omitted) add(flow *Flow) {
+	omitted.flows++
+	omitted.packets += flow.Packets()
+	omitted.bytes += flow.Bytes()
+}
+
+// 格式化为一行摘要，如 "3 more flows, 30 packets, 600 bytes"
+func (omitted *Omitted) ToString() string {
+	return strconv.Itoa(omitted.flows) + " more flows, " + strconv.Itoa(omitted.packets) + " packets, " + strconv.Itoa(omitted.bytes) + " bytes"
+}
+
+// 已结束跟踪的流的堆，堆顶为报文最少的流
+type retainedHeap []*Flow
+
+func (h retainedHeap) Len() int {
+	return len(h)
+}
+
+func (h retainedHeap) Less(i, j int) bool {
+	return h[i].Packets() < h[j].Packets()
+}
+
+func (h retainedHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *retainedHeap) Push(x any) {
+	*h = append(*h, x.(*Flow))
+}
+
+func (h *retainedHeap) Pop() any {
+	old := *h
+	flow := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return flow
+}
+
+// 按五元组跟踪 UDP 流的跟踪器，空闲超时后同一五元组的报文属于新的流，报文必须按抓包顺序送入
+type Tracker struct {
+	timeout  time.Duration       // 流的空闲超时，以抓包时间计
+	flows    map[direction]*Flow // 以两个方向分别索引的正在跟踪的流
+	order    *list.List          // 按活动时间排序的正在跟踪的流，表头为最近活动的流
+	count    int                 // 已创建的流数量，用于分配流序号
+	retained retainedHeap        // 保留摘要的已结束跟踪的流
+	omitted  Omitted             // 未保留摘要的已结束跟踪的流的合计
+}
+
+func New(timeout time.Duration) *Tracker {
+	return &Tracker{timeout: timeout, flows: map[direction]*Flow{}, order: list.New()}
+}
+
+// 按出现顺序排列的正在跟踪的流与保留摘要的已结束跟踪的流
+func (tracker *Tracker) Flows() []*Flow {
+	flows := slices.Clone([]*Flow(tracker.retained))
+	for element := tracker.order.Front(); element != nil; element = element.Next() {
+		flows = append(flows, element.Value.(*Flow))
+	}
+	slices.SortFunc(flows, func(a, b *Flow) int {
+		return a.index - b.index
+	})
+	return flows
+}
+
+// 未保留摘要的已结束跟踪的流的合计
+func (tracker *Tracker) Omitted() *Omitted {
+	return &tracker.omitted
+}
+
+// 跟踪一个报文，frame 为其帧序号，非 UDP 报文返回空的结果
+func (tracker *Tracker) Inspect(frame int, packet gopacket.Packet) Result {
+	result := Result{}
+	layer, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok || packet.NetworkLayer() == nil {
+		return result
+	}
+	timestamp := packet.Metadata().Timestamp
+	key := direction{net: packet.NetworkLayer().NetworkFlow(), transport: layer.TransportFlow()}
+	flow := tracker.flow(frame, key, timestamp)
+	forward := key.net == flow.net && key.transport == flow.transport
+	delta := time.Duration(0)
+	if flow.Packets() != 0 {
+		delta = timestamp.Sub(flow.last)
+	}
+	if forward {
+		flow.forwardPackets++
+		flow.forwardBytes += len(layer.Payload)
+	} else {
+		flow.backwardPackets++
+		flow.backwardBytes += len(layer.Payload)
+	}
+	flow.last, flow.lastFrame = timestamp, frame
+	tracker.order.MoveToFront(flow.element)
+	result.Flow = &transportlayer.UDPFlow{
+		Index:           flow.index,
+		Forward:         forward,
+		ForwardPackets:  flow.forwardPackets,
+		BackwardPackets: flow.backwardPackets,
+		ForwardBytes:    flow.forwardBytes,
+		BackwardBytes:   flow.backwardBytes,
+		Delta:           delta,
+	}
+
+	// 按端口分派的协议（如隧道）不是应用层消息
+	if len(layer.Payload) == 0 || transportlayer.PortProtocol(uint16(layer.SrcPort), uint16(layer.DstPort)) != "" {
+		return result
+	}
+	message, protocol := applicationlayer.Resolve(layer.Payload)
+	if message == nil || applicationlayer.FlowTrackers[protocol] == nil {
+		return result
+	}
+	track := flow.trackers[protocol]
+	if track == nil {
+		track = applicationlayer.FlowTrackers[protocol]()
+		flow.trackers[protocol] = track
+		flow.protocols = append(flow.protocols, protocol)
+	}
+	result.Note = track.Track(message, frame, timestamp, forward)
+	result.Noted = true
+	return result
+}
+
+// 取得或创建报文所属的流，同一五元组空闲超时后创建新的流
+func (tracker *Tracker) flow(frame int, key direction, timestamp time.Time) *Flow {
+	flow := tracker.flows[key]
+	if flow != nil && timestamp.Sub(flow.last) > tracker.timeout {
+		tracker.retire(flow)
+		statistics.Add(STATISTICS_GROUP, "Idle timeouts", 1)
+		flow = nil
+	}
+	if flow != nil {
+		return flow
+	}
+
+	if tracker.order.Len() >= MAX_FLOWS {
+		tracker.prune(timestamp)
+	}
+	flow = &Flow{
+		index:      tracker.count,
+		net:        key.net,
+		transport:  key.transport,
+		trackers:   map[string]applicationlayer.FlowTracker{},
+		first:      timestamp,
+		last:       timestamp,
+		firstFrame: frame,
+	}
+	statistics.Add(STATISTICS_GROUP, "Flows", 1)
+	flow.element = tracker.order.PushFront(flow)
+	tracker.flows[key] = flow
+	tracker.flows[key.reverse()] = flow
+	tracker.count++
+	return flow
+}
+
+// 结束跟踪空闲的流，仍达到上限时淘汰最久未活动的流
+func (tracker *Tracker) prune(timestamp time.Time) {
+	for tracker.order.Len() > 0 {
+		flow := tracker.order.Back().Value.(*Flow)
+		if timestamp.Sub(flow.last) <= tracker.timeout {
+			break
+		}
+		tracker.retire(flow)
+	}
+	for tracker.order.Len() >= MAX_FLOWS {
+		tracker.retire(tracker.order.Back().Value.(*Flow))
+		statistics.Add(STATISTICS_GROUP, "Evicted (flow limit)", 1)
+	}
+}
+
+// 结束跟踪一个流，只保留其摘要，释放应用层跟踪器的状态，超出保留数量时把报文最少的流并入合计
+func (tracker *Tracker) retire(flow *Flow) {
+	key := direction{net: flow.net, transport: flow.transport}
+	delete(tracker.flows, key)
+	delete(tracker.flows, key.reverse())
+	tracker.order.Remove(flow.element)
+	flow.summary = flow.ToString()
+	flow.trackers = nil
+	flow.protocols = nil
+	heap.Push(&tracker.retained, flow)
+	if tracker.retained.Len() > MAX_RETAINED {
+		tracker.omitted.add(heap.Pop(&tracker.retained).(*Flow))
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import "hash/crc32"
+
+// 计算互联网校验和（RFC 1071），多段数据按顺序拼接后计算；
+// 对包含校验和字段的完整数据计算的结果为 0 时校验和正确
+func InternetChecksum(data ...[]byte) uint16 {
+	sum := uint32(0)
+	odd := false
+	for _, part := range data {
+		for _, b := range part {
+			if odd {
+				sum += uint32(b)
+			} else {
+				sum += uint32(b) << 8
+			}
+			odd = !odd
+		}
+	}
+	for sum > 0xFFFF {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// 计算 CRC-32C（Castagnoli）校验和，多段数据按顺序拼接后计算
+func CRC32C(data ...[]byte) uint32 {
+	sum := uint32(0)
+	for _, part := range data {
+		sum = crc32.Update(sum, castagnoli, part)
+	}
+	return sum
+}
@@ -19,3 +19,8 @@ func ExtractUint32BE(packet []byte, offset int) uint32 {
 func ExtractUint64BE(packet []byte, offset int) uint64 {
 	return uint64(packet[offset])<<56 | uint64(packet[offset+1])<<48 | uint64(packet[offset+2])<<40 | uint64(packet[offset+3])<<32 | uint64(packet[offset+4])<<24 | uint64(packet[offset+5])<<16 | uint64(packet[offset+6])<<8 | uint64(packet[offset+7])
 }
+
+// 提取 32 位，小端
+func ExtractUint32LE(packet []byte, offset int) uint32 {
+	return uint32(packet[offset]) | uint32(packet[offset+1])<<8 | uint32(packet[offset+2])<<16 | uint32(packet[offset+3])<<24
+}